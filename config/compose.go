@@ -0,0 +1,299 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ParserConfig defines how the parser reads the configuration file and composes it with other
+// files before decoding it
+type ParserConfig struct {
+	// FileReader reads the content of the files. If nil, os.ReadFile is used
+	FileReader FileReaderFunc
+	// Stat returns the info of the includes and checks if the environment overlay exists. If nil,
+	// os.Stat is used, so it must be injected along with a FileReader not using the OS filesystem
+	Stat FileStatFunc
+	// ReadDir lists the files of the included directories. If nil, os.ReadDir is used, so it must
+	// be injected along with a FileReader not using the OS filesystem
+	ReadDir DirReaderFunc
+	// Includes is the list of files or directories with endpoint fragments to append to the
+	// endpoints of the configuration file. A fragment contains either a list of endpoints or an
+	// object with an 'endpoints' list. Directories are not traversed recursively and just their
+	// files with a supported extension are included, sorted by name.
+	Includes []string
+	// Overlays is the list of files to deep merge, in order, on top of the configuration file and
+	// its includes. The endpoints are merged by method and path, their backends by index and the
+	// rest of the objects by key. Other values replace the previous ones, and null values remove
+	// them.
+	Overlays []string
+	// Environment, if not empty, applies the file named as the configuration file with the
+	// environment before the extension (lura.prod.json for lura.json) as the last overlay, if it
+	// exists.
+	Environment string
+	// Strict rejects the configurations with unknown fields, unknown extra config namespaces or
+	// values not matching the schemas of their namespaces. The errors are reported all at once as
+	// a *ValidationError.
+	Strict bool
+	// Migrate upgrades the documents of older versions to the current ConfigVersion before
+	// decoding them, as Migrate does
+	Migrate bool
+}
+
+// NewParserWithConfig returns a Parser composing and checking the configuration files as defined
+// by the injected ParserConfig
+func NewParserWithConfig(cfg ParserConfig) Parser {
+	return newParser(cfg)
+}
+
+// Compose returns the JSON representation of the configuration file composed as defined by the
+// ParserConfig, before being normalized and initialized, so the merged result can be inspected
+func Compose(configFile string, cfg ParserConfig) ([]byte, error) {
+	doc, err := newParser(cfg).compose(configFile)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(doc, "", "\t")
+}
+
+func newParser(cfg ParserConfig) parser {
+	if cfg.FileReader == nil {
+		cfg.FileReader = os.ReadFile
+	}
+	if cfg.Stat == nil {
+		cfg.Stat = os.Stat
+	}
+	if cfg.ReadDir == nil {
+		cfg.ReadDir = os.ReadDir
+	}
+	return parser{
+		fileReader:  cfg.FileReader,
+		stat:        cfg.Stat,
+		readDir:     cfg.ReadDir,
+		includes:    cfg.Includes,
+		overlays:    cfg.Overlays,
+		environment: cfg.Environment,
+		strict:      cfg.Strict,
+		migrate:     cfg.Migrate,
+	}
+}
+
+func (p parser) isComposed() bool {
+	return len(p.includes) > 0 || len(p.overlays) > 0 || p.environment != ""
+}
+
+// compose decodes every file, checking its types against the parseable structs so the errors
+// keep their position, and merges the generic representations
+func (p parser) compose(configFile string) (map[string]interface{}, error) {
+	var base parseableServiceConfig
+	var doc map[string]interface{}
+	if err := p.decodeFile(configFile, &base, &doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+
+	for _, include := range p.includes {
+		files, err := p.includedFiles(include)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			endpoints, err := p.decodeFragment(f)
+			if err != nil {
+				return nil, err
+			}
+			current, _ := doc["endpoints"].([]interface{})
+			doc["endpoints"] = append(current, endpoints...)
+		}
+	}
+
+	overlays := p.overlays
+	if p.environment != "" {
+		ext := filepath.Ext(configFile)
+		envFile := strings.TrimSuffix(configFile, ext) + "." + p.environment + ext
+		if _, err := p.stat(envFile); err == nil {
+			overlays = append(append([]string{}, overlays...), envFile)
+		}
+	}
+	for _, f := range overlays {
+		var overlay parseableServiceConfig
+		var overlayDoc map[string]interface{}
+		if err := p.decodeFile(f, &overlay, &overlayDoc); err != nil {
+			return nil, err
+		}
+		mergeService(doc, overlayDoc)
+	}
+
+	return doc, nil
+}
+
+// decodeFile decodes the file into every received value
+func (p parser) decodeFile(file string, values ...interface{}) error {
+	data, err := p.fileReader(file)
+	if err != nil {
+		return CheckErr(err, file)
+	}
+	source, err := expandEnv(file, data)
+	if err != nil {
+		return err
+	}
+	decode := decoderFor(file)
+	for _, v := range values {
+		if err = decode(source.data, v); err != nil {
+			var decodeErr *decodeError
+			if errors.As(err, &decodeErr) {
+				return newSourceParseError(decodeErr.err, file, data, source.originalOffset(decodeErr.offset))
+			}
+			return CheckErr(err, file)
+		}
+	}
+	return nil
+}
+
+// decodeFragment returns the endpoints of the fragment
+func (p parser) decodeFragment(file string) ([]interface{}, error) {
+	var doc interface{}
+	if err := p.decodeFile(file, &doc); err != nil {
+		return nil, err
+	}
+
+	if endpoints, ok := doc.([]interface{}); ok {
+		var typed []*parseableEndpointConfig
+		return endpoints, p.decodeFile(file, &typed)
+	}
+
+	var typed struct {
+		Endpoints []*parseableEndpointConfig `json:"endpoints"`
+	}
+	if err := p.decodeFile(file, &typed); err != nil {
+		return nil, err
+	}
+	m, _ := doc.(map[string]interface{})
+	endpoints, _ := m["endpoints"].([]interface{})
+	return endpoints, nil
+}
+
+// includedFiles returns the include itself if it is a file or its files with a supported extension
+// if it is a directory
+func (p parser) includedFiles(include string) ([]string, error) {
+	info, err := p.stat(include)
+	if err != nil {
+		return nil, CheckErr(err, include)
+	}
+	if !info.IsDir() {
+		return []string{include}, nil
+	}
+	entries, err := p.readDir(include)
+	if err != nil {
+		return nil, CheckErr(err, include)
+	}
+	var files []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if _, ok := configFormats[strings.ToLower(filepath.Ext(e.Name()))]; ok {
+			files = append(files, filepath.Join(include, e.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// mergeService merges the overlay into the service document
+func mergeService(dst, src map[string]interface{}) {
+	for k, v := range src {
+		if k != "endpoints" {
+			mergeKey(dst, k, v)
+			continue
+		}
+		overlay, ok := v.([]interface{})
+		current, isList := dst[k].([]interface{})
+		if !ok || !isList {
+			mergeKey(dst, k, v)
+			continue
+		}
+		dst[k] = mergeEndpoints(current, overlay)
+	}
+}
+
+// mergeEndpoints merges the endpoints with the same method and path and appends the new ones
+func mergeEndpoints(dst, src []interface{}) []interface{} {
+	for _, e := range src {
+		overlay, ok := e.(map[string]interface{})
+		if !ok {
+			dst = append(dst, e)
+			continue
+		}
+		merged := false
+		for _, c := range dst {
+			current, ok := c.(map[string]interface{})
+			if !ok || endpointDocKey(current) != endpointDocKey(overlay) {
+				continue
+			}
+			mergeEndpoint(current, overlay)
+			merged = true
+			break
+		}
+		if !merged {
+			dst = append(dst, overlay)
+		}
+	}
+	return dst
+}
+
+func mergeEndpoint(dst, src map[string]interface{}) {
+	for k, v := range src {
+		overlay, ok := v.([]interface{})
+		current, isList := dst[k].([]interface{})
+		if k != "backend" || !ok || !isList {
+			mergeKey(dst, k, v)
+			continue
+		}
+		for i, b := range overlay {
+			if i < len(current) {
+				current[i] = mergeValues(current[i], b)
+				continue
+			}
+			current = append(current, b)
+		}
+		dst[k] = current
+	}
+}
+
+func endpointDocKey(e map[string]interface{}) string {
+	method, _ := e["method"].(string)
+	if method == "" {
+		method = "GET"
+	}
+	return fmt.Sprintf("%s %v", strings.ToUpper(method), e["endpoint"])
+}
+
+func mergeKey(dst map[string]interface{}, k string, v interface{}) {
+	if v == nil {
+		delete(dst, k)
+		return
+	}
+	dst[k] = mergeValues(dst[k], v)
+}
+
+// mergeValues deep merges the objects and returns the overlay for any other value
+func mergeValues(dst, src interface{}) interface{} {
+	current, ok := dst.(map[string]interface{})
+	overlay, isMap := src.(map[string]interface{})
+	if !ok || !isMap {
+		return src
+	}
+	for k, v := range overlay {
+		mergeKey(current, k, v)
+	}
+	return current
+}
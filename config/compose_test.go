@@ -0,0 +1,253 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func writeComposeTestFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestNewParserWithConfig(t *testing.T) {
+	dir := writeComposeTestFiles(t, map[string]string{
+		"lura.json": `{
+			"version": 3,
+			"port": 8080,
+			"timeout": "3s",
+			"host": ["http://dev.example.com"],
+			"extra_config": {"some/namespace": {"a": 1, "b": 2}},
+			"endpoints": [
+				{
+					"endpoint": "/users",
+					"backend": [{"url_pattern": "/users"}, {"url_pattern": "/roles"}]
+				}
+			]
+		}`,
+		"endpoints/01_posts.yaml": `
+- endpoint: /posts
+  method: post
+  backend:
+    - url_pattern: /posts
+`,
+		"endpoints/02_comments.toml": `
+[[endpoints]]
+endpoint = "/comments"
+
+[[endpoints.backend]]
+url_pattern = "/comments"
+`,
+		"endpoints/README.md": "ignored",
+		"lura.prod.json": `{
+			"timeout": "10s",
+			"host": ["http://prod.example.com"],
+			"extra_config": {"some/namespace": {"b": null, "c": 3}},
+			"endpoints": [
+				{"endpoint": "/users", "backend": [{}, {"url_pattern": "/v2/roles"}]},
+				{"endpoint": "/posts", "method": "POST", "timeout": "1s"},
+				{"endpoint": "/health", "backend": [{"url_pattern": "/__health"}]}
+			]
+		}`,
+		"overlay.yaml": `
+port: 9090
+`,
+	})
+
+	cfg := ParserConfig{
+		Includes:    []string{filepath.Join(dir, "endpoints")},
+		Overlays:    []string{filepath.Join(dir, "overlay.yaml")},
+		Environment: "prod",
+	}
+	serviceConfig, err := NewParserWithConfig(cfg).Parse(filepath.Join(dir, "lura.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if serviceConfig.Port != 9090 {
+		t.Errorf("unexpected port: %d", serviceConfig.Port)
+	}
+	if serviceConfig.Timeout != 10*time.Second {
+		t.Errorf("unexpected timeout: %v", serviceConfig.Timeout)
+	}
+	if h := serviceConfig.Host; len(h) != 1 || h[0] != "http://prod.example.com" {
+		t.Errorf("unexpected hosts: %v", h)
+	}
+	ns, _ := serviceConfig.ExtraConfig["some/namespace"].(map[string]interface{})
+	if _, ok := ns["b"]; ok || ns["a"] != 1.0 || ns["c"] != 3.0 {
+		t.Errorf("unexpected extra config: %v", serviceConfig.ExtraConfig)
+	}
+
+	endpoints := map[string]*EndpointConfig{}
+	for _, e := range serviceConfig.Endpoints {
+		endpoints[e.Method+" "+e.Endpoint] = e
+	}
+	if len(endpoints) != 4 {
+		t.Fatalf("unexpected endpoints: %v", endpoints)
+	}
+	users := endpoints["GET /users"]
+	if users == nil || len(users.Backend) != 2 || users.Backend[0].URLPattern != "/users" || users.Backend[1].URLPattern != "/v2/roles" {
+		t.Errorf("unexpected endpoint: %+v", users)
+	}
+	if posts := endpoints["POST /posts"]; posts == nil || posts.Timeout != time.Second || posts.Backend[0].URLPattern != "/posts" {
+		t.Errorf("unexpected endpoint: %+v", posts)
+	}
+	if endpoints["GET /comments"] == nil || endpoints["GET /health"] == nil {
+		t.Errorf("missing endpoints: %v", endpoints)
+	}
+
+	composed, err := Compose(filepath.Join(dir, "lura.json"), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flat := filepath.Join(dir, "flat.json")
+	if err := os.WriteFile(flat, composed, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	flatConfig, err := NewParser().Parse(flat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h1, err := serviceConfig.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h2, err := flatConfig.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h1 != h2 {
+		t.Errorf("the composed document does not produce the same config:\n%s", composed)
+	}
+}
+
+func TestNewParserWithConfig_environmentNotFound(t *testing.T) {
+	dir := writeComposeTestFiles(t, map[string]string{
+		"lura.json": `{"version": 3, "port": 8080, "endpoints": []}`,
+	})
+	serviceConfig, err := NewParserWithConfig(ParserConfig{Environment: "staging"}).Parse(filepath.Join(dir, "lura.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serviceConfig.Port != 8080 {
+		t.Errorf("unexpected port: %d", serviceConfig.Port)
+	}
+}
+
+func TestNewParserWithConfig_errors(t *testing.T) {
+	dir := writeComposeTestFiles(t, map[string]string{
+		"lura.json":      `{"version": 3, "endpoints": []}`,
+		"fragment.json":  "[\n\t{\"endpoint\": \"/a\", \"backend\": {}}\n]",
+		"overlay.yaml":   "version: 3\nport: nope\n",
+		"overlay2.json":  `{"port": 8080,}`,
+		"duplicated.yml": "- endpoint: /a\n- endpoint: /a\n",
+	})
+
+	for _, tc := range []struct {
+		name   string
+		cfg    ParserConfig
+		expErr string
+	}{
+		{
+			name:   "fragment type error",
+			cfg:    ParserConfig{Includes: []string{filepath.Join(dir, "fragment.json")}},
+			expErr: "fragment.json': json: cannot unmarshal object into Go struct field .0.backend of type []*config.parseableBackend, offset: 34, row: 1, col: 32",
+		},
+		{
+			name:   "overlay type error",
+			cfg:    ParserConfig{Overlays: []string{filepath.Join(dir, "overlay.yaml")}},
+			expErr: "overlay.yaml': json: cannot unmarshal string into Go struct field parseableServiceConfig.port of type int, offset: 18, row: 1, col: 7",
+		},
+		{
+			name:   "overlay syntax error",
+			cfg:    ParserConfig{Overlays: []string{filepath.Join(dir, "overlay2.json")}},
+			expErr: "overlay2.json': invalid character '}' looking for beginning of object key string, offset: 15, row: 0, col: 15",
+		},
+		{
+			name:   "missing file",
+			cfg:    ParserConfig{Overlays: []string{filepath.Join(dir, "unknown.json")}},
+			expErr: "unknown.json' (open): no such file or directory",
+		},
+		{
+			name:   "missing include",
+			cfg:    ParserConfig{Includes: []string{filepath.Join(dir, "unknown")}},
+			expErr: "unknown' (stat): no such file or directory",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewParserWithConfig(tc.cfg).Parse(filepath.Join(dir, "lura.json"))
+			if err == nil {
+				t.Fatal("error expected")
+			}
+			if !strings.HasSuffix(err.Error(), tc.expErr) {
+				t.Errorf("unexpected error: %s", err.Error())
+			}
+		})
+	}
+
+	if _, err := NewParserWithConfig(ParserConfig{Includes: []string{filepath.Join(dir, "duplicated.yml")}}).Parse(filepath.Join(dir, "lura.json")); err == nil {
+		t.Error("error expected for the duplicated endpoints")
+	}
+}
+
+func TestNewParserWithConfig_fs(t *testing.T) {
+	fsys := fstest.MapFS{
+		"lura.json":           {Data: []byte(`{"version": 3, "port": 8080, "endpoints": [{"endpoint": "/a", "backend": [{"url_pattern": "/a"}]}]}`)},
+		"endpoints/b.json":    {Data: []byte(`[{"endpoint": "/b", "backend": [{"url_pattern": "/b"}]}]`)},
+		"endpoints/README.md": {Data: []byte("ignored")},
+		"lura.prod.json":      {Data: []byte(`{"port": 9090}`)},
+	}
+	cfg := ParserConfig{
+		FileReader:  func(name string) ([]byte, error) { return fs.ReadFile(fsys, name) },
+		Stat:        func(name string) (fs.FileInfo, error) { return fs.Stat(fsys, name) },
+		ReadDir:     func(name string) ([]fs.DirEntry, error) { return fs.ReadDir(fsys, name) },
+		Includes:    []string{"endpoints"},
+		Environment: "prod",
+	}
+	serviceConfig, err := NewParserWithConfig(cfg).Parse("lura.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if serviceConfig.Port != 9090 {
+		t.Errorf("the environment overlay has not been applied: %d", serviceConfig.Port)
+	}
+	if len(serviceConfig.Endpoints) != 2 || serviceConfig.Endpoints[1].Endpoint != "/b" {
+		t.Errorf("unexpected endpoints: %v", serviceConfig.Endpoints)
+	}
+}
+
+func TestCompose(t *testing.T) {
+	dir := writeComposeTestFiles(t, map[string]string{
+		"lura.json": `{"version": 3, "name": "base", "endpoints": [{"endpoint": "/a"}]}`,
+		"b.json":    `{"endpoints": [{"endpoint": "/b"}]}`,
+	})
+	b, err := Compose(filepath.Join(dir, "lura.json"), ParserConfig{Includes: []string{filepath.Join(dir, "b.json")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if endpoints, _ := doc["endpoints"].([]interface{}); len(endpoints) != 2 || doc["name"] != "base" {
+		t.Errorf("unexpected document: %s", string(b))
+	}
+}
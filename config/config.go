@@ -8,7 +8,6 @@ package config
 import (
 	"crypto/sha256"
 	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
@@ -36,6 +35,11 @@ const (
 
 	// ConfigVersion is the current version of the config struct
 	ConfigVersion = 3
+
+	// proxyNamespace and proxyMockKey locate the mocked response of the endpoints, defined by
+	// the proxy package (that can not be imported here)
+	proxyNamespace = "github.com/devopsfaith/krakend/proxy"
+	proxyMockKey   = "mock"
 )
 
 // RoutingPattern to use during route conversion. By default, use the colon router pattern
@@ -193,6 +197,51 @@ type ServiceConfig struct {
 	// of the service. If 0, it will wait indefinitely until all the requests are served
 	// or the process is killed.
 	MaxShutdownDuration time.Duration `mapstructure:"max_shutdown_wait_time" json:"max_shutdown_wait_time"`
+
+	// ResponseLimits defines the default protections applied while reading the backend
+	// responses. The backends can override any of them.
+	ResponseLimits *ResponseLimits `mapstructure:"response_limits" json:"response_limits,omitempty"`
+
+	// secrets contains the secrets resolved out of the endpoints
+	secrets secretSet
+}
+
+// ResponseLimits defines the protections applied while reading the backend responses.
+// The zero values disable the related limit.
+type ResponseLimits struct {
+	// MaxBodySize is the max number of bytes to read from the response body, before decompressing it
+	MaxBodySize int64 `mapstructure:"max_body_size" json:"max_body_size"`
+	// MaxDecompressedSize is the max number of bytes of the decompressed response body
+	MaxDecompressedSize int64 `mapstructure:"max_decompressed_size" json:"max_decompressed_size"`
+	// MaxJSONDepth is the max nesting level of the decoded responses
+	MaxJSONDepth int `mapstructure:"max_json_depth" json:"max_json_depth"`
+	// MaxJSONElements is the max number of values of the decoded responses
+	MaxJSONElements int `mapstructure:"max_json_elements" json:"max_json_elements"`
+}
+
+// merge returns the limits with the zero values replaced by the defaults
+func (r *ResponseLimits) merge(defaults *ResponseLimits) *ResponseLimits {
+	if defaults == nil {
+		return r
+	}
+	if r == nil {
+		res := *defaults
+		return &res
+	}
+	res := *r
+	if res.MaxBodySize == 0 {
+		res.MaxBodySize = defaults.MaxBodySize
+	}
+	if res.MaxDecompressedSize == 0 {
+		res.MaxDecompressedSize = defaults.MaxDecompressedSize
+	}
+	if res.MaxJSONDepth == 0 {
+		res.MaxJSONDepth = defaults.MaxJSONDepth
+	}
+	if res.MaxJSONElements == 0 {
+		res.MaxJSONElements = defaults.MaxJSONElements
+	}
+	return &res
 }
 
 // AsyncAgent defines the configuration of a single subscriber/consumer to be initialized
@@ -247,6 +296,9 @@ type EndpointConfig struct {
 	HeadersToPass []string `mapstructure:"input_headers" json:"input_headers"`
 	// OutputEncoding defines the encoding strategy to use for the endpoint responses
 	OutputEncoding string `mapstructure:"output_encoding" json:"output_encoding"`
+
+	// secrets contains the secrets resolved in the endpoint and its backends
+	secrets secretSet
 }
 
 // Backend defines how lura should connect to the backend service (the API resource to consume)
@@ -294,6 +346,8 @@ type Backend struct {
 	HeadersToPass []string `mapstructure:"input_headers" json:"input_headers"`
 	// QueryStringsToPass has the list of query string params to be sent to the backend
 	QueryStringsToPass []string `mapstructure:"input_query_strings" json:"input_query_strings"`
+	// ResponseLimits defines the protections applied while reading the responses of this backend
+	ResponseLimits *ResponseLimits `mapstructure:"response_limits" json:"response_limits,omitempty"`
 
 	// ParentEndpoint is to be filled by the parent endpoint with its pattern enpoint
 	// so logs and other instrumentation can output better info (thus, it is not loaded
@@ -331,6 +385,10 @@ type TLS struct {
 	EnableMTLS               bool         `mapstructure:"enable_mtls" json:"enable_mtls"`
 	DisableSystemCaPool      bool         `mapstructure:"disable_system_ca_pool" json:"disable_system_ca_pool"`
 	Keys                     []TLSKeyPair `mapstructure:"keys" json:"keys"`
+	// EnableHTTP3 starts a QUIC listener serving HTTP/3 alongside the TLS one
+	EnableHTTP3 bool `mapstructure:"enable_http3" json:"enable_http3"`
+	// HTTP3Port is the UDP port of the QUIC listener. If zero, the port of the service is used.
+	HTTP3Port int `mapstructure:"http3_port" json:"http3_port"`
 }
 
 // ClientTLS defines the configuration params for an HTTP Client
@@ -389,13 +447,14 @@ var (
 )
 
 // Hash returns the sha 256 hash of the configuration in a standard base64 encoded string. It ignores the
-// name in order to reduce the noise.
+// name in order to reduce the noise and uses the references of the resolved secrets instead of their values,
+// so the rotation of a secret is detected with SecretsDigest.
 func (s *ServiceConfig) Hash() (string, error) {
 	var name string
 	name, s.Name = s.Name, ""
 	defer func() { s.Name = name }()
 
-	b, err := json.Marshal(s)
+	b, err := s.MarshalRedacted()
 	if err != nil {
 		return "", err
 	}
@@ -407,24 +466,33 @@ func (s *ServiceConfig) Hash() (string, error) {
 // Init also sanitizes the values, applies the default ones whenever necessary and
 // normalizes all the things.
 func (s *ServiceConfig) Init() error {
+	return s.init(nil)
+}
+
+// init initializes the configuration. If the report is nil, it stops at the first error. Otherwise,
+// the errors are collected in the report and the process continues with the next element.
+func (s *ServiceConfig) init(r *ValidationReport) error {
 	s.uriParser = NewSafeURIParser()
 
 	if s.Version != ConfigVersion {
-		return &UnsupportedVersionError{
+		err := &UnsupportedVersionError{
 			Have: s.Version,
 			Want: ConfigVersion,
 		}
+		if err := r.collect(err, ValidationIssue{Backend: -1, Path: []interface{}{"version"}}); err != nil {
+			return err
+		}
 	}
 
-	if err := s.initGlobalParams(); err != nil {
+	if err := s.initGlobalParams(r); err != nil {
 		return err
 	}
 
-	if err := s.initAsyncAgents(); err != nil {
+	if err := s.initAsyncAgents(r); err != nil {
 		return err
 	}
 
-	return s.initEndpoints()
+	return s.initEndpoints(r)
 }
 
 func (s *ServiceConfig) Normalize() {
@@ -443,14 +511,17 @@ func (s *ServiceConfig) Normalize() {
 	}
 }
 
-func (s *ServiceConfig) initGlobalParams() error {
+func (s *ServiceConfig) initGlobalParams(r *ValidationReport) error {
 	if s.Port == 0 {
 		s.Port = defaultPort
 	}
 
 	if s.Address != "" {
 		if !validateAddress(s.Address) {
-			return fmt.Errorf("invalid ip address %s", s.Address)
+			err := fmt.Errorf("invalid ip address %s", s.Address)
+			if err := r.collect(err, ValidationIssue{Backend: -1, Path: []interface{}{"address"}}); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -461,29 +532,40 @@ func (s *ServiceConfig) initGlobalParams() error {
 		s.Timeout = DefaultTimeout
 	}
 
-	var err error
-	s.Host, err = s.uriParser.SafeCleanHosts(s.Host)
+	hosts, err := s.uriParser.SafeCleanHosts(s.Host)
 	if err != nil {
-		return err
+		if err := r.collect(err, ValidationIssue{Backend: -1, Path: []interface{}{"host"}}); err != nil {
+			return err
+		}
+	} else {
+		s.Host = hosts
 	}
 	s.ExtraConfig.sanitize()
 	return nil
 }
 
-func (s *ServiceConfig) initAsyncAgents() error {
+func (s *ServiceConfig) initAsyncAgents(r *ValidationReport) error {
 	for i, e := range s.AsyncAgents {
 		s.initAsyncAgentDefaults(i)
 
 		e.ExtraConfig.sanitize()
 
-		for _, b := range e.Backend {
+		for j, b := range e.Backend {
 			if len(b.Host) == 0 {
 				b.Host = s.Host
 			} else if !b.HostSanitizationDisabled {
-				var err error
-				b.Host, err = s.uriParser.SafeCleanHosts(b.Host)
+				hosts, err := s.uriParser.SafeCleanHosts(b.Host)
 				if err != nil {
-					return err
+					issue := ValidationIssue{
+						AsyncAgent: e.Name,
+						Backend:    j,
+						Path:       []interface{}{"async_agent", i, "backend", j, "host"},
+					}
+					if err := r.collect(err, issue); err != nil {
+						return err
+					}
+				} else {
+					b.Host = hosts
 				}
 			}
 			if b.Method == "" {
@@ -491,6 +573,7 @@ func (s *ServiceConfig) initAsyncAgents() error {
 			}
 			b.Timeout = e.Consumer.Timeout
 			b.Decoder = encoding.GetRegister().Get(strings.ToLower(b.Encoding))(b.IsCollection)
+			b.ResponseLimits = b.ResponseLimits.merge(s.ResponseLimits)
 
 			b.ExtraConfig.sanitize()
 		}
@@ -498,12 +581,15 @@ func (s *ServiceConfig) initAsyncAgents() error {
 	return nil
 }
 
-func (s *ServiceConfig) initEndpoints() error {
+func (s *ServiceConfig) initEndpoints(r *ValidationReport) error {
 	for i, e := range s.Endpoints {
 		e.Endpoint = s.uriParser.CleanPath(e.Endpoint)
 
 		if err := e.validate(); err != nil {
-			return err
+			if err := r.collect(err, endpointIssue(i, e, -1)); err != nil {
+				return err
+			}
+			continue
 		}
 
 		for i := range e.HeadersToPass {
@@ -521,7 +607,10 @@ func (s *ServiceConfig) initEndpoints() error {
 		s.initEndpointDefaults(i)
 
 		if e.OutputEncoding == encoding.NOOP && len(e.Backend) > 1 {
-			return errInvalidNoOpEncoding
+			if err := r.collect(errInvalidNoOpEncoding, endpointIssue(i, e, -1)); err != nil {
+				return err
+			}
+			continue
 		}
 
 		e.ExtraConfig.sanitize()
@@ -531,11 +620,17 @@ func (s *ServiceConfig) initEndpoints() error {
 			b.ParentEndpoint = e.Endpoint
 			b.ParentEndpointMethod = e.Method
 			if err := s.initBackendDefaults(i, j); err != nil {
-				return err
+				if err := r.collect(err, endpointIssue(i, e, j)); err != nil {
+					return err
+				}
+				continue
 			}
 
 			if err := s.initBackendURLMappings(i, j, inputSet); err != nil {
-				return err
+				if err := r.collect(err, endpointIssue(i, e, j)); err != nil {
+					return err
+				}
+				continue
 			}
 
 			b.ExtraConfig.sanitize()
@@ -617,6 +712,7 @@ func (s *ServiceConfig) initBackendDefaults(e, b int) error {
 	backend.Timeout = endpoint.Timeout
 	backend.ConcurrentCalls = endpoint.ConcurrentCalls
 	backend.Decoder = encoding.GetRegister().Get(strings.ToLower(backend.Encoding))(backend.IsCollection)
+	backend.ResponseLimits = backend.ResponseLimits.merge(s.ResponseLimits)
 
 	for i := range backend.HeadersToPass {
 		backend.HeadersToPass[i] = textproto.CanonicalMIMEHeaderKey(backend.HeadersToPass[i])
@@ -697,6 +793,17 @@ func uniqueOutput(output []string) ([]string, int) {
 	return output[:j+1], outputSetSize
 }
 
+// Hash returns the sha 256 hash of the endpoint configuration in a standard base64 encoded string,
+// using the references of the resolved secrets instead of their values
+func (e *EndpointConfig) Hash() (string, error) {
+	b, err := e.MarshalRedacted()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return base64.StdEncoding.EncodeToString(sum[:]), nil
+}
+
 func (e *EndpointConfig) validate() error {
 	matched, err := regexp.MatchString(invalidPattern, e.Endpoint)
 	if err != nil {
@@ -710,12 +817,23 @@ func (e *EndpointConfig) validate() error {
 		return &EndpointPathError{Path: e.Endpoint, Method: e.Method}
 	}
 
-	if len(e.Backend) == 0 {
+	if len(e.Backend) == 0 && !e.Mocked() {
 		return &NoBackendsError{Path: e.Endpoint, Method: e.Method}
 	}
 	return nil
 }
 
+// Mocked returns true if the endpoint defines a mocked response in the extra config of the
+// proxy namespace, so its responses are served without calling the backends
+func (e *EndpointConfig) Mocked() bool {
+	v, ok := e.ExtraConfig[proxyNamespace].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, ok = v[proxyMockKey].(map[string]interface{})
+	return ok
+}
+
 // EndpointMatchError is the error returned by the configuration init process when the endpoint pattern
 // check fails
 type EndpointMatchError struct {
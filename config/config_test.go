@@ -216,6 +216,64 @@ func TestConfig_init(t *testing.T) {
 	}
 }
 
+func TestEndpointConfig_Hash(t *testing.T) {
+	e1 := EndpointConfig{Endpoint: "/a", Method: "GET", Backend: []*Backend{{URLPattern: "/b"}}}
+	e2 := EndpointConfig{Endpoint: "/a", Method: "GET", Backend: []*Backend{{URLPattern: "/b"}}}
+	e3 := EndpointConfig{Endpoint: "/a", Method: "GET", Backend: []*Backend{{URLPattern: "/c"}}}
+
+	h1, err := e1.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	h2, _ := e2.Hash()
+	h3, _ := e3.Hash()
+	if h1 != h2 {
+		t.Errorf("the hashes of equal endpoints should match: %s, %s", h1, h2)
+	}
+	if h1 == h3 {
+		t.Error("the hashes of different endpoints should not match")
+	}
+}
+
+func TestConfig_initResponseLimits(t *testing.T) {
+	defaultBackend := Backend{URLPattern: "/a"}
+	customBackend := Backend{
+		URLPattern:     "/b",
+		ResponseLimits: &ResponseLimits{MaxBodySize: 10, MaxJSONElements: 5},
+	}
+	agentBackend := Backend{URLPattern: "/c"}
+
+	subject := ServiceConfig{
+		Version: ConfigVersion,
+		Host:    []string{"http://127.0.0.1:8080"},
+		Endpoints: []*EndpointConfig{{
+			Endpoint: "/limits",
+			Backend:  []*Backend{&defaultBackend, &customBackend},
+		}},
+		AsyncAgents: []*AsyncAgent{{Name: "agent", Backend: []*Backend{&agentBackend}}},
+		ResponseLimits: &ResponseLimits{
+			MaxBodySize:         100,
+			MaxDecompressedSize: 1000,
+			MaxJSONDepth:        3,
+		},
+	}
+
+	if err := subject.Init(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	for name, b := range map[string]*Backend{"default": &defaultBackend, "agent": &agentBackend} {
+		if b.ResponseLimits == subject.ResponseLimits || *b.ResponseLimits != *subject.ResponseLimits {
+			t.Errorf("%s: unexpected limits: %+v", name, b.ResponseLimits)
+		}
+	}
+	expected := ResponseLimits{MaxBodySize: 10, MaxDecompressedSize: 1000, MaxJSONDepth: 3, MaxJSONElements: 5}
+	if *customBackend.ResponseLimits != expected {
+		t.Errorf("unexpected limits: %+v", customBackend.ResponseLimits)
+	}
+}
+
 func TestConfig_initKONoBackends(t *testing.T) {
 	subject := ServiceConfig{
 		Version: ConfigVersion,
@@ -338,3 +396,28 @@ func TestConfig_initKOValidSetinvalidPattern(t *testing.T) {
 
 	invalidPattern = dp
 }
+
+func TestEndpointConfig_Mocked(t *testing.T) {
+	e := EndpointConfig{
+		Endpoint: "/a",
+		Method:   "GET",
+		ExtraConfig: ExtraConfig{
+			proxyNamespace: map[string]interface{}{proxyMockKey: map[string]interface{}{"data": map[string]interface{}{}}},
+		},
+	}
+	if !e.Mocked() {
+		t.Error("the endpoint should be mocked")
+	}
+	if err := e.validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	e.ExtraConfig[proxyNamespace] = map[string]interface{}{"static": map[string]interface{}{}}
+	if e.Mocked() {
+		t.Error("the endpoint should not be mocked")
+	}
+	var noBackendsErr *NoBackendsError
+	if err := e.validate(); !errors.As(err, &noBackendsErr) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,307 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// envReference matches the ${ENV_VAR} and ${ENV_VAR:-default} references. The references
+// prefixed with an extra dollar ($${ENV_VAR}) are escaped and kept as literals (${ENV_VAR}).
+var envReference = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// envSyntax defines how the values of the env vars are escaped for the format of a config file
+type envSyntax int
+
+const (
+	envJSON envSyntax = iota
+	envYAML
+	envTOML
+)
+
+// envContext is the lexical context of an env var reference in a config file
+type envContext int
+
+const (
+	// envBare references are out of any string
+	envBare envContext = iota
+	// envDoubleQuoted references are inside a string with backslash escapes
+	envDoubleQuoted
+	// envSingleQuoted references are inside a YAML single quoted string
+	envSingleQuoted
+	// envLiteral references are inside a TOML literal string
+	envLiteral
+	// envMultilineLiteral references are inside a TOML multi-line literal string
+	envMultilineLiteral
+	// envComment references are inside a comment, so they are not expanded
+	envComment
+)
+
+// expandedSource is the content of a config file after the env var expansion, keeping track of
+// the replacements so the offsets can be translated back to the original content
+type expandedSource struct {
+	data         []byte
+	replacements []envReplacement
+}
+
+type envReplacement struct {
+	// offset of the replacement in the expanded content
+	at     int
+	newLen int
+	// offset and length of the reference in the original content
+	origAt  int
+	origLen int
+}
+
+// expandEnv replaces the env var references in the content of the config file. The unset (or
+// empty) variables are replaced by the default value, if any. The values are escaped for the
+// format of the file and the position of the reference, so they can not break or change the
+// structure of the document: inside strings they are escaped, and out of strings the values
+// other than numbers and booleans are encoded as strings. The references inside comments are
+// not expanded. The unset variables without a default value are reported as a *ParseError.
+func expandEnv(configFile string, data []byte) (expandedSource, error) {
+	matches := envReference.FindAllSubmatchIndex(data, -1)
+	if len(matches) == 0 {
+		return expandedSource{data: data}, nil
+	}
+
+	syntax := formatFor(configFile).env
+	contexts := envContexts(syntax, data, matches)
+	res := expandedSource{
+		data:         make([]byte, 0, len(data)),
+		replacements: make([]envReplacement, 0, len(matches)),
+	}
+	last := 0
+	for i, m := range matches {
+		res.data = append(res.data, data[last:m[0]]...)
+		last = m[1]
+		if contexts[i] == envComment {
+			res.data = append(res.data, data[m[0]:m[1]]...)
+			continue
+		}
+
+		var value []byte
+		if data[m[0]+1] == '$' {
+			value = data[m[0]+1 : m[1]]
+		} else {
+			name := string(data[m[2]:m[3]])
+			v, ok := os.LookupEnv(name)
+			switch {
+			case v == "" && m[6] != -1:
+				v = string(data[m[6]:m[7]])
+			case !ok:
+				return res, newSourceParseError(fmt.Errorf("env var %s is not set", name), configFile, data, m[0]+1)
+			}
+			escaped, err := escapeEnvValue(syntax, contexts[i], v, data, m[0], m[1])
+			if err != nil {
+				return res, newSourceParseError(fmt.Errorf("env var %s: %w", name, err), configFile, data, m[0]+1)
+			}
+			value = []byte(escaped)
+		}
+		res.replacements = append(res.replacements, envReplacement{
+			at:      len(res.data),
+			newLen:  len(value),
+			origAt:  m[0],
+			origLen: m[1] - m[0],
+		})
+		res.data = append(res.data, value...)
+	}
+	res.data = append(res.data, data[last:]...)
+	return res, nil
+}
+
+// envContexts returns the lexical context of every matched reference. The content of the
+// references is skipped, so the quotes of their default values are ignored.
+func envContexts(syntax envSyntax, data []byte, matches [][]int) []envContext {
+	contexts := make([]envContext, len(matches))
+	ctx := envBare
+	// closing is the delimiter ending the current string
+	closing := ""
+	next := 0
+	for i := 0; i < len(data); i++ {
+		if next < len(matches) && i == matches[next][0] {
+			contexts[next] = ctx
+			i = matches[next][1] - 1
+			next++
+			continue
+		}
+		c := data[i]
+		switch ctx {
+		case envComment:
+			if c == '\n' {
+				ctx = envBare
+			}
+		case envDoubleQuoted:
+			if c == '\\' {
+				i++
+				continue
+			}
+			if bytes.HasPrefix(data[i:], []byte(closing)) {
+				i += len(closing) - 1
+				ctx = envBare
+			}
+		case envSingleQuoted:
+			if c == '\'' {
+				if i+1 < len(data) && data[i+1] == '\'' {
+					i++
+					continue
+				}
+				ctx = envBare
+			}
+		case envLiteral, envMultilineLiteral:
+			if bytes.HasPrefix(data[i:], []byte(closing)) {
+				i += len(closing) - 1
+				ctx = envBare
+			}
+		default:
+			switch {
+			case c == '#' && syntax == envTOML:
+				ctx = envComment
+			case c == '#' && syntax == envYAML && (i == 0 || data[i-1] == ' ' || data[i-1] == '\t' || data[i-1] == '\n'):
+				ctx = envComment
+			case c == '"' && (syntax != envYAML || startsYAMLScalar(data, i)):
+				ctx, closing = envDoubleQuoted, `"`
+				if syntax == envTOML && bytes.HasPrefix(data[i:], []byte(`"""`)) {
+					closing = `"""`
+					i += 2
+				}
+			case c == '\'' && syntax == envYAML && startsYAMLScalar(data, i):
+				ctx = envSingleQuoted
+			case c == '\'' && syntax == envTOML:
+				ctx, closing = envLiteral, `'`
+				if bytes.HasPrefix(data[i:], []byte(`'''`)) {
+					ctx, closing = envMultilineLiteral, `'''`
+					i += 2
+				}
+			}
+		}
+	}
+	return contexts
+}
+
+// startsYAMLScalar returns true if the character at the given offset is the first one of a YAML
+// scalar, so the quotes in the middle of the plain scalars are not taken as strings
+func startsYAMLScalar(data []byte, offset int) bool {
+	prev := bytes.TrimRight(data[lineStart(data, offset):offset], " \t")
+	if len(prev) == 0 {
+		return true
+	}
+	switch prev[len(prev)-1] {
+	case ':', '-', '[', '{', ',', '?':
+		return true
+	}
+	return false
+}
+
+func lineStart(data []byte, offset int) int {
+	return bytes.LastIndexByte(data[:offset], '\n') + 1
+}
+
+// escapeEnvValue returns the representation of the value for the context of the reference
+// placed at data[start:end]
+func escapeEnvValue(syntax envSyntax, ctx envContext, value string, data []byte, start, end int) (string, error) {
+	switch ctx {
+	case envDoubleQuoted:
+		s := quoteEnvValue(value)
+		return s[1 : len(s)-1], nil
+	case envSingleQuoted:
+		if strings.ContainsAny(value, "\r\n") {
+			return "", fmt.Errorf("the value can not be placed in a single quoted string")
+		}
+		return strings.ReplaceAll(value, "'", "''"), nil
+	case envLiteral:
+		if strings.ContainsAny(value, "'\r\n") {
+			return "", fmt.Errorf("the value can not be placed in a literal string")
+		}
+		return value, nil
+	case envMultilineLiteral:
+		if strings.Contains(value, "'''") {
+			return "", fmt.Errorf("the value can not be placed in a multi-line literal string")
+		}
+		return value, nil
+	}
+
+	if isEnvScalar(value) {
+		return value, nil
+	}
+	if syntax != envYAML {
+		return quoteEnvValue(value), nil
+	}
+	if isYAMLPlainSafe(value) {
+		return value, nil
+	}
+	if isWholeYAMLScalar(data, start, end) {
+		return quoteEnvValue(value), nil
+	}
+	return "", fmt.Errorf("the value can not be placed in a plain scalar, quote the reference")
+}
+
+// quoteEnvValue returns the value as a double quoted string, valid in JSON, YAML and TOML
+func quoteEnvValue(value string) string {
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(value)
+	s := strings.TrimSuffix(buf.String(), "\n")
+	// the DEL character is not allowed in the TOML strings
+	return strings.ReplaceAll(s, "\x7f", `\u007f`)
+}
+
+// isEnvScalar returns true if the value is a number or a boolean, so it can be expanded out of
+// the strings as it is
+func isEnvScalar(value string) bool {
+	if value == "true" || value == "false" {
+		return true
+	}
+	_, err := strconv.ParseFloat(value, 64)
+	return err == nil && json.Valid([]byte(value))
+}
+
+// isYAMLPlainSafe returns true if the value can be placed in a YAML plain scalar without
+// changing the structure of the document
+func isYAMLPlainSafe(value string) bool {
+	if value == "" {
+		return true
+	}
+	if strings.ContainsAny(value, "\r\n\t,[]{}") || strings.Contains(value, ": ") || strings.Contains(value, " #") ||
+		strings.HasSuffix(value, ":") || strings.TrimSpace(value) != value {
+		return false
+	}
+	return !strings.ContainsRune("-?:#&*!|>'\"%@`", rune(value[0]))
+}
+
+// isWholeYAMLScalar returns true if the reference at data[start:end] is the whole value of a
+// YAML scalar, so its value can be replaced by a double quoted string
+func isWholeYAMLScalar(data []byte, start, end int) bool {
+	if !startsYAMLScalar(data, start) {
+		return false
+	}
+	lineEnd := bytes.IndexByte(data[end:], '\n')
+	if lineEnd == -1 {
+		lineEnd = len(data) - end
+	}
+	rest := bytes.TrimLeft(data[end:end+lineEnd], " \t")
+	return len(rest) == 0 || rest[0] == '#' || rest[0] == '\r'
+}
+
+// originalOffset translates an offset of the expanded content into the original one. The offsets
+// inside a replaced value are moved to the beginning of its reference.
+func (e expandedSource) originalOffset(offset int) int {
+	shift := 0
+	for _, r := range e.replacements {
+		if offset < r.at {
+			break
+		}
+		if offset < r.at+r.newLen {
+			return r.origAt + 1
+		}
+		shift += r.origLen - r.newLen
+	}
+	return offset + shift
+}
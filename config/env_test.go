@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("LURA_TEST_HOST", "http://example.com")
+	t.Setenv("LURA_TEST_EMPTY", "")
+
+	for _, tc := range []struct {
+		in, out string
+	}{
+		{in: `{"host": ["${LURA_TEST_HOST}"]}`, out: `{"host": ["http://example.com"]}`},
+		{in: `"${LURA_TEST_UNKNOWN:-http://localhost:8080}"`, out: `"http://localhost:8080"`},
+		{in: `"${LURA_TEST_EMPTY:-fallback}"`, out: `"fallback"`},
+		{in: `"${LURA_TEST_EMPTY}"`, out: `""`},
+		{in: `"$${LURA_TEST_HOST}"`, out: `"${LURA_TEST_HOST}"`},
+		{in: `"$LURA_TEST_HOST {param} ${not valid}"`, out: `"$LURA_TEST_HOST {param} ${not valid}"`},
+	} {
+		source, err := expandEnv("lura.json", []byte(tc.in))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.in, err)
+			continue
+		}
+		if res := string(source.data); res != tc.out {
+			t.Errorf("%s: unexpected result: %s", tc.in, res)
+		}
+	}
+}
+
+func TestExpandEnv_escaped(t *testing.T) {
+	t.Setenv("LURA_TEST_INJECTION", `x", "backend": [{"url_pattern": "/evil"}], "y": "`)
+	t.Setenv("LURA_TEST_MULTILINE", "a'b\\c\nd")
+	t.Setenv("LURA_TEST_PORT", "8080")
+	t.Setenv("LURA_TEST_PLAIN", "example.com:8080")
+
+	for _, tc := range []struct {
+		file, in, out string
+	}{
+		{file: "lura.json", in: `{"a": "${LURA_TEST_INJECTION}"}`, out: `{"a": "x\", \"backend\": [{\"url_pattern\": \"/evil\"}], \"y\": \""}`},
+		{file: "lura.json", in: `{"a": "${LURA_TEST_MULTILINE}"}`, out: `{"a": "a'b\\c\nd"}`},
+		{file: "lura.json", in: `{"port": ${LURA_TEST_PORT}, "a": ${LURA_TEST_INJECTION}}`, out: `{"port": 8080, "a": "x\", \"backend\": [{\"url_pattern\": \"/evil\"}], \"y\": \""}`},
+		{file: "lura.yaml", in: "a: \"${LURA_TEST_MULTILINE}\"\n", out: "a: \"a'b\\\\c\\nd\"\n"},
+		{file: "lura.yaml", in: "a: ${LURA_TEST_MULTILINE} # ${LURA_TEST_UNKNOWN}\n", out: "a: \"a'b\\\\c\\nd\" # ${LURA_TEST_UNKNOWN}\n"},
+		{file: "lura.yaml", in: "a: http://${LURA_TEST_PLAIN}/\nport: ${LURA_TEST_PORT}\n", out: "a: http://example.com:8080/\nport: 8080\n"},
+		{file: "lura.yaml", in: "a: it's ${LURA_TEST_PLAIN}\n", out: "a: it's example.com:8080\n"},
+		{file: "lura.toml", in: "a = \"${LURA_TEST_MULTILINE}\"\nport = ${LURA_TEST_PORT}\n", out: "a = \"a'b\\\\c\\nd\"\nport = 8080\n"},
+		{file: "lura.toml", in: "a = '''${LURA_TEST_MULTILINE}'''\n", out: "a = '''a'b\\c\nd'''\n"},
+	} {
+		source, err := expandEnv(tc.file, []byte(tc.in))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.in, err)
+			continue
+		}
+		if res := string(source.data); res != tc.out {
+			t.Errorf("%s: unexpected result: %s", tc.in, res)
+		}
+	}
+}
+
+func TestExpandEnv_errors(t *testing.T) {
+	t.Setenv("LURA_TEST_MULTILINE", "a\nb")
+	t.Setenv("LURA_TEST_MAPPING", "a: b")
+
+	for _, tc := range []struct {
+		file, in, expErr string
+	}{
+		{file: "lura.json", in: "{\n\t\"a\": \"${LURA_TEST_UNKNOWN}\"\n}", expErr: "'lura.json': env var LURA_TEST_UNKNOWN is not set, offset: 10, row: 1, col: 8"},
+		{file: "lura.yaml", in: "a: 'x ${LURA_TEST_MULTILINE}'\n", expErr: "env var LURA_TEST_MULTILINE: the value can not be placed in a single quoted string"},
+		{file: "lura.yaml", in: "a: x ${LURA_TEST_MAPPING}\n", expErr: "env var LURA_TEST_MAPPING: the value can not be placed in a plain scalar, quote the reference"},
+		{file: "lura.toml", in: "a = '${LURA_TEST_MULTILINE}'\n", expErr: "env var LURA_TEST_MULTILINE: the value can not be placed in a literal string"},
+	} {
+		_, err := expandEnv(tc.file, []byte(tc.in))
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) {
+			t.Errorf("%s: unexpected error: %v", tc.in, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.expErr) {
+			t.Errorf("%s: unexpected error: %s", tc.in, err.Error())
+		}
+	}
+}
+
+func TestExpandEnv_originalOffset(t *testing.T) {
+	t.Setenv("LURA_TEST_LONG", "0123456789")
+	t.Setenv("LURA_TEST_SHORT", "x")
+
+	in := `a${LURA_TEST_LONG}b${LURA_TEST_SHORT}c`
+	source, err := expandEnv("lura.yaml", []byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(source.data) != "a0123456789bxc" {
+		t.Fatalf("unexpected result: %s", source.data)
+	}
+
+	for expanded, original := range map[int]int{
+		0:  0,
+		1:  2,
+		5:  2,
+		11: 18,
+		12: 20,
+		13: 37,
+		14: 38,
+	} {
+		if res := source.originalOffset(expanded); res != original {
+			t.Errorf("offset %d: unexpected original offset %d, want %d", expanded, res, original)
+		}
+	}
+}
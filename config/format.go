@@ -0,0 +1,383 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	yamlparser "github.com/goccy/go-yaml/parser"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+)
+
+// configDecoder decodes the content of a config file into the received value. The errors
+// related to a position of the content are returned as *decodeError
+type configDecoder func(data []byte, v interface{}) error
+
+// configLocator returns the offset of the value at the given path (keys and indexes) of the
+// content of a config file, pointing right after its first character
+type configLocator func(data []byte, path []interface{}) (int, bool)
+
+// configEncoder returns the representation of the value in the format of a config file
+type configEncoder func(v interface{}) ([]byte, error)
+
+type configFormat struct {
+	decode configDecoder
+	locate configLocator
+	encode configEncoder
+	env    envSyntax
+}
+
+var jsonFormat = configFormat{decode: decodeJSON, locate: locateJSON, encode: encodeJSON, env: envJSON}
+
+// configFormats are the supported formats, indexed by file extension. The files with any other
+// extension are decoded as JSON.
+var configFormats = map[string]configFormat{
+	".json": jsonFormat,
+	".yaml": {decode: decodeYAML, locate: locateYAML, encode: yaml.Marshal, env: envYAML},
+	".yml":  {decode: decodeYAML, locate: locateYAML, encode: yaml.Marshal, env: envYAML},
+	".toml": {decode: decodeTOML, locate: locateTOML, encode: toml.Marshal, env: envTOML},
+}
+
+func formatFor(configFile string) configFormat {
+	if f, ok := configFormats[strings.ToLower(filepath.Ext(configFile))]; ok {
+		return f
+	}
+	return jsonFormat
+}
+
+func decoderFor(configFile string) configDecoder {
+	return formatFor(configFile).decode
+}
+
+// decodeError is an error found at the given offset of the decoded content. As in the JSON
+// errors, the offset points right after the offending character.
+type decodeError struct {
+	err    error
+	offset int
+}
+
+func (d *decodeError) Error() string { return d.err.Error() }
+
+func (d *decodeError) Unwrap() error { return d.err }
+
+func decodeJSON(data []byte, v interface{}) error {
+	err := json.Unmarshal(data, v)
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		return &decodeError{err: err, offset: int(e.Offset)}
+	case *json.UnmarshalTypeError:
+		return &decodeError{err: err, offset: int(e.Offset)}
+	}
+	return err
+}
+
+func encodeJSON(v interface{}) ([]byte, error) {
+	b, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+func decodeYAML(data []byte, v interface{}) error {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		var yamlErr yaml.Error
+		if errors.As(err, &yamlErr) && yamlErr.GetToken() != nil {
+			pos := yamlErr.GetToken().Position
+			return &decodeError{
+				err:    fmt.Errorf("yaml: %s", yamlErr.GetMessage()),
+				offset: getErrorOffset(data, pos.Line, pos.Column),
+			}
+		}
+		return err
+	}
+	return decodeDocument(data, doc, v, locateYAML)
+}
+
+func decodeTOML(data []byte, v interface{}) error {
+	var doc map[string]interface{}
+	if err := toml.Unmarshal(data, &doc); err != nil {
+		var tomlErr *toml.DecodeError
+		if errors.As(err, &tomlErr) {
+			row, col := tomlErr.Position()
+			return &decodeError{err: err, offset: getErrorOffset(data, row, col)}
+		}
+		return err
+	}
+	return decodeDocument(data, doc, v, locateTOML)
+}
+
+// decodeDocument decodes the generic representation of a YAML or TOML document through its JSON
+// representation, so the values get the same types as the ones parsed from a JSON file. The
+// type errors are located in the source with the received function.
+func decodeDocument(source []byte, doc, v interface{}, locate func([]byte, []interface{}) (int, bool)) error {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(b, v)
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		if offset, ok := locate(source, jsonPathAt(b, typeErr.Offset)); ok {
+			return &decodeError{err: err, offset: offset}
+		}
+	}
+	return err
+}
+
+// jsonPathAt returns the path (keys and indexes) of the value found at the offset of the
+// received JSON content
+func jsonPathAt(data []byte, offset int64) []interface{} {
+	var res []interface{}
+	walkJSON(data, func(path []interface{}, _, end int64) bool {
+		res = path
+		return end < offset
+	})
+	return res
+}
+
+// locateJSON returns the offset of the value at the given path of the JSON content
+func locateJSON(data []byte, path []interface{}) (int, bool) {
+	var offset int64
+	found := false
+	walkJSON(data, func(p []interface{}, start, _ int64) bool {
+		if equalPath(p, path) {
+			offset, found = start+1, true
+			return false
+		}
+		return true
+	})
+	return int(offset), found
+}
+
+// walkJSON calls the visitor with the path of every value of the JSON content, the offset of its
+// first character and the offset after its first token, until the visitor returns false
+func walkJSON(data []byte, visit func(path []interface{}, start, end int64) bool) {
+	type frame struct {
+		array     bool
+		expectKey bool
+		key       string
+		index     int
+	}
+	var stack []*frame
+	path := func() []interface{} {
+		res := make([]interface{}, 0, len(stack))
+		for _, f := range stack {
+			if f.array {
+				res = append(res, f.index)
+				continue
+			}
+			res = append(res, f.key)
+		}
+		return res
+	}
+	next := func() {
+		if len(stack) == 0 {
+			return
+		}
+		if f := stack[len(stack)-1]; f.array {
+			f.index++
+		} else {
+			f.expectKey = true
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		start := dec.InputOffset()
+		tok, err := dec.Token()
+		if err != nil {
+			return
+		}
+		if len(stack) > 0 {
+			if f := stack[len(stack)-1]; f.expectKey {
+				if d, ok := tok.(json.Delim); ok && d == '}' {
+					stack = stack[:len(stack)-1]
+					next()
+					continue
+				}
+				f.key, f.expectKey = fmt.Sprintf("%v", tok), false
+				continue
+			}
+		}
+		if d, ok := tok.(json.Delim); ok && (d == '}' || d == ']') {
+			stack = stack[:len(stack)-1]
+			next()
+			continue
+		}
+		for start < int64(len(data)) && bytes.IndexByte([]byte(" \t\r\n:,"), data[start]) >= 0 {
+			start++
+		}
+		if !visit(path(), start, dec.InputOffset()) {
+			return
+		}
+		if d, ok := tok.(json.Delim); ok {
+			stack = append(stack, &frame{array: d == '[', expectKey: d == '{'})
+			continue
+		}
+		next()
+	}
+}
+
+// locateYAML returns the offset of the node at the given path of the YAML document
+func locateYAML(source []byte, path []interface{}) (int, bool) {
+	file, err := yamlparser.ParseBytes(source, 0)
+	if err != nil {
+		return 0, false
+	}
+	b := (&yaml.PathBuilder{}).Root()
+	for _, p := range path {
+		switch k := p.(type) {
+		case string:
+			b = b.Child(k)
+		case int:
+			b = b.Index(uint(k))
+		}
+	}
+	node, err := b.Build().FilterFile(file)
+	if err != nil || node == nil {
+		return 0, false
+	}
+	// the token of a mapping is the one of its first separator, so its first key is used instead
+	if m, ok := node.(*ast.MappingNode); ok && len(m.Values) > 0 {
+		node = m.Values[0].Key
+	}
+	if node.GetToken() == nil {
+		return 0, false
+	}
+	pos := node.GetToken().Position
+	return getErrorOffset(source, pos.Line, pos.Column), true
+}
+
+// locateTOML returns the offset of the value at the given path of the TOML document. If the value
+// has no position in the source, the offset of its key is returned.
+func locateTOML(source []byte, path []interface{}) (int, bool) {
+	p := &unstable.Parser{}
+	p.Reset(source)
+
+	var prefix []interface{}
+	arrays := map[string]int{}
+	for p.NextExpression() {
+		expr := p.Expression()
+		switch expr.Kind {
+		case unstable.Table, unstable.ArrayTable:
+			prefix = tomlTablePath(expr, arrays)
+			if equalPath(prefix, path) {
+				return p.Shape(tomlKeyRange(expr)).Start.Offset + 1, true
+			}
+		case unstable.KeyValue:
+			at := append(append([]interface{}{}, prefix...), tomlKey(expr)...)
+			if offset, ok := locateTOMLValue(p, expr.Value(), at, path, tomlKeyRange(expr)); ok {
+				return offset, true
+			}
+		}
+	}
+	return 0, false
+}
+
+func locateTOMLValue(p *unstable.Parser, n *unstable.Node, at, path []interface{}, fallback unstable.Range) (int, bool) {
+	if len(at) > len(path) || !equalPath(at, path[:len(at)]) {
+		return 0, false
+	}
+	r := n.Raw
+	if r.Length == 0 {
+		r = fallback
+	}
+	if len(at) == len(path) {
+		return p.Shape(r).Start.Offset + 1, true
+	}
+
+	switch n.Kind {
+	case unstable.Array:
+		it := n.Children()
+		for i := 0; it.Next(); i++ {
+			if offset, ok := locateTOMLValue(p, it.Node(), append(append([]interface{}{}, at...), i), path, r); ok {
+				return offset, true
+			}
+		}
+	case unstable.InlineTable:
+		it := n.Children()
+		for it.Next() {
+			kv := it.Node()
+			kvAt := append(append([]interface{}{}, at...), tomlKey(kv)...)
+			if offset, ok := locateTOMLValue(p, kv.Value(), kvAt, path, tomlKeyRange(kv)); ok {
+				return offset, true
+			}
+		}
+	}
+	return p.Shape(r).Start.Offset + 1, true
+}
+
+// tomlTablePath returns the path of the table defined by the expression, selecting the last
+// element of the array tables
+func tomlTablePath(expr *unstable.Node, arrays map[string]int) []interface{} {
+	var res []interface{}
+	it := expr.Key()
+	for it.Next() {
+		res = append(res, string(it.Node().Data))
+		k := fmt.Sprintf("%v", res)
+		if it.IsLast() && expr.Kind == unstable.ArrayTable {
+			arrays[k]++
+			return append(res, arrays[k]-1)
+		}
+		if n, ok := arrays[k]; ok {
+			res = append(res, n-1)
+		}
+	}
+	return res
+}
+
+func tomlKey(kv *unstable.Node) []interface{} {
+	var res []interface{}
+	it := kv.Key()
+	for it.Next() {
+		res = append(res, string(it.Node().Data))
+	}
+	return res
+}
+
+// tomlKeyRange returns the range of the first part of the key of the node
+func tomlKeyRange(n *unstable.Node) unstable.Range {
+	it := n.Key()
+	if !it.Next() {
+		return unstable.Range{}
+	}
+	return it.Node().Raw
+}
+
+func equalPath(a, b []interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// getErrorOffset returns the offset pointing right after the character at the given line and
+// column (both starting at 1)
+func getErrorOffset(source []byte, line, col int) int {
+	offset := 0
+	for l := 1; l < line && offset < len(source); offset++ {
+		if source[offset] == '\n' {
+			l++
+		}
+	}
+	offset += col
+	if offset > len(source) {
+		offset = len(source)
+	}
+	return offset
+}
@@ -0,0 +1,181 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	formatTestJSON = `{
+	"version": 3,
+	"name": "My lovely gateway",
+	"port": 8080,
+	"timeout": "3s",
+	"host": ["${LURA_FORMAT_TEST_HOST:-http://127.0.0.1:8080}"],
+	"extra_config": {"some/namespace": {"limit": 10, "enabled": true}},
+	"endpoints": [
+		{
+			"endpoint": "/users/{id}",
+			"backend": [
+				{"url_pattern": "/v1/users/{id}", "allow": ["id", "name"]},
+				{"url_pattern": "/v1/posts", "extra_config": {"other/namespace": {"ratio": 0.5}}}
+			]
+		}
+	]
+}`
+	formatTestYAML = `version: 3
+name: My lovely gateway
+port: 8080
+timeout: 3s
+host:
+  - ${LURA_FORMAT_TEST_HOST:-http://127.0.0.1:8080}
+extra_config:
+  some/namespace:
+    limit: 10
+    enabled: true
+endpoints:
+  - endpoint: /users/{id}
+    backend:
+      - url_pattern: /v1/users/{id}
+        allow: [id, name]
+      - url_pattern: /v1/posts
+        extra_config:
+          other/namespace:
+            ratio: 0.5
+`
+	formatTestTOML = `version = 3
+name = "My lovely gateway"
+port = 8080
+timeout = "3s"
+host = ["${LURA_FORMAT_TEST_HOST:-http://127.0.0.1:8080}"]
+
+[extra_config."some/namespace"]
+limit = 10
+enabled = true
+
+[[endpoints]]
+endpoint = "/users/{id}"
+
+[[endpoints.backend]]
+url_pattern = "/v1/users/{id}"
+allow = ["id", "name"]
+
+[[endpoints.backend]]
+url_pattern = "/v1/posts"
+extra_config = { "other/namespace" = { ratio = 0.5 } }
+`
+)
+
+func writeFormatTestFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNewParser_formats(t *testing.T) {
+	t.Setenv("LURA_FORMAT_TEST_HOST", "http://backend.example.com")
+
+	expected, err := NewParser().Parse(writeFormatTestFile(t, "lura.json", formatTestJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := expected.Host[0]; h != "http://backend.example.com" {
+		t.Errorf("unexpected host: %s", h)
+	}
+	if v, ok := expected.ExtraConfig["some/namespace"].(map[string]interface{})["limit"].(float64); !ok || v != 10 {
+		t.Errorf("unexpected extra config: %v", expected.ExtraConfig)
+	}
+	expectedHash, err := expected.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range map[string]string{
+		"lura.yaml": formatTestYAML,
+		"lura.yml":  formatTestYAML,
+		"lura.YAML": formatTestYAML,
+		"lura.toml": formatTestTOML,
+	} {
+		cfg, err := NewParser().Parse(writeFormatTestFile(t, name, content))
+		if err != nil {
+			t.Errorf("%s: %s", name, err.Error())
+			continue
+		}
+		h, err := cfg.Hash()
+		if err != nil {
+			t.Errorf("%s: %s", name, err.Error())
+			continue
+		}
+		if h != expectedHash {
+			t.Errorf("%s: unexpected config: %+v", name, cfg)
+		}
+	}
+}
+
+func TestNewParser_formatErrorMessages(t *testing.T) {
+	t.Setenv("LURA_FORMAT_TEST_PORT", "1234567890")
+
+	for _, tc := range []struct {
+		name    string
+		file    string
+		content string
+		expErr  string
+	}{
+		{
+			name:    "json type error after an expansion",
+			file:    "lura.json",
+			content: "{\n\t\"name\": \"${LURA_FORMAT_TEST_PORT}\",\n\t\"port\": \"${LURA_FORMAT_TEST_PORT}\"\n}",
+			expErr:  "json: cannot unmarshal string into Go struct field parseableServiceConfig.port of type int, offset: 74, row: 2, col: 35",
+		},
+		{
+			name:    "yaml syntax error",
+			file:    "lura.yaml",
+			content: "version: 3\nendpoints:\n  - endpoint: /a\n   backend: []\n",
+			expErr:  "yaml: value is not allowed in this context, offset: 43, row: 3, col: 4",
+		},
+		{
+			name:    "yaml type error",
+			file:    "lura.yaml",
+			content: "version: 3\nendpoints:\n  - endpoint: /a\n    backend:\n      - url_pattern: /a\n        allow: nope\n",
+			expErr:  "json: cannot unmarshal string into Go struct field parseableServiceConfig.endpoints.0.backend.0.allow of type []string, offset: 92, row: 5, col: 16",
+		},
+		{
+			name:    "toml syntax error",
+			file:    "lura.toml",
+			content: "version = 3\nport = \n",
+			expErr:  "toml: incomplete number, offset: 20, row: 2, col: 0",
+		},
+		{
+			name:    "toml type error",
+			file:    "lura.toml",
+			content: "version = 3\n\n[[endpoints]]\nendpoint = \"/a\"\n\n[[endpoints]]\nendpoint = \"/b\"\n\n[[endpoints.backend]]\nurl_pattern = \"/b\"\nallow = \"nope\"\n",
+			expErr:  "json: cannot unmarshal string into Go struct field parseableServiceConfig.endpoints.1.backend.0.allow of type []string, offset: 125, row: 10, col: 9",
+		},
+		{
+			name:    "toml type error in an inline table",
+			file:    "lura.toml",
+			content: "version = 3\nendpoints = [{endpoint = \"/a\", backend = [{url_pattern = 42}]}]\n",
+			expErr:  "json: cannot unmarshal number into Go struct field parseableServiceConfig.endpoints.0.backend.0.url_pattern of type string, offset: 70, row: 1, col: 58",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeFormatTestFile(t, tc.file, tc.content)
+			_, err := NewParser().Parse(path)
+			if err == nil {
+				t.Fatal("error expected")
+			}
+			if _, ok := err.(*ParseError); !ok {
+				t.Errorf("unexpected error type %T", err)
+			}
+			if expErr := "'" + path + "': " + tc.expErr; err.Error() != expErr {
+				t.Errorf("unexpected error.\nhave: %s\nwant: %s", err.Error(), expErr)
+			}
+		})
+	}
+}
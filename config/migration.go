@@ -0,0 +1,252 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrNoMigration is the error returned when there is no migration registered for the version of
+// the document
+var ErrNoMigration = errors.New("no migration available")
+
+// Migration upgrades the config documents of a version to the next one
+type Migration struct {
+	// From is the version of the documents upgraded to the version From+1
+	From int
+	// Migrate modifies the generic representation of the document in place and returns the changes
+	// applied
+	Migrate func(doc map[string]interface{}) []MigrationChange
+}
+
+// MigrationChange describes a change applied to a config document by a migration
+type MigrationChange struct {
+	// Path is the location of the changed value in the migrated document (keys and indexes)
+	Path        []interface{}
+	Description string
+}
+
+// String returns a string representation of the MigrationChange
+func (m MigrationChange) String() string {
+	if len(m.Path) == 0 {
+		return m.Description
+	}
+	parts := make([]string, len(m.Path))
+	for i, p := range m.Path {
+		parts[i] = fmt.Sprintf("%v", p)
+	}
+	return strings.Join(parts, ".") + ": " + m.Description
+}
+
+// MigrationReport contains the changes applied to a document while upgrading it from the version
+// From to the version To
+type MigrationReport struct {
+	From    int
+	To      int
+	Changes []MigrationChange
+}
+
+var (
+	migrationsMutex = &sync.RWMutex{}
+	migrations      = map[int]Migration{}
+)
+
+func init() {
+	RegisterMigration(Migration{From: 2, Migrate: migrateV2})
+}
+
+// RegisterMigration adds the migration to the register, replacing the one registered for the
+// same version, if any
+func RegisterMigration(m Migration) {
+	migrationsMutex.Lock()
+	migrations[m.From] = m
+	migrationsMutex.Unlock()
+}
+
+// Migrate upgrades the config document to the current ConfigVersion, applying in order the
+// migrations registered for its version and the following ones, and returns the changes applied.
+// The aliased extra config namespaces are renamed to their targets, as defined by the
+// ExtraConfigAlias, even if the document is already at the current version.
+func Migrate(doc map[string]interface{}) (*MigrationReport, error) {
+	version, ok := docVersion(doc["version"])
+	if !ok || version > ConfigVersion {
+		return nil, &UnsupportedVersionError{Have: version, Want: ConfigVersion}
+	}
+
+	r := &MigrationReport{From: version, To: ConfigVersion}
+	for ; version < ConfigVersion; version++ {
+		migrationsMutex.RLock()
+		m, ok := migrations[version]
+		migrationsMutex.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("%w from the version %d", ErrNoMigration, version)
+		}
+		r.Changes = append(r.Changes, m.Migrate(doc)...)
+		doc["version"] = version + 1
+		r.Changes = append(r.Changes, MigrationChange{
+			Path:        []interface{}{"version"},
+			Description: fmt.Sprintf("upgraded from %d to %d", version, version+1),
+		})
+	}
+
+	forEachExtraConfig(doc, func(path []interface{}, extra map[string]interface{}) {
+		for _, name := range sortedKeys(extra) {
+			if target, ok := ExtraConfigAlias[name]; ok {
+				r.Changes = append(r.Changes, renameKey(extra, path, name, target)...)
+			}
+		}
+	})
+	return r, nil
+}
+
+// MigrateFile upgrades the configuration file to the current ConfigVersion and returns it encoded
+// in the format of the file. The env var references are not expanded, so they are kept in the
+// migrated content, but they must be placed inside strings. The fields are sorted by name.
+func MigrateFile(configFile string) ([]byte, *MigrationReport, error) {
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, nil, CheckErr(err, configFile)
+	}
+	f := formatFor(configFile)
+	var doc map[string]interface{}
+	if err := f.decode(data, &doc); err != nil {
+		var decodeErr *decodeError
+		if errors.As(err, &decodeErr) {
+			return nil, nil, newSourceParseError(decodeErr.err, configFile, data, decodeErr.offset)
+		}
+		return nil, nil, CheckErr(err, configFile)
+	}
+	r, err := Migrate(doc)
+	if err != nil {
+		return nil, nil, CheckErr(err, configFile)
+	}
+	b, err := f.encode(doc)
+	if err != nil {
+		return nil, nil, CheckErr(err, configFile)
+	}
+	return b, r, nil
+}
+
+func docVersion(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case float64:
+		return int(n), float64(int(n)) == n
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	}
+	return 0, false
+}
+
+// legacyNamespaces are the namespaces renamed when the packages moved to the luraproject
+// organization
+var legacyNamespaces = map[string]string{
+	"github_com/devopsfaith/krakend/router/gin": "github_com/luraproject/lura/router/gin",
+	"github.com/devopsfaith/krakend/router/gin": "github_com/luraproject/lura/router/gin",
+}
+
+// migrateV2 upgrades the documents of the version 2 to the version 3
+func migrateV2(doc map[string]interface{}) []MigrationChange {
+	var changes []MigrationChange
+	forEachItem(doc, nil, "endpoints", func(path []interface{}, e map[string]interface{}) {
+		changes = append(changes, renameKey(e, path, "querystring_params", "input_query_strings")...)
+		changes = append(changes, renameKey(e, path, "headers_to_pass", "input_headers")...)
+	})
+	forEachBackend(doc, func(path []interface{}, b map[string]interface{}) {
+		changes = append(changes, renameKey(b, path, "whitelist", "allow")...)
+		changes = append(changes, renameKey(b, path, "blacklist", "deny")...)
+	})
+	forEachExtraConfig(doc, func(path []interface{}, extra map[string]interface{}) {
+		for _, name := range sortedKeys(extra) {
+			if target, ok := legacyNamespaces[name]; ok {
+				changes = append(changes, renameKey(extra, path, name, target)...)
+				continue
+			}
+			// the namespaces registered with the other github.com / github_com variant
+			if _, ok := lookupNamespace(name); ok {
+				continue
+			}
+			variant := strings.Replace(name, "github_com/", "github.com/", 1)
+			if variant == name {
+				variant = strings.Replace(name, "github.com/", "github_com/", 1)
+			}
+			if _, ok := lookupNamespace(variant); ok {
+				changes = append(changes, renameKey(extra, path, name, variant)...)
+			}
+		}
+	})
+	return changes
+}
+
+// renameKey moves the value of the key to the target key, unless the target is already defined
+func renameKey(m map[string]interface{}, path []interface{}, key, target string) []MigrationChange {
+	v, ok := m[key]
+	if !ok {
+		return nil
+	}
+	if _, ok := m[target]; ok {
+		return []MigrationChange{{
+			Path:        appendPath(path, key),
+			Description: fmt.Sprintf("not renamed to '%s', since it is already defined", target),
+		}}
+	}
+	m[target] = v
+	delete(m, key)
+	return []MigrationChange{{
+		Path:        appendPath(path, target),
+		Description: fmt.Sprintf("renamed from '%s'", key),
+	}}
+}
+
+// forEachBackend calls the function with every backend of the endpoints and the async agents
+func forEachBackend(doc map[string]interface{}, f func(path []interface{}, b map[string]interface{})) {
+	for _, parent := range []string{"endpoints", "async_agent"} {
+		forEachItem(doc, nil, parent, func(path []interface{}, e map[string]interface{}) {
+			forEachItem(e, path, "backend", f)
+		})
+	}
+}
+
+// forEachExtraConfig calls the function with every extra config of the document
+func forEachExtraConfig(doc map[string]interface{}, f func(path []interface{}, extra map[string]interface{})) {
+	visit := func(path []interface{}, m map[string]interface{}) {
+		if extra, ok := m["extra_config"].(map[string]interface{}); ok {
+			f(appendPath(path, "extra_config"), extra)
+		}
+	}
+	visit(nil, doc)
+	for _, parent := range []string{"endpoints", "async_agent"} {
+		forEachItem(doc, nil, parent, visit)
+	}
+	forEachBackend(doc, visit)
+}
+
+// forEachItem calls the function with every object of the list stored at the key of the object
+// found at the path
+func forEachItem(m map[string]interface{}, path []interface{}, key string, f func(path []interface{}, item map[string]interface{})) {
+	items, _ := m[key].([]interface{})
+	for i, item := range items {
+		if obj, ok := item.(map[string]interface{}); ok {
+			f(appendPath(path, key, i), obj)
+		}
+	}
+}
+
+func appendPath(path []interface{}, elems ...interface{}) []interface{} {
+	return append(append([]interface{}{}, path...), elems...)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,223 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const migrationTestJSON = `{
+	"version": 2,
+	"host": ["${LURA_MIGRATION_TEST_HOST:-http://127.0.0.1:8080}"],
+	"extra_config": {
+		"github_com/devopsfaith/krakend/router/gin": {"auto_options": true},
+		"migration/alias": {"a": 1}
+	},
+	"endpoints": [
+		{
+			"endpoint": "/users/{id}",
+			"querystring_params": ["page"],
+			"headers_to_pass": ["Authorization"],
+			"backend": [
+				{
+					"url_pattern": "/users/{id}",
+					"whitelist": ["id", "name"],
+					"blacklist": ["password"],
+					"deny": ["token"],
+					"extra_config": {"github_com/migration/test": {"b": 2}}
+				}
+			]
+		}
+	]
+}`
+
+func TestMigrate(t *testing.T) {
+	if err := RegisterNamespace(Namespace{Name: "github.com/migration/test"}); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterNamespace("github.com/migration/test")
+	ExtraConfigAlias["migration/alias"] = "migration/target"
+	defer delete(ExtraConfigAlias, "migration/alias")
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(migrationTestJSON), &doc); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Migrate(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.From != 2 || r.To != ConfigVersion {
+		t.Errorf("unexpected versions: %d -> %d", r.From, r.To)
+	}
+
+	changes := make([]string, len(r.Changes))
+	for i, c := range r.Changes {
+		changes[i] = c.String()
+	}
+	expected := []string{
+		"endpoints.0.input_query_strings: renamed from 'querystring_params'",
+		"endpoints.0.input_headers: renamed from 'headers_to_pass'",
+		"endpoints.0.backend.0.allow: renamed from 'whitelist'",
+		"endpoints.0.backend.0.blacklist: not renamed to 'deny', since it is already defined",
+		"extra_config.github_com/luraproject/lura/router/gin: renamed from 'github_com/devopsfaith/krakend/router/gin'",
+		"endpoints.0.backend.0.extra_config.github.com/migration/test: renamed from 'github_com/migration/test'",
+		"version: upgraded from 2 to 3",
+		"extra_config.migration/target: renamed from 'migration/alias'",
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("unexpected changes:\n%s", strings.Join(changes, "\n"))
+	}
+
+	b, _ := json.Marshal(doc)
+	for _, s := range []string{
+		`"version":3`,
+		`"input_query_strings":["page"]`,
+		`"allow":["id","name"]`,
+		`"blacklist":["password"]`,
+		`"github_com/luraproject/lura/router/gin":{"auto_options":true}`,
+		`"migration/target":{"a":1}`,
+		`"github.com/migration/test":{"b":2}`,
+	} {
+		if !strings.Contains(string(b), s) {
+			t.Errorf("%s not found in the migrated document: %s", s, b)
+		}
+	}
+}
+
+func TestMigrate_currentVersion(t *testing.T) {
+	doc := map[string]interface{}{"version": 3, "whitelist": true}
+	r, err := Migrate(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.From != ConfigVersion || len(r.Changes) != 0 {
+		t.Errorf("unexpected report: %+v", r)
+	}
+	if _, ok := doc["whitelist"]; !ok {
+		t.Error("the current documents should not be migrated")
+	}
+}
+
+func TestMigrate_unsupportedVersion(t *testing.T) {
+	var versionErr *UnsupportedVersionError
+	for _, doc := range []map[string]interface{}{
+		{"version": 4},
+		{"version": "2"},
+		{},
+	} {
+		if _, err := Migrate(doc); !errors.As(err, &versionErr) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	if _, err := Migrate(map[string]interface{}{"version": 1}); !errors.Is(err, ErrNoMigration) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	RegisterMigration(Migration{
+		From: 1,
+		Migrate: func(doc map[string]interface{}) []MigrationChange {
+			doc["migrated"] = true
+			return []MigrationChange{{Description: "migrated from v1"}}
+		},
+	})
+	defer func() {
+		migrationsMutex.Lock()
+		delete(migrations, 1)
+		migrationsMutex.Unlock()
+	}()
+
+	doc := map[string]interface{}{"version": 1}
+	r, err := Migrate(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.From != 1 || len(r.Changes) != 3 || r.Changes[0].String() != "migrated from v1" {
+		t.Errorf("unexpected report: %+v", r)
+	}
+	if doc["version"] != ConfigVersion || doc["migrated"] != true {
+		t.Errorf("unexpected document: %v", doc)
+	}
+}
+
+func TestMigrateFile(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"lura.json": migrationTestJSON,
+		"lura.yaml": "version: 2\nhost: ['${LURA_MIGRATION_TEST_HOST}']\nendpoints:\n  - endpoint: /\n    backend:\n      - url_pattern: /\n        whitelist: [a]\n",
+	} {
+		configFile := filepath.Join(dir, name)
+		if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		b, r, err := MigrateFile(configFile)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if r.From != 2 {
+			t.Errorf("%s: unexpected report: %+v", name, r)
+		}
+		if !strings.Contains(string(b), "${LURA_MIGRATION_TEST_HOST") {
+			t.Errorf("%s: the env var references should be kept: %s", name, b)
+		}
+
+		var doc map[string]interface{}
+		if err := decoderFor(name)(b, &doc); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		endpoints, _ := doc["endpoints"].([]interface{})
+		e, _ := endpoints[0].(map[string]interface{})
+		backends, _ := e["backend"].([]interface{})
+		if b, _ := backends[0].(map[string]interface{}); b["allow"] == nil || doc["version"] != float64(ConfigVersion) {
+			t.Errorf("%s: unexpected document: %v", name, doc)
+		}
+	}
+
+	if _, _, err := MigrateFile(filepath.Join(dir, "unknown.json")); err == nil {
+		t.Error("expecting an error")
+	}
+}
+
+func TestNewParserWithConfig_migrate(t *testing.T) {
+	readFile := func(string) ([]byte, error) { return []byte(migrationTestJSON), nil }
+
+	var versionErr *UnsupportedVersionError
+	if _, err := NewParserWithFileReader(readFile).Parse("lura.json"); !errors.As(err, &versionErr) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	cfg, err := NewParserWithConfig(ParserConfig{FileReader: readFile, Migrate: true}).Parse("lura.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := cfg.Endpoints[0].Backend[0]
+	if !reflect.DeepEqual(b.AllowList, []string{"id", "name"}) {
+		t.Errorf("unexpected allow list: %v", b.AllowList)
+	}
+	if !reflect.DeepEqual(cfg.Endpoints[0].QueryString, []string{"page"}) {
+		t.Errorf("unexpected query strings: %v", cfg.Endpoints[0].QueryString)
+	}
+	if _, ok := cfg.ExtraConfig["github_com/luraproject/lura/router/gin"]; !ok {
+		t.Errorf("unexpected extra config: %v", cfg.ExtraConfig)
+	}
+
+	current := strings.Replace(migrationTestJSON, `"version": 2`, `"version": 3`, 1)
+	readFile = func(string) ([]byte, error) { return []byte(current), nil }
+	cfg, err = NewParserWithConfig(ParserConfig{FileReader: readFile, Migrate: true}).Parse("lura.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Endpoints[0].QueryString) != 0 {
+		t.Errorf("the current documents should not be migrated: %v", cfg.Endpoints[0].QueryString)
+	}
+}
@@ -0,0 +1,197 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/luraproject/lura/v2/jsonschema"
+)
+
+var (
+	// ErrUnknownNamespace is the issue reported when an extra config uses a namespace not registered
+	ErrUnknownNamespace = errors.New("unknown extra config namespace")
+	// ErrNamespaceScope is the issue reported when a registered namespace is used in an extra config
+	// of a level not supported by its component
+	ErrNamespaceScope = errors.New("extra config namespace not supported at this level")
+)
+
+// ExtraConfigScope is the set of levels of the configuration where a namespace can be used
+type ExtraConfigScope int
+
+const (
+	// ServiceScope is the extra config of the service
+	ServiceScope ExtraConfigScope = 1 << iota
+	// EndpointScope is the extra config of the endpoints
+	EndpointScope
+	// BackendScope is the extra config of the backends
+	BackendScope
+	// AsyncAgentScope is the extra config of the async agents
+	AsyncAgentScope
+	// AnyScope accepts the namespace at every level
+	AnyScope = ServiceScope | EndpointScope | BackendScope | AsyncAgentScope
+)
+
+func (s ExtraConfigScope) String() string {
+	var names []string
+	for _, l := range []struct {
+		scope ExtraConfigScope
+		name  string
+	}{
+		{ServiceScope, "service"},
+		{EndpointScope, "endpoint"},
+		{BackendScope, "backend"},
+		{AsyncAgentScope, "async_agent"},
+	} {
+		if s&l.scope != 0 {
+			names = append(names, l.name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// Namespace describes an extra config namespace declared by a component
+type Namespace struct {
+	// Name is the key of the namespace in the extra config
+	Name string
+	// Scope is the set of levels accepting the namespace. Zero means AnyScope
+	Scope ExtraConfigScope
+	// Schema is the JSON Schema of the value of the namespace. If empty, any value is accepted
+	Schema json.RawMessage
+}
+
+type registeredNamespace struct {
+	Namespace
+	schema *jsonschema.Schema
+}
+
+var (
+	namespacesMutex = &sync.RWMutex{}
+	namespaces      = map[string]registeredNamespace{}
+)
+
+// RegisterNamespace declares the extra config namespace of a component, so the strict parsers
+// and the validation can flag the unknown namespaces and the invalid values. It returns an error
+// if the schema can not be compiled.
+func RegisterNamespace(ns Namespace) error {
+	if ns.Scope == 0 {
+		ns.Scope = AnyScope
+	}
+	r := registeredNamespace{Namespace: ns}
+	if len(ns.Schema) > 0 {
+		s, err := jsonschema.CompileJSON(ns.Schema)
+		if err != nil {
+			return fmt.Errorf("compiling the schema of the namespace %s: %w", ns.Name, err)
+		}
+		r.schema = s
+	}
+	namespacesMutex.Lock()
+	namespaces[ns.Name] = r
+	namespacesMutex.Unlock()
+	return nil
+}
+
+// MustRegisterNamespace is like RegisterNamespace but panics if the schema can not be compiled.
+// It simplifies the registration of the namespaces at the init functions of the components.
+func MustRegisterNamespace(ns Namespace) {
+	if err := RegisterNamespace(ns); err != nil {
+		panic(err)
+	}
+}
+
+// UnregisterNamespace removes the namespace from the register
+func UnregisterNamespace(name string) {
+	namespacesMutex.Lock()
+	delete(namespaces, name)
+	namespacesMutex.Unlock()
+}
+
+// LookupNamespace returns the registered namespace with the name or alias
+func LookupNamespace(name string) (Namespace, bool) {
+	ns, ok := lookupNamespace(name)
+	return ns.Namespace, ok
+}
+
+// Namespaces returns the registered namespaces sorted by name
+func Namespaces() []Namespace {
+	namespacesMutex.RLock()
+	res := make([]Namespace, 0, len(namespaces))
+	for _, ns := range namespaces {
+		res = append(res, ns.Namespace)
+	}
+	namespacesMutex.RUnlock()
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+func lookupNamespace(name string) (registeredNamespace, bool) {
+	if alias, ok := ExtraConfigAlias[name]; ok {
+		name = alias
+	}
+	namespacesMutex.RLock()
+	ns, ok := namespaces[name]
+	namespacesMutex.RUnlock()
+	return ns, ok
+}
+
+// extraConfigIssues returns the issues of the namespaces used in every extra config of the service
+func (s *ServiceConfig) extraConfigIssues() []*ValidationIssue {
+	var issues []*ValidationIssue
+	issues = append(issues, checkExtraConfig(s.ExtraConfig, ServiceScope, ValidationIssue{Backend: -1})...)
+	for i, e := range s.Endpoints {
+		issue := endpointIssue(i, e, -1)
+		issues = append(issues, checkExtraConfig(e.ExtraConfig, EndpointScope, issue)...)
+		for j, b := range e.Backend {
+			issue := endpointIssue(i, e, j)
+			issues = append(issues, checkExtraConfig(b.ExtraConfig, BackendScope, issue)...)
+		}
+	}
+	for i, a := range s.AsyncAgents {
+		issue := ValidationIssue{AsyncAgent: a.Name, Backend: -1, Path: []interface{}{"async_agent", i}}
+		issues = append(issues, checkExtraConfig(a.ExtraConfig, AsyncAgentScope, issue)...)
+		for j, b := range a.Backend {
+			issue := ValidationIssue{
+				AsyncAgent: a.Name,
+				Backend:    j,
+				Path:       []interface{}{"async_agent", i, "backend", j},
+			}
+			issues = append(issues, checkExtraConfig(b.ExtraConfig, BackendScope, issue)...)
+		}
+	}
+	return issues
+}
+
+func checkExtraConfig(extra ExtraConfig, scope ExtraConfigScope, issue ValidationIssue) []*ValidationIssue {
+	names := make([]string, 0, len(extra))
+	for name := range extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var issues []*ValidationIssue
+	for _, name := range names {
+		i := issue
+		i.Path = append(append([]interface{}{}, issue.Path...), "extra_config", name)
+
+		ns, ok := lookupNamespace(name)
+		switch {
+		case !ok:
+			i.Err = fmt.Errorf("%w: %s", ErrUnknownNamespace, name)
+		case ns.Scope&scope == 0:
+			i.Err = fmt.Errorf("%w: %s (supported at: %s)", ErrNamespaceScope, name, ns.Scope)
+		case ns.schema != nil:
+			if violations := ns.schema.Validate(extra[name]); len(violations) > 0 {
+				i.Err = fmt.Errorf("invalid extra config for the namespace %s: %w", name, &jsonschema.ValidationError{Violations: violations})
+			}
+		}
+		if i.Err != nil {
+			issues = append(issues, &i)
+		}
+	}
+	return issues
+}
@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/luraproject/lura/v2/jsonschema"
+)
+
+func TestRegisterNamespace(t *testing.T) {
+	if err := RegisterNamespace(Namespace{Name: "ns/b", Scope: BackendScope, Schema: []byte(`{"type": "object"}`)}); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterNamespace("ns/b")
+	if err := RegisterNamespace(Namespace{Name: "ns/a"}); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterNamespace("ns/a")
+
+	if err := RegisterNamespace(Namespace{Name: "ns/c", Schema: []byte(`{"pattern": "("}`)}); !errors.Is(err, jsonschema.ErrInvalidSchema) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, ok := LookupNamespace("ns/c"); ok {
+		t.Error("the namespace with an invalid schema should not be registered")
+	}
+
+	ns, ok := LookupNamespace("ns/a")
+	if !ok || ns.Scope != AnyScope {
+		t.Errorf("unexpected namespace: %+v", ns)
+	}
+
+	ExtraConfigAlias["ns/alias"] = "ns/b"
+	defer delete(ExtraConfigAlias, "ns/alias")
+	if ns, ok := LookupNamespace("ns/alias"); !ok || ns.Name != "ns/b" {
+		t.Errorf("unexpected namespace: %+v", ns)
+	}
+
+	found := 0
+	for _, ns := range Namespaces() {
+		if ns.Name == "ns/a" || ns.Name == "ns/b" {
+			found++
+			if found == 1 && ns.Name != "ns/a" {
+				t.Error("the namespaces should be sorted by name")
+			}
+		}
+	}
+	if found != 2 {
+		t.Errorf("unexpected namespaces: %v", Namespaces())
+	}
+
+	UnregisterNamespace("ns/a")
+	if _, ok := LookupNamespace("ns/a"); ok {
+		t.Error("the namespace should be removed")
+	}
+}
+
+func TestExtraConfigScope_String(t *testing.T) {
+	for scope, expected := range map[ExtraConfigScope]string{
+		ServiceScope:                 "service",
+		EndpointScope | BackendScope: "endpoint, backend",
+		AnyScope:                     "service, endpoint, backend, async_agent",
+	} {
+		if s := scope.String(); s != expected {
+			t.Errorf("unexpected value. have: %s, want: %s", s, expected)
+		}
+	}
+}
+
+func TestServiceConfig_ValidateStrict(t *testing.T) {
+	if err := RegisterNamespace(Namespace{
+		Name:   "ns/backend",
+		Scope:  BackendScope,
+		Schema: []byte(`{"type": "object", "required": ["name"]}`),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterNamespace("ns/backend")
+
+	cfg := ServiceConfig{
+		Version: ConfigVersion,
+		Host:    []string{"http://127.0.0.1:8080"},
+		AsyncAgents: []*AsyncAgent{
+			{
+				Name:        "agent",
+				ExtraConfig: ExtraConfig{"ns/backend": map[string]interface{}{"name": "x"}},
+				Backend: []*Backend{
+					{URLPattern: "/", ExtraConfig: ExtraConfig{"ns/backend": map[string]interface{}{}}},
+				},
+			},
+		},
+	}
+
+	r := cfg.Validate()
+	if len(r.Errors) != 0 || len(r.Warnings) != 2 {
+		t.Fatalf("unexpected report: %v %v", r.Errors, r.Warnings)
+	}
+
+	r = cfg.ValidateStrict()
+	if len(r.Errors) != 2 || len(r.Warnings) != 0 {
+		t.Fatalf("unexpected report: %v %v", r.Errors, r.Warnings)
+	}
+	if !errors.Is(r.Errors[0], ErrNamespaceScope) {
+		t.Errorf("unexpected error: %v", r.Errors[0])
+	}
+	var schemaErr *jsonschema.ValidationError
+	if !errors.As(r.Errors[1], &schemaErr) || len(schemaErr.Violations) != 1 {
+		t.Errorf("unexpected error: %v", r.Errors[1])
+	}
+	if msg := r.Errors[1].Error(); msg != "async agent 'agent', backend 0: invalid extra config for the namespace ns/backend: schema validation failed: missing required property \"name\"" {
+		t.Errorf("unexpected message: %s", msg)
+	}
+}
@@ -5,6 +5,7 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"os"
 	"time"
 )
@@ -21,7 +22,10 @@ type ParserFunc func(string) (ServiceConfig, error)
 // Parse implements the Parser interface
 func (f ParserFunc) Parse(configFile string) (ServiceConfig, error) { return f(configFile) }
 
-// NewParser creates a new parser using the json library
+// NewParser creates a new parser using the os.ReadFile function. The format of the file (JSON, YAML
+// or TOML) is selected by its extension, defaulting to JSON, and the ${ENV_VAR} and
+// ${ENV_VAR:-default} references are expanded before decoding it. The values are escaped for the
+// format of the file and the references to unset variables without a default value fail.
 func NewParser() Parser {
 	return NewParserWithFileReader(os.ReadFile)
 }
@@ -32,29 +36,89 @@ func NewParserWithFileReader(f FileReaderFunc) Parser {
 }
 
 type parser struct {
-	fileReader FileReaderFunc
+	fileReader  FileReaderFunc
+	stat        FileStatFunc
+	readDir     DirReaderFunc
+	includes    []string
+	overlays    []string
+	environment string
+	strict      bool
+	migrate     bool
 }
 
 // Parser implements the Parse interface
 func (p parser) Parse(configFile string) (ServiceConfig, error) {
 	var result ServiceConfig
 	var cfg parseableServiceConfig
-	data, err := p.fileReader(configFile)
+	doc, err := p.decode(configFile, &cfg)
 	if err != nil {
-		return result, CheckErr(err, configFile)
+		return result, err
 	}
-	if err = json.Unmarshal(data, &cfg); err != nil {
+	result = cfg.normalize()
+
+	if err := result.ResolveSecrets(); err != nil {
 		return result, CheckErr(err, configFile)
 	}
-	result = cfg.normalize()
 
-	if err = result.Init(); err != nil {
+	if p.strict {
+		if err := p.checkStrict(configFile, doc, &result); err != nil {
+			return result, err
+		}
+	}
+
+	if err := result.Init(); err != nil {
 		return result, CheckErr(err, configFile)
 	}
 
 	return result, nil
 }
 
+// decode decodes the configuration file into the parseable struct. The generic representation of
+// the document is returned just for the strict and migrating parsers.
+func (p parser) decode(configFile string, cfg *parseableServiceConfig) (interface{}, error) {
+	var doc map[string]interface{}
+	if !p.isComposed() {
+		if !p.strict && !p.migrate {
+			return nil, p.decodeFile(configFile, cfg)
+		}
+		if err := p.decodeFile(configFile, cfg, &doc); err != nil {
+			return nil, err
+		}
+		if !p.needsMigration(doc) {
+			return doc, nil
+		}
+	} else {
+		var err error
+		if doc, err = p.compose(configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.needsMigration(doc) {
+		if _, err := Migrate(doc); err != nil {
+			return nil, CheckErr(err, configFile)
+		}
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, CheckErr(err, configFile)
+	}
+	*cfg = parseableServiceConfig{}
+	// the type errors of the merged or migrated document have no position in the source files
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("'%s': %w", configFile, err)
+	}
+	return doc, nil
+}
+
+func (p parser) needsMigration(doc map[string]interface{}) bool {
+	if !p.migrate {
+		return false
+	}
+	v, ok := docVersion(doc["version"])
+	return ok && v < ConfigVersion
+}
+
 // CheckErr returns a proper documented error
 func CheckErr(err error, configFile string) error {
 	switch e := err.(type) {
@@ -70,7 +134,7 @@ func CheckErr(err error, configFile string) error {
 			e.Err.Error(),
 		)
 	default:
-		return fmt.Errorf("'%s': %v", configFile, err)
+		return fmt.Errorf("'%s': %w", configFile, err)
 	}
 }
 
@@ -87,6 +151,17 @@ func NewParseError(err error, configFile string, offset int) *ParseError {
 	}
 }
 
+func newSourceParseError(err error, configFile string, source []byte, offset int) *ParseError {
+	row, col := getErrorRowCol(source, offset)
+	return &ParseError{
+		ConfigFile: configFile,
+		Err:        err,
+		Offset:     offset,
+		Row:        row,
+		Col:        col,
+	}
+}
+
 func getErrorRowCol(source []byte, offset int) (row, col int) {
 	if len(source) < offset {
 		offset = len(source) - 1
@@ -131,6 +206,12 @@ func (p *ParseError) Error() string {
 // FileReaderFunc is a function used to read the content of a config file
 type FileReaderFunc func(string) ([]byte, error)
 
+// FileStatFunc is a function used to get the info of a config file or directory, as os.Stat does
+type FileStatFunc func(string) (fs.FileInfo, error)
+
+// DirReaderFunc is a function used to list the entries of a config directory, as os.ReadDir does
+type DirReaderFunc func(string) ([]fs.DirEntry, error)
+
 type parseableServiceConfig struct {
 	Name                  string                     `json:"name"`
 	Endpoints             []*parseableEndpointConfig `json:"endpoints"`
@@ -167,6 +248,7 @@ type parseableServiceConfig struct {
 	UseH2C                bool                       `json:"use_h2c,omitempty"`
 	DNSCacheTTL           string                     `json:"dns_cache_ttl"`
 	MaxShutdownDuration   string                     `json:"max_shutdown_wait_time"`
+	ResponseLimits        *ResponseLimits            `json:"response_limits,omitempty"`
 }
 
 func (p *parseableServiceConfig) normalize() ServiceConfig {
@@ -201,6 +283,7 @@ func (p *parseableServiceConfig) normalize() ServiceConfig {
 		UseH2C:                p.UseH2C,
 		DNSCacheTTL:           parseDuration(p.DNSCacheTTL),
 		MaxShutdownDuration:   parseDuration(p.MaxShutdownDuration),
+		ResponseLimits:        p.ResponseLimits,
 	}
 	if p.TLS != nil {
 		cfg.TLS = &TLS{
@@ -215,6 +298,8 @@ func (p *parseableServiceConfig) normalize() ServiceConfig {
 			CipherSuites:             p.TLS.CipherSuites,
 			EnableMTLS:               p.TLS.EnableMTLS,
 			DisableSystemCaPool:      p.TLS.DisableSystemCaPool,
+			EnableHTTP3:              p.TLS.EnableHTTP3,
+			HTTP3Port:                p.TLS.HTTP3Port,
 		}
 		for _, k := range p.TLS.Keys {
 			cfg.TLS.Keys = append(cfg.TLS.Keys, TLSKeyPair(k))
@@ -269,6 +354,8 @@ type parseableTLS struct {
 	EnableMTLS               bool                  `json:"enable_mtls"`
 	DisableSystemCaPool      bool                  `json:"disable_system_ca_pool"`
 	Keys                     []parseableTLSKeyPair `json:"keys"`
+	EnableHTTP3              bool                  `json:"enable_http3"`
+	HTTP3Port                int                   `json:"http3_port"`
 }
 
 type parseableClientTLS struct {
@@ -384,6 +471,7 @@ type parseableBackend struct {
 	HeadersToPass            []string          `json:"input_headers"`
 	SDScheme                 string            `json:"sd_scheme"`
 	QueryStringsToPass       []string          `json:"input_query_strings"`
+	ResponseLimits           *ResponseLimits   `json:"response_limits,omitempty"`
 }
 
 func (p *parseableBackend) normalize() *Backend {
@@ -403,6 +491,7 @@ func (p *parseableBackend) normalize() *Backend {
 		DenyList:                 p.DenyList,
 		HeadersToPass:            p.HeadersToPass,
 		QueryStringsToPass:       p.QueryStringsToPass,
+		ResponseLimits:           p.ResponseLimits,
 	}
 	if b.SDScheme == "" {
 		b.SDScheme = "http"
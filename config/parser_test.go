@@ -16,9 +16,12 @@ func TestNewParser_ok(t *testing.T) {
     "cache_ttl": "3600s",
     "timeout": "3s",
     "max_header_bytes": 10000,
+    "response_limits": {"max_body_size": 1024, "max_json_depth": 10},
     "tls": {
 		"public_key":  "cert.pem",
-		"private_key": "key.pem"
+		"private_key": "key.pem",
+		"enable_http3": true,
+		"http3_port": 8443
 	},
 	"async_agent": [
 		{
@@ -55,6 +58,7 @@ func TestNewParser_ok(t *testing.T) {
                         "authorizations_url",
                         "code_search_url"
                     ],
+                    "response_limits": {"max_body_size": 512, "max_decompressed_size": 2048},
                     "extra_config" : {"user":"test","hits":6,"parents":["gomez","morticia"]}
                 }
             ]
@@ -137,6 +141,9 @@ func TestNewParser_ok(t *testing.T) {
 		if serviceConfig.TLS.PrivateKey != "key.pem" {
 			t.Error("Unexpected TLS Private key")
 		}
+		if !serviceConfig.TLS.EnableHTTP3 || serviceConfig.TLS.HTTP3Port != 8443 {
+			t.Error("Unexpected HTTP/3 config:", serviceConfig.TLS.EnableHTTP3, serviceConfig.TLS.HTTP3Port)
+		}
 	}
 
 	backend := endpoint.Backend[0]
@@ -147,6 +154,11 @@ func TestNewParser_ok(t *testing.T) {
 		t.Error("Extra config is not present in BackendConfig")
 	}
 
+	expectedLimits := ResponseLimits{MaxBodySize: 512, MaxDecompressedSize: 2048, MaxJSONDepth: 10}
+	if backend.ResponseLimits == nil || *backend.ResponseLimits != expectedLimits {
+		t.Errorf("unexpected response limits: %+v", backend.ResponseLimits)
+	}
+
 	if err := os.Remove(configPath); err != nil {
 		t.FailNow()
 	}
@@ -0,0 +1,182 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// extraConfigScopes are the scopes of the extra configs of the parseable structs
+var extraConfigScopes = map[reflect.Type]ExtraConfigScope{
+	reflect.TypeOf(parseableServiceConfig{}):  ServiceScope,
+	reflect.TypeOf(parseableEndpointConfig{}): EndpointScope,
+	reflect.TypeOf(parseableBackend{}):        BackendScope,
+	reflect.TypeOf(parseableAsyncAgent{}):     AsyncAgentScope,
+}
+
+var extraConfigDefinitions = map[ExtraConfigScope]string{
+	ServiceScope:    "ServiceExtraConfig",
+	EndpointScope:   "EndpointExtraConfig",
+	BackendScope:    "BackendExtraConfig",
+	AsyncAgentScope: "AsyncAgentExtraConfig",
+}
+
+// requiredFields are the fields rejected by the init process when they are empty. The backends
+// are not required, since the mocked endpoints do not need them.
+var requiredFields = map[reflect.Type][]string{
+	reflect.TypeOf(parseableServiceConfig{}):  {"version"},
+	reflect.TypeOf(parseableEndpointConfig{}): {"endpoint"},
+}
+
+var (
+	extraConfigType = reflect.TypeOf(ExtraConfig{})
+	durationType    = reflect.TypeOf(time.Duration(0))
+)
+
+// JSONSchema returns the JSON Schema (draft-07) of the configuration files, generated from the
+// structs used by the parser and extended with the schemas of the registered extra config
+// namespaces at the levels they support. In strict mode, the unknown fields and the unknown
+// namespaces are rejected, as the strict parsers do, but the field names are case sensitive.
+// The schemas of the namespaces must not contain references to their own definitions, since
+// they are embedded in the generated one.
+func JSONSchema(strict bool) ([]byte, error) {
+	g := schemaGenerator{strict: strict, definitions: map[string]interface{}{}}
+	root := g.structSchema(reflect.TypeOf(parseableServiceConfig{}))
+	root["$schema"] = "http://json-schema.org/draft-07/schema#"
+	root["title"] = "Lura service configuration"
+	root["definitions"] = g.definitions
+	if props, ok := root["properties"].(map[string]interface{}); ok {
+		props["version"] = map[string]interface{}{"type": "integer", "const": ConfigVersion}
+	}
+	return json.MarshalIndent(root, "", "\t")
+}
+
+// StructSchema returns the JSON Schema of the values decoded into the type of v, generated from
+// its fields and their json tags as the schema of the configuration files is, and rejecting the
+// unknown fields. The nested structs are inlined, so the result can be used as the schema of an
+// extra config namespace decoded into a struct:
+//
+//	config.MustRegisterNamespace(config.Namespace{Name: Namespace, Schema: config.StructSchema(options{})})
+//
+// The type must not reference itself. It panics if the schema can not be encoded.
+func StructSchema(v interface{}) json.RawMessage {
+	g := schemaGenerator{strict: true, inline: true}
+	b, err := json.Marshal(g.typeSchema(reflect.TypeOf(v), 0))
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+type schemaGenerator struct {
+	strict bool
+	// inline embeds the schemas of the structs instead of referencing their definitions
+	inline      bool
+	definitions map[string]interface{}
+}
+
+func (g schemaGenerator) typeSchema(t reflect.Type, scope ExtraConfigScope) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == extraConfigType && g.inline:
+		return map[string]interface{}{"type": "object"}
+	case t == extraConfigType:
+		return g.extraConfigSchema(scope)
+	case t == durationType:
+		return map[string]interface{}{"type": "string"}
+	case reflect.PointerTo(t).Implements(jsonUnmarshalerType):
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.typeSchema(t.Elem(), 0)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.typeSchema(t.Elem(), 0)}
+	case reflect.Struct:
+		if t.Name() == "" || g.inline {
+			return g.structSchema(t)
+		}
+		name := schemaDefinitionName(t)
+		if _, ok := g.definitions[name]; !ok {
+			// the placeholder stops the recursion of the self referenced structs
+			g.definitions[name] = nil
+			g.definitions[name] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/definitions/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+func (g schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	scope := extraConfigScopes[t]
+	props := map[string]interface{}{}
+	for _, f := range structFields(t) {
+		props[f.name] = g.typeSchema(f.typ, scope)
+	}
+	res := map[string]interface{}{"type": "object", "properties": props}
+	if required, ok := requiredFields[t]; ok {
+		res["required"] = required
+	}
+	if g.strict {
+		res["additionalProperties"] = false
+	}
+	return res
+}
+
+// extraConfigSchema returns the reference to the schema of the extra configs of the scope, with
+// the namespaces supporting it
+func (g schemaGenerator) extraConfigSchema(scope ExtraConfigScope) map[string]interface{} {
+	name, ok := extraConfigDefinitions[scope]
+	if !ok {
+		return map[string]interface{}{"type": "object"}
+	}
+	if _, ok := g.definitions[name]; ok {
+		return map[string]interface{}{"$ref": "#/definitions/" + name}
+	}
+
+	props := map[string]interface{}{}
+	for _, ns := range Namespaces() {
+		if ns.Scope&scope == 0 {
+			continue
+		}
+		var s interface{} = map[string]interface{}{}
+		if len(ns.Schema) > 0 {
+			if err := json.Unmarshal(ns.Schema, &s); err != nil {
+				continue
+			}
+		}
+		props[ns.Name] = s
+		for alias, target := range ExtraConfigAlias {
+			if target == ns.Name {
+				props[alias] = s
+			}
+		}
+	}
+	g.definitions[name] = map[string]interface{}{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": !g.strict,
+	}
+	return map[string]interface{}{"$ref": "#/definitions/" + name}
+}
+
+func schemaDefinitionName(t reflect.Type) string {
+	name := strings.TrimPrefix(t.Name(), "parseable")
+	return strings.ToUpper(name[:1]) + name[1:]
+}
@@ -0,0 +1,144 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/luraproject/lura/v2/jsonschema"
+)
+
+func TestJSONSchema(t *testing.T) {
+	if err := RegisterNamespace(Namespace{
+		Name:   "schema/test",
+		Scope:  ServiceScope | BackendScope,
+		Schema: []byte(`{"type": "object", "properties": {"limit": {"type": "integer", "maximum": 5}}}`),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterNamespace("schema/test")
+
+	b, err := JSONSchema(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(b, &schema); err != nil {
+		t.Fatal(err)
+	}
+	definitions, _ := schema["definitions"].(map[string]interface{})
+	for _, name := range []string{"EndpointConfig", "Backend", "AsyncAgent", "TLS", "ClientTLS", "ServiceExtraConfig"} {
+		if _, ok := definitions[name]; !ok {
+			t.Errorf("the definition %s is missing", name)
+		}
+	}
+	for name, expected := range map[string]bool{
+		"ServiceExtraConfig":    true,
+		"EndpointExtraConfig":   false,
+		"BackendExtraConfig":    true,
+		"AsyncAgentExtraConfig": false,
+	} {
+		d, _ := definitions[name].(map[string]interface{})
+		props, _ := d["properties"].(map[string]interface{})
+		if _, ok := props["schema/test"]; ok != expected {
+			t.Errorf("%s: unexpected namespaces: %v", name, props)
+		}
+	}
+
+	s, err := jsonschema.CompileJSON(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, content := range []string{formatTestJSON, strictTestJSON} {
+		if violations := validateSchema(t, s, content); len(violations) > 0 {
+			t.Errorf("unexpected violations: %v", violations)
+		}
+	}
+
+	violations := validateSchema(t, s, `{"version": 2, "extra_config": {"schema/test": {"limit": 10}}, "endpoints": [{"method": "GET"}]}`)
+	expected := map[string]bool{"/version": false, "/extra_config/schema~1test/limit": false, "/endpoints/0": false}
+	for _, v := range violations {
+		expected[v.Path] = true
+	}
+	for path, found := range expected {
+		if !found {
+			t.Errorf("the violation at %s is missing: %v", path, violations)
+		}
+	}
+}
+
+func TestJSONSchema_strict(t *testing.T) {
+	b, err := JSONSchema(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := jsonschema.CompileJSON(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	violations := validateSchema(t, s, strictTestJSON)
+	expected := map[string]bool{
+		"/timout":                           false,
+		"/tls/privat_key":                   false,
+		"/extra_config/strict~1test":        false,
+		"/endpoints/0/concurent_calls":      false,
+		"/endpoints/0/backend/0/url_patern": false,
+		"/async_agent/0/consumer/workerz":   false,
+	}
+	for _, v := range violations {
+		expected[v.Path] = true
+	}
+	for path, found := range expected {
+		if !found {
+			t.Errorf("the violation at %s is missing: %v", path, violations)
+		}
+	}
+
+	if violations := validateSchema(t, s, formatTestJSON); len(violations) != 2 {
+		t.Errorf("the unknown namespaces should be rejected: %v", violations)
+	}
+}
+
+func TestStructSchema(t *testing.T) {
+	type limits struct {
+		Max *int `json:"max"`
+	}
+	type options struct {
+		Name    string        `json:"name"`
+		Enabled *bool         `json:"enabled,omitempty"`
+		Hosts   []string      `json:"hosts"`
+		Limits  limits        `json:"limits"`
+		Timeout time.Duration `json:"timeout"`
+		Ignored string        `json:"-"`
+	}
+
+	s, err := jsonschema.CompileJSON(StructSchema(options{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if violations := validateSchema(t, s, `{"name": "a", "enabled": true, "hosts": ["b"], "limits": {"max": 1}, "timeout": "1s"}`); len(violations) > 0 {
+		t.Errorf("unexpected violations: %v", violations)
+	}
+	violations := validateSchema(t, s, `{"name": 1, "limits": {"max": "1", "min": 0}, "Ignored": ""}`)
+	expected := map[string]bool{"/name": false, "/limits/max": false, "/limits/min": false, "/Ignored": false}
+	for _, v := range violations {
+		expected[v.Path] = true
+	}
+	for path, found := range expected {
+		if !found {
+			t.Errorf("the violation at %s is missing: %v", path, violations)
+		}
+	}
+}
+
+func validateSchema(t *testing.T, s *jsonschema.Schema, content string) []jsonschema.Violation {
+	t.Helper()
+	var doc interface{}
+	if err := json.Unmarshal([]byte(content), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return s.Validate(doc)
+}
@@ -0,0 +1,328 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrUnknownSecretScheme is the error returned when a secret reference uses a scheme without a
+// registered SecretResolver
+var ErrUnknownSecretScheme = errors.New("unknown secret scheme")
+
+// SecretResolver resolves the value of the secret references of a given scheme
+type SecretResolver interface {
+	Resolve(ref string) (string, error)
+}
+
+// SecretResolverFunc type is an adapter to allow the use of ordinary functions as secret resolvers
+type SecretResolverFunc func(string) (string, error)
+
+// Resolve implements the SecretResolver interface
+func (f SecretResolverFunc) Resolve(ref string) (string, error) { return f(ref) }
+
+// SecretError is the error returned when a secret reference can not be resolved
+type SecretError struct {
+	Reference string
+	Err       error
+}
+
+// Error returns a string representation of the SecretError
+func (s *SecretError) Error() string {
+	return fmt.Sprintf("resolving the secret %s: %s", s.Reference, s.Err.Error())
+}
+
+// Unwrap returns the wrapped error
+func (s *SecretError) Unwrap() error { return s.Err }
+
+// secretReference matches the ${scheme:ref} references, like ${file:/run/secrets/x} or
+// ${env:NAME}
+var secretReference = regexp.MustCompile(`\$\{([a-z][a-z0-9+.-]*):([^}]+)\}`)
+
+var (
+	secretResolversMutex = &sync.RWMutex{}
+	secretResolvers      = map[string]SecretResolver{}
+
+	endpointConfigType = reflect.TypeOf(EndpointConfig{})
+
+	// secretsDigestKey is the key of the secret digests, so they can not be used to guess the
+	// values of the secrets
+	secretsDigestKey = newSecretsDigestKey()
+)
+
+func newSecretsDigestKey() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// secretSet indexes the original values of the strings containing resolved secrets by their
+// resolved values, so they can be redacted
+type secretSet map[string]string
+
+// digest returns the keyed hash of the resolved values of the set
+func (s secretSet) digest() string {
+	resolved := make([]string, 0, len(s))
+	for r := range s {
+		resolved = append(resolved, r)
+	}
+	sort.Strings(resolved)
+	mac := hmac.New(sha256.New, secretsDigestKey)
+	for _, r := range resolved {
+		mac.Write([]byte(s[r]))
+		mac.Write([]byte{0})
+		mac.Write([]byte(r))
+		mac.Write([]byte{0})
+	}
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func init() {
+	RegisterSecretResolver("file", SecretResolverFunc(resolveFileSecret))
+	RegisterSecretResolver("env", SecretResolverFunc(resolveEnvSecret))
+}
+
+// RegisterSecretResolver registers the resolver for the references using the scheme
+func RegisterSecretResolver(scheme string, r SecretResolver) {
+	secretResolversMutex.Lock()
+	secretResolvers[strings.ToLower(scheme)] = r
+	secretResolversMutex.Unlock()
+}
+
+// UnregisterSecretResolver removes the resolver of the scheme
+func UnregisterSecretResolver(scheme string) {
+	secretResolversMutex.Lock()
+	delete(secretResolvers, strings.ToLower(scheme))
+	secretResolversMutex.Unlock()
+}
+
+func resolveFileSecret(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+func resolveEnvSecret(name string) (string, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("the env var %s is not defined", name)
+	}
+	return v, nil
+}
+
+// ResolveSecrets replaces the secret references found in the string values of the config,
+// including the extra config trees. The resolved values are kept out of the hashes and the
+// redacted representations of the config, and their changes are tracked by the secret digests.
+func (s *ServiceConfig) ResolveSecrets() error {
+	s.secrets = secretSet{}
+	return resolveSecrets(reflect.ValueOf(s).Elem(), s.secrets)
+}
+
+// SecretsDigest returns a keyed hash of the values of the secrets resolved out of the endpoints,
+// so the rotation of a secret can be detected, even if the hash of the config does not change.
+// The digests are comparable just inside the same process.
+func (s *ServiceConfig) SecretsDigest() string {
+	return s.secrets.digest()
+}
+
+// SecretsDigest returns a keyed hash of the values of the secrets resolved in the endpoint and
+// its backends. The digests are comparable just inside the same process.
+func (e *EndpointConfig) SecretsDigest() string {
+	return e.secrets.digest()
+}
+
+// MarshalRedacted returns the JSON representation of the config with the strings containing
+// resolved secrets replaced by their original references. It should be used when the config
+// is hashed, logged or exposed.
+func (s *ServiceConfig) MarshalRedacted() ([]byte, error) {
+	secrets := secretSet{}
+	for r, original := range s.secrets {
+		secrets[r] = original
+	}
+	for _, e := range s.Endpoints {
+		for r, original := range e.secrets {
+			secrets[r] = original
+		}
+	}
+	return marshalRedacted(s, secrets)
+}
+
+// MarshalRedacted returns the JSON representation of the endpoint config with the strings
+// containing resolved secrets replaced by their original references
+func (e *EndpointConfig) MarshalRedacted() ([]byte, error) {
+	return marshalRedacted(e, e.secrets)
+}
+
+// resolveSecrets resolves the secrets of the value, adding them to the set. The endpoints
+// collect their secrets in their own sets.
+func resolveSecrets(v reflect.Value, secrets secretSet) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		return resolveSecrets(v.Elem(), secrets)
+	case reflect.Struct:
+		if v.Type() == endpointConfigType && v.CanAddr() {
+			e := v.Addr().Interface().(*EndpointConfig)
+			e.secrets = secretSet{}
+			secrets = e.secrets
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Field(i); f.CanSet() {
+				if err := resolveSecrets(f, secrets); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := resolveSecrets(v.Index(i), secrets); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			value := reflect.New(iter.Value().Type()).Elem()
+			value.Set(iter.Value())
+			if err := resolveSecrets(value, secrets); err != nil {
+				return err
+			}
+			v.SetMapIndex(iter.Key(), value)
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		value := reflect.New(v.Elem().Type()).Elem()
+		value.Set(v.Elem())
+		if err := resolveSecrets(value, secrets); err != nil {
+			return err
+		}
+		v.Set(value)
+	case reflect.String:
+		resolved, err := resolveSecretString(v.String())
+		if err != nil {
+			return err
+		}
+		if resolved != v.String() {
+			secrets[resolved] = v.String()
+		}
+		v.SetString(resolved)
+	}
+	return nil
+}
+
+func resolveSecretString(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var err error
+	resolved := secretReference.ReplaceAllStringFunc(s, func(ref string) string {
+		if err != nil {
+			return ref
+		}
+		m := secretReference.FindStringSubmatch(ref)
+		secretResolversMutex.RLock()
+		r, ok := secretResolvers[m[1]]
+		secretResolversMutex.RUnlock()
+		if !ok {
+			err = &SecretError{Reference: ref, Err: ErrUnknownSecretScheme}
+			return ref
+		}
+		value, rErr := r.Resolve(m[2])
+		if rErr != nil {
+			err = &SecretError{Reference: ref, Err: rErr}
+			return ref
+		}
+		return value
+	})
+	if err != nil {
+		return s, err
+	}
+	return resolved, nil
+}
+
+func marshalRedacted(v interface{}, secrets secretSet) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil || len(secrets) == 0 {
+		return b, err
+	}
+	return redactSecrets(b, secrets)
+}
+
+// redactSecrets re-encodes the JSON content token by token, so the result is equal to the
+// received content except for the redacted strings
+func redactSecrets(b []byte, secrets secretSet) ([]byte, error) {
+	type container struct {
+		object bool
+		items  int
+	}
+	var stack []*container
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	out := bytes.NewBuffer(make([]byte, 0, len(b)))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return out.Bytes(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if d, ok := tok.(json.Delim); ok && (d == '}' || d == ']') {
+			stack = stack[:len(stack)-1]
+			out.WriteRune(rune(d))
+			continue
+		}
+		isKey := false
+		if len(stack) > 0 {
+			c := stack[len(stack)-1]
+			isKey = c.object && c.items%2 == 0
+			switch {
+			case c.object && !isKey:
+				out.WriteByte(':')
+			case c.items > 0:
+				out.WriteByte(',')
+			}
+			c.items++
+		}
+
+		switch t := tok.(type) {
+		case json.Delim:
+			out.WriteRune(rune(t))
+			stack = append(stack, &container{object: t == '{'})
+			continue
+		case string:
+			if original, ok := secrets[t]; ok && !isKey {
+				tok = original
+			}
+		}
+		v, err := json.Marshal(tok)
+		if err != nil {
+			return nil, err
+		}
+		out.Write(v)
+	}
+}
@@ -0,0 +1,192 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const secretTestConfig = `{
+	"version": 3,
+	"host": ["http://127.0.0.1:8080"],
+	"tls": {"public_key": "cert.pem", "private_key": "${file:%s}"},
+	"extra_config": {"auth/basic": {"user": "admin", "password": "${env:LURA_SECRET_TEST_PASSWORD}"}},
+	"endpoints": [
+		{
+			"endpoint": "/a",
+			"backend": [
+				{
+					"url_pattern": "/a",
+					"extra_config": {"auth/token": {"headers": ["Bearer ${env:LURA_SECRET_TEST_TOKEN}"]}}
+				}
+			]
+		}
+	]
+}`
+
+func TestNewParser_secrets(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "key")
+	if err := os.WriteFile(secretFile, []byte("s3cr3t-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	configFile := filepath.Join(dir, "lura.json")
+	content := strings.Replace(secretTestConfig, "%s", secretFile, 1)
+	if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("LURA_SECRET_TEST_PASSWORD", "p4ssw0rd")
+	t.Setenv("LURA_SECRET_TEST_TOKEN", "t0k3n")
+
+	cfg, err := NewParser().Parse(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.TLS.PrivateKey != "s3cr3t-key" {
+		t.Errorf("unexpected private key: %s", cfg.TLS.PrivateKey)
+	}
+	if p := cfg.ExtraConfig["auth/basic"].(map[string]interface{})["password"]; p != "p4ssw0rd" {
+		t.Errorf("unexpected password: %v", p)
+	}
+	headers := cfg.Endpoints[0].Backend[0].ExtraConfig["auth/token"].(map[string]interface{})["headers"].([]interface{})
+	if headers[0] != "Bearer t0k3n" {
+		t.Errorf("unexpected headers: %v", headers)
+	}
+
+	b, err := cfg.MarshalRedacted()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"s3cr3t-key", "p4ssw0rd", "t0k3n"} {
+		if bytes.Contains(b, []byte(secret)) {
+			t.Errorf("the secret %s has not been redacted: %s", secret, string(b))
+		}
+	}
+	for _, ref := range []string{"${env:LURA_SECRET_TEST_PASSWORD}", "Bearer ${env:LURA_SECRET_TEST_TOKEN}"} {
+		if !bytes.Contains(b, []byte(ref)) {
+			t.Errorf("the reference %s is missing: %s", ref, string(b))
+		}
+	}
+
+	serviceHash, err := cfg.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpointHash, err := cfg.Endpoints[0].Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	same, err := NewParser().Parse(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same.SecretsDigest() != cfg.SecretsDigest() {
+		t.Error("unexpected service secrets digest")
+	}
+	if same.Endpoints[0].SecretsDigest() != cfg.Endpoints[0].SecretsDigest() {
+		t.Error("unexpected endpoint secrets digest")
+	}
+
+	// the hashes do not depend on the values of the secrets, but the digests do
+	t.Setenv("LURA_SECRET_TEST_PASSWORD", "rotated")
+	t.Setenv("LURA_SECRET_TEST_TOKEN", "rotated-token")
+	rotated, err := NewParser().Parse(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h, _ := rotated.Hash(); h != serviceHash {
+		t.Error("the service hash depends on the secret values")
+	}
+	if h, _ := rotated.Endpoints[0].Hash(); h != endpointHash {
+		t.Error("the endpoint hash depends on the secret values")
+	}
+	if rotated.SecretsDigest() == cfg.SecretsDigest() {
+		t.Error("the service secrets digest does not depend on the secret values")
+	}
+	if rotated.Endpoints[0].SecretsDigest() == cfg.Endpoints[0].SecretsDigest() {
+		t.Error("the endpoint secrets digest does not depend on the secret values")
+	}
+
+	// the secrets of a config are not redacted in the other ones
+	other := ServiceConfig{Name: "t0k3n", ExtraConfig: ExtraConfig{"ns": "p4ssw0rd"}}
+	if b, _ := other.MarshalRedacted(); !bytes.Contains(b, []byte("p4ssw0rd")) {
+		t.Errorf("unexpected redaction: %s", string(b))
+	}
+}
+
+func TestNewParser_secretErrors(t *testing.T) {
+	dir := t.TempDir()
+	for name, tc := range map[string]struct {
+		value  string
+		expErr error
+	}{
+		"unknown scheme": {value: "${vault:secret/data/x}", expErr: ErrUnknownSecretScheme},
+		"undefined env":  {value: "${env:LURA_SECRET_TEST_UNDEFINED}"},
+		"missing file":   {value: "${file:" + filepath.Join(dir, "unknown") + "}", expErr: os.ErrNotExist},
+	} {
+		configFile := filepath.Join(dir, "lura.json")
+		content := `{"version": 3, "extra_config": {"ns": {"key": "` + tc.value + `"}}, "endpoints": []}`
+		if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := NewParser().Parse(configFile)
+		if err == nil {
+			t.Errorf("%s: error expected", name)
+			continue
+		}
+		if !strings.Contains(err.Error(), "resolving the secret "+tc.value) {
+			t.Errorf("%s: unexpected error: %s", name, err.Error())
+		}
+		var secretErr *SecretError
+		if !errors.As(err, &secretErr) {
+			t.Errorf("%s: unexpected error type %T", name, err)
+		}
+		if tc.expErr != nil && !errors.Is(err, tc.expErr) {
+			t.Errorf("%s: unexpected error: %s", name, err.Error())
+		}
+	}
+}
+
+func TestRegisterSecretResolver(t *testing.T) {
+	RegisterSecretResolver("vault", SecretResolverFunc(func(ref string) (string, error) {
+		return "value-of-" + ref, nil
+	}))
+	defer UnregisterSecretResolver("vault")
+
+	cfg := ServiceConfig{Endpoints: []*EndpointConfig{{Endpoint: "/a", ExtraConfig: ExtraConfig{"ns": "${vault:x}"}}}}
+	if err := cfg.ResolveSecrets(); err != nil {
+		t.Fatal(err)
+	}
+	if v := cfg.Endpoints[0].ExtraConfig["ns"]; v != "value-of-x" {
+		t.Errorf("unexpected value: %v", v)
+	}
+}
+
+func TestRedactSecrets(t *testing.T) {
+	in := []byte(`{"a":[1,2.5e10,"x",{"b":null,"c":true}],"d":{},"e":[],"\u003ctag\u003e":"v\"q\u0026"}`)
+	out, err := redactSecrets(in, secretSet{"secret": "${env:X}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(in, out) {
+		t.Errorf("unexpected result: %s", string(out))
+	}
+
+	b, _ := json.Marshal(ServiceConfig{Name: "name", Port: 8080, Endpoints: []*EndpointConfig{{Endpoint: "/a"}}})
+	out, err = redactSecrets(b, secretSet{"secret": "${env:X}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, out) {
+		t.Errorf("unexpected result: %s", string(out))
+	}
+}
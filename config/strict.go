@@ -0,0 +1,145 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ErrUnknownField is the issue reported by the strict parsers when the configuration contains a
+// field not supported at its level, usually because of a typo
+var ErrUnknownField = errors.New("unknown field")
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// unknownFieldIssues returns the issues of the fields of the decoded document not defined in the
+// parseable structs
+func unknownFieldIssues(doc interface{}) []*ValidationIssue {
+	var issues []*ValidationIssue
+	findUnknownFields(doc, reflect.TypeOf(parseableServiceConfig{}), ValidationIssue{Backend: -1}, &issues)
+	return issues
+}
+
+func findUnknownFields(v interface{}, t reflect.Type, issue ValidationIssue, issues *[]*ValidationIssue) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return
+		}
+		fields := jsonFields(t)
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			i := issue
+			i.Path = append(append([]interface{}{}, issue.Path...), k)
+			f, ok := fields[strings.ToLower(k)]
+			if !ok {
+				i.Err = fmt.Errorf("%w: %s", ErrUnknownField, k)
+				*issues = append(*issues, &i)
+				continue
+			}
+			findUnknownFields(m[k], f, i, issues)
+		}
+	case reflect.Slice, reflect.Array:
+		a, ok := v.([]interface{})
+		if !ok {
+			return
+		}
+		for idx, item := range a {
+			i := issue
+			i.Path = append(append([]interface{}{}, issue.Path...), idx)
+			describeItem(&i, item)
+			findUnknownFields(item, t.Elem(), i, issues)
+		}
+	}
+}
+
+// describeItem sets the endpoint, async agent and backend of the issues found in the item
+func describeItem(issue *ValidationIssue, item interface{}) {
+	m, _ := item.(map[string]interface{})
+	path := issue.Path
+	switch {
+	case len(path) == 2 && path[0] == "endpoints":
+		issue.Endpoint, _ = m["endpoint"].(string)
+		issue.Method, _ = m["method"].(string)
+	case len(path) == 2 && path[0] == "async_agent":
+		issue.AsyncAgent, _ = m["name"].(string)
+	case len(path) == 4 && path[2] == "backend":
+		issue.Backend, _ = path[3].(int)
+	}
+}
+
+// jsonFields returns the types of the fields of the struct indexed by their lowercased JSON
+// names, since the decoder matches them case-insensitively
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	res := map[string]reflect.Type{}
+	for _, f := range structFields(t) {
+		res[strings.ToLower(f.name)] = f.typ
+	}
+	return res
+}
+
+type structField struct {
+	name string
+	typ  reflect.Type
+}
+
+// structFields returns the fields of the struct decoded by the JSON decoder, including the ones of
+// the embedded structs
+func structFields(t reflect.Type) []structField {
+	var res []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				res = append(res, structFields(ft)...)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		res = append(res, structField{name: name, typ: f.Type})
+	}
+	return res
+}
+
+// checkStrict returns a *ValidationError with the unknown fields of the decoded document and the
+// issues of the extra config namespaces
+func (p parser) checkStrict(configFile string, doc interface{}, cfg *ServiceConfig) error {
+	issues := append(unknownFieldIssues(doc), cfg.extraConfigIssues()...)
+	if len(issues) == 0 {
+		return nil
+	}
+	if !p.isComposed() {
+		p.locateIssues(configFile, issues)
+	}
+	return &ValidationError{Issues: issues}
+}
@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const strictTestJSON = `{
+	"version": 3,
+	"host": ["http://127.0.0.1:8080"],
+	"timout": "3s",
+	"tls": {"public_key": "cert.pem", "privat_key": "key.pem"},
+	"extra_config": {"strict/test": {"limit": 10}},
+	"endpoints": [
+		{
+			"endpoint": "/users/{id}",
+			"concurent_calls": 2,
+			"backend": [
+				{"url_patern": "/users/{id}", "URL_PATTERN": "/users/{id}", "mapping": {"any": "key"}}
+			]
+		}
+	],
+	"async_agent": [
+		{"name": "agent", "consumer": {"topic": "t", "timeout": "1s", "workerz": 2}, "backend": [{"url_pattern": "/"}]}
+	]
+}`
+
+func TestNewParserWithConfig_strict(t *testing.T) {
+	if err := RegisterNamespace(Namespace{Name: "strict/test", Scope: ServiceScope}); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterNamespace("strict/test")
+
+	readFile := func(string) ([]byte, error) { return []byte(strictTestJSON), nil }
+
+	if _, err := NewParserWithFileReader(readFile).Parse("strict.json"); err != nil {
+		t.Errorf("the default parser should ignore the unknown fields: %v", err)
+	}
+
+	_, err := NewParserWithConfig(ParserConfig{FileReader: readFile, Strict: true}).Parse("strict.json")
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !errors.Is(err, ErrUnknownField) {
+		t.Error("the error should wrap ErrUnknownField")
+	}
+
+	expected := []string{
+		"'strict.json': async agent 'agent': unknown field: workerz, offset: 469, row: 16, col: 76",
+		"'strict.json': endpoint '/users/{id}', backend 0: unknown field: url_patern, offset: 291, row: 11, col: 20",
+		"'strict.json': endpoint '/users/{id}': unknown field: concurent_calls, offset: 253, row: 9, col: 23",
+		"'strict.json': unknown field: timout, offset: 65, row: 3, col: 12",
+		"'strict.json': unknown field: privat_key, offset: 120, row: 4, col: 50",
+	}
+	if len(validationErr.Issues) != len(expected) {
+		t.Fatalf("unexpected issues: %v", validationErr.Issues)
+	}
+	for i, issue := range validationErr.Issues {
+		if msg := issue.Error(); msg != expected[i] {
+			t.Errorf("#%d: unexpected message. have: %s, want: %s", i, msg, expected[i])
+		}
+	}
+}
+
+func TestNewParserWithConfig_strictNamespaces(t *testing.T) {
+	if err := RegisterNamespace(Namespace{
+		Name:   "strict/test",
+		Scope:  ServiceScope,
+		Schema: []byte(`{"type": "object", "properties": {"limit": {"type": "integer", "maximum": 5}}}`),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterNamespace("strict/test")
+
+	content := `version: 3
+host: ["http://127.0.0.1:8080"]
+extra_config:
+  strict/test:
+    limit: 10
+endpoints:
+  - endpoint: /
+    extra_config:
+      strict/test: {}
+      strict/unknown: true
+    backend:
+      - url_pattern: /
+`
+	readFile := func(string) ([]byte, error) { return []byte(content), nil }
+	_, err := NewParserWithConfig(ParserConfig{FileReader: readFile, Strict: true}).Parse("strict.yaml")
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []struct {
+		err error
+		msg string
+	}{
+		{
+			msg: "'strict.yaml': invalid extra config for the namespace strict/test: schema validation failed: /limit: expected a value less than or equal to 5, offset: 77, row: 4, col: 5",
+		},
+		{
+			err: ErrNamespaceScope,
+			msg: "'strict.yaml': endpoint '/': extra config namespace not supported at this level: strict/test (supported at: service), offset: 151, row: 8, col: 20",
+		},
+		{
+			err: ErrUnknownNamespace,
+			msg: "'strict.yaml': endpoint '/': unknown extra config namespace: strict/unknown, offset: 176, row: 9, col: 23",
+		},
+	}
+	if len(validationErr.Issues) != len(expected) {
+		t.Fatalf("unexpected issues: %v", validationErr.Issues)
+	}
+	for i, e := range expected {
+		issue := validationErr.Issues[i]
+		if e.err != nil && !errors.Is(issue, e.err) {
+			t.Errorf("#%d: unexpected error: %v", i, issue.Err)
+		}
+		if msg := issue.Error(); msg != e.msg {
+			t.Errorf("#%d: unexpected message. have: %s, want: %s", i, msg, e.msg)
+		}
+	}
+}
+
+func TestValidateFile_strict(t *testing.T) {
+	readFile := func(string) ([]byte, error) { return []byte(strictTestJSON), nil }
+
+	_, r, err := ValidateFile("strict.json", ParserConfig{FileReader: readFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(r.Warnings) != 1 || !errors.Is(r.Warnings[0], ErrUnknownNamespace) {
+		t.Errorf("unexpected warnings: %v", r.Warnings)
+	}
+
+	_, r, err = ValidateFile("strict.json", ParserConfig{FileReader: readFile, Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Errors) != 6 {
+		t.Fatalf("unexpected errors: %v", r.Errors)
+	}
+	for _, issue := range r.Errors[:5] {
+		if !errors.Is(issue, ErrUnknownField) {
+			t.Errorf("unexpected error: %v", issue)
+		}
+	}
+	if !errors.Is(r.Errors[5], ErrUnknownNamespace) {
+		t.Errorf("unexpected error: %v", r.Errors[5])
+	}
+	if len(r.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", r.Warnings)
+	}
+}
+
+func TestNewParserWithConfig_strictComposed(t *testing.T) {
+	files := map[string]string{
+		"base.json":    `{"version": 3, "host": ["http://127.0.0.1:8080"], "endpoints": [{"endpoint": "/", "backend": [{"url_pattern": "/"}]}]}`,
+		"overlay.json": `{"endpoints": [{"endpoint": "/", "backend": [{"url_patern": "/x"}]}]}`,
+	}
+	readFile := func(name string) ([]byte, error) { return []byte(files[name]), nil }
+	_, err := NewParserWithConfig(ParserConfig{
+		FileReader: readFile,
+		Overlays:   []string{"overlay.json"},
+		Strict:     true,
+	}).Parse("base.json")
+	if err == nil {
+		t.Fatal("expecting an error")
+	}
+	if msg := err.Error(); !strings.HasSuffix(msg, "endpoint '/', backend 0: unknown field: url_patern") {
+		t.Errorf("unexpected error: %s", msg)
+	}
+}
@@ -0,0 +1,286 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/luraproject/lura/v2/encoding"
+)
+
+var (
+	// ErrDuplicatedEndpoint is the warning reported when several endpoints share the same method and path
+	ErrDuplicatedEndpoint = errors.New("duplicated endpoint")
+	// ErrNonGETBackendInMerge is the warning reported when a backend merged with others is not
+	// using the GET method, so the request body is consumed just by one of them
+	ErrNonGETBackendInMerge = errors.New("non-GET backend in a parallel merge")
+	// ErrUnknownSDDriver is the warning reported when a backend uses a service discovery driver not
+	// registered, so the static one is used instead
+	ErrUnknownSDDriver = errors.New("unknown sd driver")
+	// ErrUnknownEncoding is the warning reported when a backend uses an encoding without a registered
+	// decoder, so the JSON one is used instead
+	ErrUnknownEncoding = errors.New("unknown encoding")
+)
+
+var (
+	sdDriversMutex = &sync.RWMutex{}
+	sdDrivers      = map[string]struct{}{"": {}, "static": {}}
+)
+
+// RegisterSDDriver adds the name of a service discovery driver to the set of drivers accepted
+// by the validation
+func RegisterSDDriver(name string) {
+	sdDriversMutex.Lock()
+	sdDrivers[name] = struct{}{}
+	sdDriversMutex.Unlock()
+}
+
+func isKnownSDDriver(name string) bool {
+	sdDriversMutex.RLock()
+	_, ok := sdDrivers[name]
+	sdDriversMutex.RUnlock()
+	return ok
+}
+
+// SourcePosition is the position of a value in a config file
+type SourcePosition struct {
+	ConfigFile string
+	Offset     int
+	Row        int
+	Col        int
+}
+
+// ValidationIssue is an error or a warning found while validating the configuration
+type ValidationIssue struct {
+	// Endpoint and Method identify the endpoint of the issue, if any
+	Endpoint string
+	Method   string
+	// AsyncAgent is the name of the async agent of the issue, if any
+	AsyncAgent string
+	// Backend is the index of the backend of the issue or -1
+	Backend int
+	// Path is the location of the issue in the config document (keys and indexes)
+	Path []interface{}
+	// Source is the position of the issue in the config file, when available
+	Source *SourcePosition
+	Err    error
+}
+
+// Error returns a string representation of the ValidationIssue
+func (v *ValidationIssue) Error() string {
+	var location []string
+	switch {
+	case v.Endpoint != "":
+		location = append(location, fmt.Sprintf("endpoint '%s'", strings.TrimSpace(v.Method+" "+v.Endpoint)))
+	case v.AsyncAgent != "":
+		location = append(location, fmt.Sprintf("async agent '%s'", v.AsyncAgent))
+	}
+	if v.Backend >= 0 && (v.Endpoint != "" || v.AsyncAgent != "") {
+		location = append(location, fmt.Sprintf("backend %d", v.Backend))
+	}
+	msg := v.Err.Error()
+	if len(location) > 0 {
+		msg = strings.Join(location, ", ") + ": " + msg
+	}
+	if v.Source != nil {
+		msg = fmt.Sprintf("'%s': %s, offset: %d, row: %d, col: %d", v.Source.ConfigFile, msg, v.Source.Offset, v.Source.Row, v.Source.Col)
+	}
+	return msg
+}
+
+// Unwrap returns the wrapped error
+func (v *ValidationIssue) Unwrap() error { return v.Err }
+
+// ValidationError is the error collecting all the errors found while validating the configuration
+type ValidationError struct {
+	Issues []*ValidationIssue
+}
+
+// Error returns a string representation of the ValidationError
+func (v *ValidationError) Error() string {
+	msgs := make([]string, len(v.Issues))
+	for i, issue := range v.Issues {
+		msgs[i] = "\t" + issue.Error()
+	}
+	return fmt.Sprintf("%d errors found in the configuration:\n%s", len(v.Issues), strings.Join(msgs, "\n"))
+}
+
+// Unwrap returns the errors of the issues
+func (v *ValidationError) Unwrap() []error {
+	errs := make([]error, len(v.Issues))
+	for i, issue := range v.Issues {
+		errs[i] = issue
+	}
+	return errs
+}
+
+// ValidationReport contains the errors and the warnings found while validating the configuration
+type ValidationReport struct {
+	Errors   []*ValidationIssue
+	Warnings []*ValidationIssue
+}
+
+// Err returns the errors of the report as a *ValidationError, or nil if there are none
+func (r *ValidationReport) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	return &ValidationError{Issues: r.Errors}
+}
+
+// collect returns the error if there is no report to collect it, so the init process stops
+func (r *ValidationReport) collect(err error, issue ValidationIssue) error {
+	if r == nil {
+		return err
+	}
+	issue.Err = err
+	r.Errors = append(r.Errors, &issue)
+	return nil
+}
+
+func (r *ValidationReport) warn(err error, issue ValidationIssue) {
+	issue.Err = err
+	r.Warnings = append(r.Warnings, &issue)
+}
+
+func (r *ValidationReport) issues() []*ValidationIssue {
+	return append(append([]*ValidationIssue{}, r.Errors...), r.Warnings...)
+}
+
+// Validate initializes the configuration like Init, but without stopping at the first error. The
+// report contains every error found and the warnings about suspicious but legal setups, like
+// duplicated endpoints, non-GET backends in parallel merges, unknown sd drivers and encodings or
+// unknown and invalid extra config namespaces.
+func (s *ServiceConfig) Validate() *ValidationReport {
+	return s.validate(false)
+}
+
+// ValidateStrict is like Validate, but the issues of the extra config namespaces are reported as
+// errors
+func (s *ServiceConfig) ValidateStrict() *ValidationReport {
+	return s.validate(true)
+}
+
+func (s *ServiceConfig) validate(strict bool) *ValidationReport {
+	r := &ValidationReport{}
+	if err := s.init(r); err != nil {
+		// the errors are collected, so this should not happen
+		r.Errors = append(r.Errors, &ValidationIssue{Backend: -1, Err: err})
+	}
+	s.warn(r)
+	if strict {
+		r.Errors = append(r.Errors, s.extraConfigIssues()...)
+	} else {
+		r.Warnings = append(r.Warnings, s.extraConfigIssues()...)
+	}
+	return r
+}
+
+func (s *ServiceConfig) warn(r *ValidationReport) {
+	seen := map[string]struct{}{}
+	for i, e := range s.Endpoints {
+		key := e.Method + " " + e.Endpoint
+		if _, ok := seen[key]; ok {
+			r.warn(ErrDuplicatedEndpoint, endpointIssue(i, e, -1))
+		}
+		seen[key] = struct{}{}
+
+		for j, b := range e.Backend {
+			if len(e.Backend) > 1 && b.Method != "" && !strings.EqualFold(b.Method, http.MethodGet) {
+				r.warn(ErrNonGETBackendInMerge, endpointIssue(i, e, j))
+			}
+			warnBackend(r, b, endpointIssue(i, e, j))
+		}
+	}
+	for i, a := range s.AsyncAgents {
+		for j, b := range a.Backend {
+			warnBackend(r, b, ValidationIssue{
+				AsyncAgent: a.Name,
+				Backend:    j,
+				Path:       []interface{}{"async_agent", i, "backend", j},
+			})
+		}
+	}
+}
+
+func warnBackend(r *ValidationReport, b *Backend, issue ValidationIssue) {
+	if !isKnownSDDriver(b.SD) {
+		i := issue
+		i.Path = append(append([]interface{}{}, issue.Path...), "sd")
+		r.warn(fmt.Errorf("%w: %s", ErrUnknownSDDriver, b.SD), i)
+	}
+	if b.Encoding != "" && !encoding.GetRegister().Has(strings.ToLower(b.Encoding)) {
+		i := issue
+		i.Path = append(append([]interface{}{}, issue.Path...), "encoding")
+		r.warn(fmt.Errorf("%w: %s", ErrUnknownEncoding, b.Encoding), i)
+	}
+}
+
+func endpointIssue(i int, e *EndpointConfig, backend int) ValidationIssue {
+	path := []interface{}{"endpoints", i}
+	if backend >= 0 {
+		path = append(path, "backend", backend)
+	}
+	return ValidationIssue{
+		Endpoint: e.Endpoint,
+		Method:   e.Method,
+		Backend:  backend,
+		Path:     path,
+	}
+}
+
+// ValidateFile parses the configuration file like the parser defined by the ParserConfig, but
+// validating it instead of initializing it. In strict mode, the unknown fields are reported as
+// errors too. The issues are located in the configuration file when it is not composed with other
+// files. The returned error is not nil only if the file can not be decoded.
+func ValidateFile(configFile string, cfg ParserConfig) (ServiceConfig, *ValidationReport, error) {
+	p := newParser(cfg)
+	var result ServiceConfig
+	var parseable parseableServiceConfig
+	doc, err := p.decode(configFile, &parseable)
+	if err != nil {
+		return result, nil, err
+	}
+	result = parseable.normalize()
+
+	if err := result.ResolveSecrets(); err != nil {
+		return result, nil, CheckErr(err, configFile)
+	}
+
+	r := result.validate(p.strict)
+	if p.strict {
+		r.Errors = append(unknownFieldIssues(doc), r.Errors...)
+	}
+	if !p.isComposed() {
+		p.locateIssues(configFile, r.issues())
+	}
+	return result, r, nil
+}
+
+func (p parser) locateIssues(configFile string, issues []*ValidationIssue) {
+	data, err := p.fileReader(configFile)
+	if err != nil {
+		return
+	}
+	source, err := expandEnv(configFile, data)
+	if err != nil {
+		return
+	}
+	locate := formatFor(configFile).locate
+	for _, issue := range issues {
+		if issue.Path == nil {
+			continue
+		}
+		offset, ok := locate(source.data, issue.Path)
+		if !ok {
+			continue
+		}
+		offset = source.originalOffset(offset)
+		row, col := getErrorRowCol(data, offset)
+		issue.Source = &SourcePosition{ConfigFile: configFile, Offset: offset, Row: row, Col: col}
+	}
+}
@@ -0,0 +1,251 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const (
+	validationTestJSON = `{
+	"version": 2,
+	"host": ["http://127.0.0.1:8080"],
+	"endpoints": [
+		{"endpoint": "/empty"},
+		{"endpoint": "/undefined", "backend": [{"url_pattern": "/users/{id}"}]},
+		{
+			"endpoint": "/merge",
+			"backend": [
+				{"url_pattern": "/a", "sd": "consul"},
+				{"url_pattern": "/b", "method": "POST", "encoding": "weird"}
+			]
+		},
+		{"endpoint": "/merge", "backend": [{"url_pattern": "/c"}]}
+	]
+}`
+	validationTestYAML = `version: 2
+host:
+  - http://127.0.0.1:8080
+endpoints:
+  - endpoint: /empty
+  - endpoint: /undefined
+    backend:
+      - url_pattern: /users/{id}
+  - endpoint: /merge
+    backend:
+      - url_pattern: /a
+        sd: consul
+      - url_pattern: /b
+        method: POST
+        encoding: weird
+  - endpoint: /merge
+    backend:
+      - url_pattern: /c
+`
+)
+
+func TestServiceConfig_Validate(t *testing.T) {
+	cfg := ServiceConfig{
+		Version: 2,
+		Host:    []string{"http://127.0.0.1:8080"},
+		Endpoints: []*EndpointConfig{
+			{Endpoint: "/empty", Method: "GET"},
+			{Endpoint: "/undefined", Method: "GET", Backend: []*Backend{{URLPattern: "/users/{id}"}}},
+			{Endpoint: "/ok", Method: "GET", Backend: []*Backend{{URLPattern: "/ok"}}},
+		},
+	}
+
+	r := cfg.Validate()
+	if len(r.Errors) != 3 {
+		t.Fatalf("unexpected number of errors: %v", r.Errors)
+	}
+	if len(r.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", r.Warnings)
+	}
+
+	var versionErr *UnsupportedVersionError
+	if !errors.As(r.Errors[0], &versionErr) {
+		t.Errorf("unexpected first error: %v", r.Errors[0])
+	}
+	if msg := r.Errors[1].Error(); msg != "endpoint 'GET /empty': ignoring the 'GET /empty' endpoint, since it has 0 backends defined!" {
+		t.Errorf("unexpected second error: %s", msg)
+	}
+	if r.Errors[2].Endpoint != "/undefined" || r.Errors[2].Backend != 0 {
+		t.Errorf("unexpected third error: %+v", r.Errors[2])
+	}
+
+	if cfg.Endpoints[2].Backend[0].Decoder == nil {
+		t.Error("the valid endpoints should be initialized")
+	}
+
+	err := r.Err()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Issues) != 3 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !errors.As(err, &versionErr) {
+		t.Error("the version error should be reachable through the validation error")
+	}
+	if !strings.HasPrefix(err.Error(), "3 errors found in the configuration:\n\t") {
+		t.Errorf("unexpected error message: %s", err.Error())
+	}
+
+	if err := cfg.Init(); !errors.As(err, &versionErr) {
+		t.Errorf("Init should stop at the first error. have: %v", err)
+	}
+}
+
+func TestServiceConfig_Validate_ok(t *testing.T) {
+	cfg := ServiceConfig{
+		Version: ConfigVersion,
+		Host:    []string{"http://127.0.0.1:8080"},
+		Endpoints: []*EndpointConfig{
+			{Endpoint: "/ok", Method: "GET", Backend: []*Backend{{URLPattern: "/ok", SD: "static"}}},
+		},
+	}
+	r := cfg.Validate()
+	if err := r.Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(r.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", r.Warnings)
+	}
+}
+
+func TestServiceConfig_Validate_warnings(t *testing.T) {
+	cfg := ServiceConfig{
+		Version: ConfigVersion,
+		Host:    []string{"http://127.0.0.1:8080"},
+		Endpoints: []*EndpointConfig{
+			{Endpoint: "/merge", Method: "GET", Backend: []*Backend{
+				{URLPattern: "/a", SD: "consul"},
+				{URLPattern: "/b", Method: "POST", Encoding: "weird"},
+			}},
+			{Endpoint: "/merge", Method: "GET", Backend: []*Backend{{URLPattern: "/c"}}},
+			{Endpoint: "/post", Method: "POST", Backend: []*Backend{{URLPattern: "/c", Method: "POST"}}},
+		},
+		AsyncAgents: []*AsyncAgent{
+			{Name: "agent", Backend: []*Backend{{URLPattern: "/d", SD: "unknown"}}},
+		},
+	}
+
+	r := cfg.Validate()
+	if err := r.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []struct {
+		err  error
+		path string
+	}{
+		{ErrUnknownSDDriver, "[endpoints 0 backend 0 sd]"},
+		{ErrNonGETBackendInMerge, "[endpoints 0 backend 1]"},
+		{ErrUnknownEncoding, "[endpoints 0 backend 1 encoding]"},
+		{ErrDuplicatedEndpoint, "[endpoints 1]"},
+		{ErrUnknownSDDriver, "[async_agent 0 backend 0 sd]"},
+	}
+	if len(r.Warnings) != len(expected) {
+		t.Fatalf("unexpected warnings: %v", r.Warnings)
+	}
+	for i, e := range expected {
+		w := r.Warnings[i]
+		if !errors.Is(w, e.err) {
+			t.Errorf("#%d: unexpected warning: %v", i, w)
+		}
+		if path := fmt.Sprintf("%v", w.Path); path != e.path {
+			t.Errorf("#%d: unexpected path. have: %s, want: %s", i, path, e.path)
+		}
+	}
+
+	if msg := r.Warnings[4].Error(); msg != "async agent 'agent', backend 0: unknown sd driver: unknown" {
+		t.Errorf("unexpected message: %s", msg)
+	}
+
+	RegisterSDDriver("consul")
+	defer func() {
+		sdDriversMutex.Lock()
+		delete(sdDrivers, "consul")
+		sdDriversMutex.Unlock()
+	}()
+	if r := cfg.Validate(); len(r.Warnings) != len(expected)-1 {
+		t.Errorf("unexpected warnings: %v", r.Warnings)
+	}
+}
+
+func TestValidateFile(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		content  string
+		errors   []string
+		warnings []string
+	}{
+		{
+			name:    "validation.json",
+			content: validationTestJSON,
+			errors: []string{
+				"row: 1, col: 13",
+				"row: 4, col: 3",
+				"row: 5, col: 42",
+			},
+			warnings: []string{
+				"row: 9, col: 33",
+				"row: 10, col: 5",
+				"row: 10, col: 57",
+				"row: 13, col: 3",
+			},
+		},
+		{
+			name:    "validation.yaml",
+			content: validationTestYAML,
+			errors: []string{
+				"row: 0, col: 10",
+				"row: 4, col: 5",
+				"row: 7, col: 9",
+			},
+			warnings: []string{
+				"row: 11, col: 13",
+				"row: 12, col: 9",
+				"row: 14, col: 19",
+				"row: 15, col: 5",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			readFile := func(string) ([]byte, error) { return []byte(tc.content), nil }
+			_, r, err := ValidateFile(tc.name, ParserConfig{FileReader: readFile})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			checkLocatedIssues(t, tc.name, r.Errors, tc.errors)
+			checkLocatedIssues(t, tc.name, r.Warnings, tc.warnings)
+		})
+	}
+}
+
+func TestValidateFile_decodeError(t *testing.T) {
+	readFile := func(string) ([]byte, error) { return []byte(`{"version": 3,}`), nil }
+	_, r, err := ValidateFile("broken.json", ParserConfig{FileReader: readFile})
+	if err == nil {
+		t.Fatal("expecting an error")
+	}
+	if r != nil {
+		t.Errorf("unexpected report: %v", r)
+	}
+}
+
+func checkLocatedIssues(t *testing.T, name string, issues []*ValidationIssue, positions []string) {
+	t.Helper()
+	if len(issues) != len(positions) {
+		t.Errorf("unexpected issues: %v", issues)
+		return
+	}
+	for i, issue := range issues {
+		msg := issue.Error()
+		if !strings.HasPrefix(msg, "'"+name+"': ") || !strings.HasSuffix(msg, positions[i]) {
+			t.Errorf("#%d: unexpected message: %s", i, msg)
+		}
+	}
+}
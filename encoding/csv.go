@@ -0,0 +1,147 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package encoding
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// CSV is the key for the csv encoding
+const CSV = "csv"
+
+// CSVOptions defines how the CSV documents are converted into maps
+type CSVOptions struct {
+	// Comma is the field delimiter
+	Comma rune
+	// Comment, if not 0, is the character starting the lines to ignore
+	Comment rune
+	// Header signals if the first record contains the names of the columns
+	Header bool
+	// Columns overrides the names of the columns. When empty, the names are taken from the
+	// header row or, without it, from the position of each column ("0", "1", ...)
+	Columns []string
+}
+
+// DefaultCSVOptions are the options used by the decoder registered under the CSV key
+var DefaultCSVOptions = CSVOptions{
+	Comma:  ',',
+	Header: true,
+}
+
+// NewCSVDecoder returns the CSV decoder using the DefaultCSVOptions
+func NewCSVDecoder(isCollection bool) func(io.Reader, *map[string]interface{}) error {
+	return NewCSVDecoderFactory(DefaultCSVOptions)(isCollection)
+}
+
+// NewCSVDecoderFactory returns a DecoderFactory for CSV documents with the received options.
+// Every record is decoded as a map with the names of the columns as keys and the fields as
+// string values. The collection decoders return all the records under the key 'collection'
+// and the entity decoders return just the first one.
+func NewCSVDecoderFactory(opts CSVOptions) DecoderFactory {
+	return func(isCollection bool) func(io.Reader, *map[string]interface{}) error {
+		return func(r io.Reader, v *map[string]interface{}) error {
+			records, err := readCSV(r, opts)
+			if err != nil {
+				return err
+			}
+			if isCollection {
+				*(v) = map[string]interface{}{"collection": records}
+				return nil
+			}
+			if len(records) == 0 {
+				*(v) = map[string]interface{}{}
+				return nil
+			}
+			*(v) = records[0].(map[string]interface{})
+			return nil
+		}
+	}
+}
+
+func readCSV(r io.Reader, opts CSVOptions) ([]interface{}, error) {
+	cr := csv.NewReader(r)
+	if opts.Comma != 0 {
+		cr.Comma = opts.Comma
+	}
+	cr.Comment = opts.Comment
+
+	columns := opts.Columns
+	if opts.Header {
+		header, err := cr.Read()
+		if err == io.EOF {
+			return []interface{}{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(columns) == 0 {
+			columns = header
+		}
+	}
+
+	records := []interface{}{}
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{}, len(record))
+		for i, field := range record {
+			if i < len(columns) {
+				row[columns[i]] = field
+				continue
+			}
+			row[strconv.Itoa(i)] = field
+		}
+		records = append(records, row)
+	}
+}
+
+// EncodeCSV writes the rows as a CSV document. The header contains the sorted keys of all
+// the rows and the items not being objects are written under the 'value' column.
+func EncodeCSV(w io.Writer, rows []interface{}) error {
+	records := make([]map[string]interface{}, len(rows))
+	keys := map[string]struct{}{}
+	for i, row := range rows {
+		m, ok := row.(map[string]interface{})
+		if !ok {
+			m = map[string]interface{}{"value": row}
+		}
+		for k := range m {
+			keys[k] = struct{}{}
+		}
+		records[i] = m
+	}
+
+	header := make([]string, 0, len(keys))
+	for k := range keys {
+		header = append(header, k)
+	}
+	sort.Strings(header)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, m := range records {
+		record := make([]string, len(header))
+		for i, k := range header {
+			s, err := formatValue(m[k])
+			if err != nil {
+				return err
+			}
+			record[i] = s
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
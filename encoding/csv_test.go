@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package encoding
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewCSVDecoder(t *testing.T) {
+	in := "id,name\n1,first\n2,\"second, last\"\n"
+
+	var result map[string]interface{}
+	if err := GetRegister().Get(CSV)(true)(strings.NewReader(in), &result); err != nil {
+		t.Error(err)
+		return
+	}
+	expected := map[string]interface{}{"collection": []interface{}{
+		map[string]interface{}{"id": "1", "name": "first"},
+		map[string]interface{}{"id": "2", "name": "second, last"},
+	}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("unexpected result: %v", result)
+	}
+
+	result = nil
+	if err := NewCSVDecoder(false)(strings.NewReader(in), &result); err != nil {
+		t.Error(err)
+		return
+	}
+	if !reflect.DeepEqual(result, map[string]interface{}{"id": "1", "name": "first"}) {
+		t.Errorf("unexpected result: %v", result)
+	}
+}
+
+func TestNewCSVDecoderFactory(t *testing.T) {
+	in := "# exported rows\n1;first;x\n2;second;y\n"
+
+	for _, tc := range []struct {
+		name     string
+		opts     CSVOptions
+		expected []interface{}
+	}{
+		{
+			name: "positional",
+			opts: CSVOptions{Comma: ';', Comment: '#'},
+			expected: []interface{}{
+				map[string]interface{}{"0": "1", "1": "first", "2": "x"},
+				map[string]interface{}{"0": "2", "1": "second", "2": "y"},
+			},
+		},
+		{
+			name: "columns",
+			opts: CSVOptions{Comma: ';', Comment: '#', Columns: []string{"id", "name"}},
+			expected: []interface{}{
+				map[string]interface{}{"id": "1", "name": "first", "2": "x"},
+				map[string]interface{}{"id": "2", "name": "second", "2": "y"},
+			},
+		},
+		{
+			name: "header and columns",
+			opts: CSVOptions{Comma: ';', Comment: '#', Header: true, Columns: []string{"id", "name", "kind"}},
+			expected: []interface{}{
+				map[string]interface{}{"id": "2", "name": "second", "kind": "y"},
+			},
+		},
+	} {
+		var result map[string]interface{}
+		if err := NewCSVDecoderFactory(tc.opts)(true)(strings.NewReader(in), &result); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, map[string]interface{}{"collection": tc.expected}) {
+			t.Errorf("%s: unexpected result: %v", tc.name, result)
+		}
+	}
+}
+
+func TestNewCSVDecoder_empty(t *testing.T) {
+	for _, in := range []string{"", "id,name\n"} {
+		var result map[string]interface{}
+		if err := NewCSVDecoder(true)(strings.NewReader(in), &result); err != nil {
+			t.Error(err)
+			continue
+		}
+		if !reflect.DeepEqual(result, map[string]interface{}{"collection": []interface{}{}}) {
+			t.Errorf("%q: unexpected result: %v", in, result)
+		}
+
+		result = nil
+		if err := NewCSVDecoder(false)(strings.NewReader(in), &result); err != nil {
+			t.Error(err)
+			continue
+		}
+		if len(result) != 0 || result == nil {
+			t.Errorf("%q: unexpected result: %v", in, result)
+		}
+	}
+}
+
+func TestNewCSVDecoder_ko(t *testing.T) {
+	for _, in := range []string{"id,name\n1\n", "id,\"name\n"} {
+		var result map[string]interface{}
+		if err := NewCSVDecoder(true)(strings.NewReader(in), &result); err == nil {
+			t.Errorf("%q: error expected", in)
+		}
+	}
+}
+
+func TestEncodeCSV(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := EncodeCSV(buf, []interface{}{
+		map[string]interface{}{"id": 1, "name": "first, last"},
+		map[string]interface{}{"id": 2, "tags": []interface{}{"a"}},
+		"loose",
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expected := "id,name,tags,value\n1,\"first, last\",,\n2,,\"[\"\"a\"\"]\",\n,,,loose\n"
+	if buf.String() != expected {
+		t.Errorf("unexpected result: %q", buf.String())
+	}
+}
@@ -33,7 +33,7 @@ func TestRegister(t *testing.T) {
 
 	original := GetRegister()
 
-	if len(original.data.Clone()) != 4 {
+	if len(original.data.Clone()) != 9 {
 		t.Error("Unexpected number of registered factories:", len(original.data.Clone()))
 	}
 
@@ -49,7 +49,7 @@ func TestGet(t *testing.T) {
 	decoders = initDecoderRegister()
 	defer func() { decoders = initDecoderRegister() }()
 
-	if len(decoders.data.Clone()) != 4 {
+	if len(decoders.data.Clone()) != 9 {
 		t.Error("Unexpected number of registered factories:", len(decoders.data.Clone()))
 	}
 
@@ -67,6 +67,18 @@ func TestGet(t *testing.T) {
 	checkDecoder(t, "some")
 }
 
+func TestDecoderRegister_Has(t *testing.T) {
+	decoders = initDecoderRegister()
+	defer func() { decoders = initDecoderRegister() }()
+
+	if !GetRegister().Has(JSON) {
+		t.Error("the json decoder should be registered")
+	}
+	if GetRegister().Has("unknown") {
+		t.Error("the unknown decoder should not be registered")
+	}
+}
+
 func TestRegister_complete_ok(t *testing.T) {
 	decoders = initDecoderRegister()
 	defer func() { decoders = initDecoderRegister() }()
@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package encoding
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+)
+
+// FORM is the key for the application/x-www-form-urlencoded encoding
+const FORM = "form"
+
+// NewFormDecoder returns a form-urlencoded decoder
+func NewFormDecoder(_ bool) func(io.Reader, *map[string]interface{}) error {
+	return FormDecoder
+}
+
+// FormDecoder decodes an application/x-www-form-urlencoded body into a map. The keys with a
+// single value are decoded as strings and the repeated ones as arrays of strings.
+func FormDecoder(r io.Reader, v *map[string]interface{}) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+	res := make(map[string]interface{}, len(values))
+	for k, vs := range values {
+		if len(vs) == 1 {
+			res[k] = vs[0]
+			continue
+		}
+		items := make([]interface{}, len(vs))
+		for i, s := range vs {
+			items[i] = s
+		}
+		res[k] = items
+	}
+	*(v) = res
+	return nil
+}
+
+// EncodeForm returns the application/x-www-form-urlencoded representation of the data. The
+// arrays are encoded as repeated keys and the nested objects as JSON strings.
+func EncodeForm(data map[string]interface{}) (string, error) {
+	values := url.Values{}
+	for k, v := range data {
+		if items, ok := v.([]interface{}); ok {
+			for _, item := range items {
+				s, err := formatValue(item)
+				if err != nil {
+					return "", err
+				}
+				values.Add(k, s)
+			}
+			continue
+		}
+		s, err := formatValue(v)
+		if err != nil {
+			return "", err
+		}
+		values.Set(k, s)
+	}
+	return values.Encode(), nil
+}
+
+// formatValue returns the string representation of a decoded value, so it can be added
+// to a form or to a csv record
+func formatValue(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return t, nil
+	case map[string]interface{}, []interface{}:
+		b, err := json.Marshal(t)
+		return string(b), err
+	default:
+		return fmt.Sprintf("%v", t), nil
+	}
+}
@@ -0,0 +1,52 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package encoding
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewFormDecoder(t *testing.T) {
+	in := "access_token=abc%20123&scope=read&scope=write&expires_in=3600"
+	for _, isCollection := range []bool{true, false} {
+		var result map[string]interface{}
+		if err := GetRegister().Get(FORM)(isCollection)(strings.NewReader(in), &result); err != nil {
+			t.Error(err)
+			return
+		}
+		expected := map[string]interface{}{
+			"access_token": "abc 123",
+			"scope":        []interface{}{"read", "write"},
+			"expires_in":   "3600",
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("unexpected result: %v", result)
+		}
+	}
+}
+
+func TestFormDecoder_ko(t *testing.T) {
+	var result map[string]interface{}
+	if err := FormDecoder(strings.NewReader("a=%zz"), &result); err == nil {
+		t.Error("error expected")
+	}
+}
+
+func TestEncodeForm(t *testing.T) {
+	res, err := EncodeForm(map[string]interface{}{
+		"token":  "abc 123",
+		"scope":  []interface{}{"read", "write"},
+		"expiry": 3600,
+		"empty":  nil,
+		"user":   map[string]interface{}{"id": 1},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "empty=&expiry=3600&scope=read&scope=write&token=abc+123&user=%7B%22id%22%3A1%7D" {
+		t.Errorf("unexpected result: %s", res)
+	}
+}
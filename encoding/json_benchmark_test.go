@@ -52,3 +52,48 @@ func BenchmarkDecoder(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkStreamingJSONDecoder(b *testing.B) {
+	item := `{"id": 42, "name": "lura", "tags": ["a", "b", "c"], "payload": {"blob": "` +
+		strings.Repeat("x", 512) + `", "values": [1, 2, 3, 4, 5, 6, 7, 8]}}`
+	items := make([]string, 1000)
+	for i := range items {
+		items[i] = item
+	}
+	entity := `{"meta": {"total": 1000}, "items": [` + strings.Join(items, ",") + `]}`
+
+	for _, tc := range []struct {
+		name    string
+		decoder func(io.Reader, *map[string]interface{}) error
+	}{
+		{
+			name:    "json",
+			decoder: NewJSONDecoder(false),
+		},
+		{
+			name:    "streaming",
+			decoder: NewStreamingJSONDecoder(StreamingJSONOptions{})(false),
+		},
+		{
+			name:    "streaming-target",
+			decoder: NewStreamingJSONDecoder(StreamingJSONOptions{Target: "meta"})(false),
+		},
+		{
+			name:    "streaming-allow",
+			decoder: NewStreamingJSONDecoder(StreamingJSONOptions{AllowList: []string{"meta.total"}})(false),
+		},
+		{
+			name:    "streaming-deny",
+			decoder: NewStreamingJSONDecoder(StreamingJSONOptions{DenyList: []string{"items"}})(false),
+		},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(entity)))
+			var result map[string]interface{}
+			for i := 0; i < b.N; i++ {
+				_ = tc.decoder(strings.NewReader(entity), &result)
+			}
+		})
+	}
+}
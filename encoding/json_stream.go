@@ -0,0 +1,453 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package encoding
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Namespace is the key to use to store and access the custom config data for the decoders
+const Namespace = "github.com/devopsfaith/krakend/encoding"
+
+// StreamingJSONOptions defines the transformations applied by the streaming JSON decoders while
+// tokenizing the document. They have the same semantics as the target extraction and the allow
+// and deny lists of the backends.
+type StreamingJSONOptions struct {
+	// Target is the path of the object to extract from the document
+	Target string
+	// AllowList contains the paths of the properties to keep. It takes precedence over the DenyList
+	AllowList []string
+	// DenyList contains the paths of the properties to drop
+	DenyList []string
+	// MaxBytes is the max number of bytes to read from the reader. Zero means no limit.
+	MaxBytes int64
+	// MaxDepth is the max nesting level of the objects and arrays. Zero means no limit.
+	MaxDepth int
+	// MaxElements is the max number of values to store. Zero means no limit.
+	MaxElements int
+}
+
+// NewStreamingJSONDecoder returns a DecoderFactory for JSON documents applying the received
+// options while tokenizing the document, so the discarded properties are never stored in memory.
+// The collection decoders return a map with the array at the 'collection' key, as
+// JSONCollectionDecoder does.
+func NewStreamingJSONDecoder(opts StreamingJSONOptions) DecoderFactory {
+	s := streamingJSONDecoder{maxBytes: opts.MaxBytes, maxDepth: opts.MaxDepth, maxElements: opts.MaxElements}
+	if opts.Target != "" {
+		s.target = strings.Split(opts.Target, ".")
+	}
+	if len(opts.AllowList) > 0 {
+		s.filter = jsonFilter{tree: buildFilterTree(opts.AllowList, true), allow: true}
+	} else if len(opts.DenyList) > 0 {
+		s.filter = jsonFilter{tree: buildFilterTree(opts.DenyList, false)}
+	}
+
+	return func(isCollection bool) func(io.Reader, *map[string]interface{}) error {
+		if isCollection {
+			return s.decodeCollection
+		}
+		return s.decode
+	}
+}
+
+type streamingJSONDecoder struct {
+	target      []string
+	filter      jsonFilter
+	maxBytes    int64
+	maxDepth    int
+	maxElements int
+}
+
+// jsonFilter holds the tree of paths of the allow or deny list. The leaves of an allow list
+// are true and the leaves of a deny list are nil.
+type jsonFilter struct {
+	tree  map[string]interface{}
+	allow bool
+}
+
+func (f jsonFilter) isEmpty() bool { return f.tree == nil }
+
+// child returns the action for the received key: drop it, keep it as it is or keep it while
+// filtering its properties with the returned filter
+func (f jsonFilter) child(key string) (keep bool, sub jsonFilter) {
+	v, ok := f.tree[key]
+	if f.allow {
+		if !ok {
+			return false, jsonFilter{}
+		}
+		if m, ok := v.(map[string]interface{}); ok {
+			return true, jsonFilter{tree: m, allow: true}
+		}
+		return true, jsonFilter{}
+	}
+	if !ok {
+		return true, jsonFilter{}
+	}
+	if m, ok := v.(map[string]interface{}); ok {
+		return true, jsonFilter{tree: m}
+	}
+	return false, jsonFilter{}
+}
+
+func buildFilterTree(paths []string, allow bool) map[string]interface{} {
+	tree := map[string]interface{}{}
+	for _, path := range paths {
+		keys := strings.Split(path, ".")
+		node := tree
+		for _, k := range keys[:len(keys)-1] {
+			current, ok := node[k]
+			if ok && current == nil && !allow {
+				// all the descendants of a denied property are already dropped
+				node = nil
+				break
+			}
+			child, isMap := current.(map[string]interface{})
+			if !isMap {
+				child = map[string]interface{}{}
+				node[k] = child
+			}
+			node = child
+		}
+		if node == nil {
+			continue
+		}
+		if allow {
+			node[keys[len(keys)-1]] = true
+		} else {
+			node[keys[len(keys)-1]] = nil
+		}
+	}
+	return tree
+}
+
+func (s streamingJSONDecoder) newReader(r io.Reader) (*jsonReader, *LimitedReader) {
+	var limited *LimitedReader
+	if s.maxBytes > 0 {
+		limited = NewLimitedReader(r, LimitSize, s.maxBytes)
+		r = limited
+	}
+	d := json.NewDecoder(r)
+	d.UseNumber()
+	return &jsonReader{d: d, maxDepth: s.maxDepth, maxElements: s.maxElements}, limited
+}
+
+func (s streamingJSONDecoder) decode(r io.Reader, v *map[string]interface{}) error {
+	jr, limited := s.newReader(r)
+	res, err := s.decodeEntity(jr)
+	if err = limited.Err(err); err != nil {
+		return err
+	}
+	*v = res
+	return nil
+}
+
+func (s streamingJSONDecoder) decodeCollection(r io.Reader, v *map[string]interface{}) error {
+	jr, limited := s.newReader(r)
+	res, err := s.readCollection(jr)
+	if err = limited.Err(err); err != nil {
+		return err
+	}
+	*v = res
+	return nil
+}
+
+func (s streamingJSONDecoder) readCollection(r *jsonReader) (map[string]interface{}, error) {
+	if err := r.open('['); err != nil {
+		return nil, err
+	}
+
+	// the collection is stored under a 'collection' key, so the options are applied to that key
+	keep := len(s.target) == 0
+	sub := jsonFilter{}
+	if keep && !s.filter.isEmpty() {
+		keep, sub = s.filter.child("collection")
+		// the items of the collection are not objects, so the nested filters just drop it
+		keep = keep && (sub.isEmpty() || !sub.allow)
+	}
+
+	collection := []interface{}{}
+	for r.d.More() {
+		if !keep {
+			if err := r.skipValue(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		item, err := r.readValue(jsonFilter{})
+		if err != nil {
+			return nil, err
+		}
+		collection = append(collection, item)
+	}
+	if err := r.close(']'); err != nil {
+		return nil, err
+	}
+
+	if !keep {
+		return map[string]interface{}{}, nil
+	}
+	return map[string]interface{}{"collection": collection}, nil
+}
+
+func (s streamingJSONDecoder) decodeEntity(r *jsonReader) (map[string]interface{}, error) {
+	if err := r.open('{'); err != nil {
+		return nil, err
+	}
+	if len(s.target) == 0 {
+		return r.readObject(s.filter)
+	}
+	return s.extractTarget(r, s.target)
+}
+
+// extractTarget reads the properties of the current object, keeping just the one in the path.
+// It returns an empty map if the target is not found or it is not an object.
+func (s streamingJSONDecoder) extractTarget(r *jsonReader, path []string) (map[string]interface{}, error) {
+	res := map[string]interface{}{}
+	for r.d.More() {
+		key, err := r.readKey()
+		if err != nil {
+			return nil, err
+		}
+		if key != path[0] {
+			if err := r.skipValue(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		t, err := r.d.Token()
+		if err != nil {
+			return nil, err
+		}
+		if t != json.Delim('{') {
+			if err := r.skipRest(t); err != nil {
+				return nil, err
+			}
+			res = map[string]interface{}{}
+			continue
+		}
+		if err := r.enter(); err != nil {
+			return nil, err
+		}
+		if len(path) == 1 {
+			res, err = r.readObject(s.filter)
+		} else {
+			res, err = s.extractTarget(r, path[1:])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := r.close('}'); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// jsonReader builds the values from the tokens of the decoder, checking the nesting level
+// and the number of stored values
+type jsonReader struct {
+	d           *json.Decoder
+	depth       int
+	elements    int
+	maxDepth    int
+	maxElements int
+}
+
+// readObject reads the properties of the current object, applying the filter. The opening
+// delimiter has been already consumed.
+func (r *jsonReader) readObject(f jsonFilter) (map[string]interface{}, error) {
+	res := map[string]interface{}{}
+	for r.d.More() {
+		key, err := r.readKey()
+		if err != nil {
+			return nil, err
+		}
+		keep, sub := true, jsonFilter{}
+		if !f.isEmpty() {
+			keep, sub = f.child(key)
+		}
+		if !keep {
+			if err := r.skipValue(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		v, err := r.readValue(sub)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := v.(filteredValue); ok {
+			delete(res, key)
+			continue
+		}
+		res[key] = v
+	}
+	if err := r.close('}'); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// filteredValue signals a value removed by a nested allow list
+type filteredValue struct{}
+
+// readValue reads the next value. When the filter is not empty, it is applied to the objects.
+// Nested allow lists drop the values that are not objects and the objects without any allowed
+// property, as the allow list filter of the entity formatter does.
+func (r *jsonReader) readValue(f jsonFilter) (interface{}, error) {
+	t, err := r.d.Token()
+	if err != nil {
+		return nil, err
+	}
+	if f.isEmpty() {
+		return r.buildValue(t)
+	}
+	if t != json.Delim('{') {
+		if f.allow {
+			return filteredValue{}, r.skipRest(t)
+		}
+		return r.buildValue(t)
+	}
+	if err := r.count(); err != nil {
+		return nil, err
+	}
+	if err := r.enter(); err != nil {
+		return nil, err
+	}
+	obj, err := r.readObject(f)
+	if err != nil {
+		return nil, err
+	}
+	if f.allow && len(obj) == 0 {
+		return filteredValue{}, nil
+	}
+	return obj, nil
+}
+
+// buildValue returns the value starting with the received token
+func (r *jsonReader) buildValue(t json.Token) (interface{}, error) {
+	if err := r.count(); err != nil {
+		return nil, err
+	}
+	switch t {
+	case json.Delim('{'):
+		if err := r.enter(); err != nil {
+			return nil, err
+		}
+		return r.readObject(jsonFilter{})
+	case json.Delim('['):
+		if err := r.enter(); err != nil {
+			return nil, err
+		}
+		items := []interface{}{}
+		for r.d.More() {
+			item, err := r.readValue(jsonFilter{})
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		if err := r.close(']'); err != nil {
+			return nil, err
+		}
+		return items, nil
+	}
+	if _, ok := t.(json.Delim); ok {
+		return nil, fmt.Errorf("encoding: unexpected delimiter %v", t)
+	}
+	return t, nil
+}
+
+func (r *jsonReader) readKey() (string, error) {
+	t, err := r.d.Token()
+	if err != nil {
+		return "", err
+	}
+	key, ok := t.(string)
+	if !ok {
+		return "", fmt.Errorf("encoding: unexpected token %v", t)
+	}
+	return key, nil
+}
+
+// open consumes the expected opening delimiter
+func (r *jsonReader) open(delim json.Delim) error {
+	if err := r.expectDelim(delim); err != nil {
+		return err
+	}
+	return r.enter()
+}
+
+// close consumes the expected closing delimiter
+func (r *jsonReader) close(delim json.Delim) error {
+	if err := r.expectDelim(delim); err != nil {
+		return err
+	}
+	r.depth--
+	return nil
+}
+
+func (r *jsonReader) expectDelim(delim json.Delim) error {
+	t, err := r.d.Token()
+	if err != nil {
+		return err
+	}
+	if t != delim {
+		return fmt.Errorf("encoding: expected %v, got %v", delim, t)
+	}
+	return nil
+}
+
+// enter increases the nesting level
+func (r *jsonReader) enter() error {
+	r.depth++
+	if r.maxDepth > 0 && r.depth > r.maxDepth {
+		return &LimitError{Limit: LimitJSONDepth, Max: int64(r.maxDepth)}
+	}
+	return nil
+}
+
+// count increases the number of stored values
+func (r *jsonReader) count() error {
+	r.elements++
+	if r.maxElements > 0 && r.elements > r.maxElements {
+		return &LimitError{Limit: LimitJSONElements, Max: int64(r.maxElements)}
+	}
+	return nil
+}
+
+// skipValue consumes the next value without storing it
+func (r *jsonReader) skipValue() error {
+	t, err := r.d.Token()
+	if err != nil {
+		return err
+	}
+	return r.skipRest(t)
+}
+
+// skipRest consumes the value starting with the received token. The skipped values are not
+// stored, but the nesting level is still checked.
+func (r *jsonReader) skipRest(t json.Token) error {
+	base := r.depth
+	for {
+		switch t {
+		case json.Delim('{'), json.Delim('['):
+			if err := r.enter(); err != nil {
+				return err
+			}
+		case json.Delim('}'), json.Delim(']'):
+			r.depth--
+		}
+		if r.depth == base {
+			return nil
+		}
+		var err error
+		if t, err = r.d.Token(); err != nil {
+			return err
+		}
+	}
+}
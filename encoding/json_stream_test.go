@@ -0,0 +1,250 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package encoding
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const streamingInput = `{
+	"id": 42,
+	"user": {"name": "lura", "email": "lura@example.com", "address": {"city": "Málaga", "zip": "29001"}},
+	"tags": ["a", {"b": true}],
+	"debug": {"trace": [1, 2, 3], "nested": {"deep": [{"x": null}]}},
+	"data": {"items": [{"id": 1}], "meta": {"total": 1, "page": "1"}, "scalar": "x"}
+}`
+
+func TestNewStreamingJSONDecoder(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		opts     StreamingJSONOptions
+		expected string
+	}{
+		{
+			name:     "no options",
+			expected: streamingInput,
+		},
+		{
+			name:     "allow",
+			opts:     StreamingJSONOptions{AllowList: []string{"id", "user.address.city", "tags", "debug.unknown"}},
+			expected: `{"id": 42, "user": {"address": {"city": "Málaga"}}, "tags": ["a", {"b": true}]}`,
+		},
+		{
+			name:     "allow a scalar as an object",
+			opts:     StreamingJSONOptions{AllowList: []string{"id.value", "user.name"}},
+			expected: `{"user": {"name": "lura"}}`,
+		},
+		{
+			name:     "allow overriding a branch",
+			opts:     StreamingJSONOptions{AllowList: []string{"user.name", "user"}},
+			expected: `{"user": {"name": "lura", "email": "lura@example.com", "address": {"city": "Málaga", "zip": "29001"}}}`,
+		},
+		{
+			name:     "deny",
+			opts:     StreamingJSONOptions{DenyList: []string{"debug", "debug.trace", "user.address.zip", "user.email", "tags.b", "unknown.key"}},
+			expected: `{"id": 42, "user": {"name": "lura", "address": {"city": "Málaga"}}, "tags": ["a", {"b": true}], "data": {"items": [{"id": 1}], "meta": {"total": 1, "page": "1"}, "scalar": "x"}}`,
+		},
+		{
+			name:     "target",
+			opts:     StreamingJSONOptions{Target: "data.meta"},
+			expected: `{"total": 1, "page": "1"}`,
+		},
+		{
+			name:     "target and allow",
+			opts:     StreamingJSONOptions{Target: "data", AllowList: []string{"meta.total"}, DenyList: []string{"meta"}},
+			expected: `{"meta": {"total": 1}}`,
+		},
+		{
+			name:     "target and deny",
+			opts:     StreamingJSONOptions{Target: "data", DenyList: []string{"items", "meta.page"}},
+			expected: `{"meta": {"total": 1}, "scalar": "x"}`,
+		},
+		{
+			name:     "target not being an object",
+			opts:     StreamingJSONOptions{Target: "data.scalar"},
+			expected: `{}`,
+		},
+		{
+			name:     "unknown target",
+			opts:     StreamingJSONOptions{Target: "data.unknown"},
+			expected: `{}`,
+		},
+		{
+			name:     "max bytes",
+			opts:     StreamingJSONOptions{MaxBytes: int64(len(streamingInput))},
+			expected: streamingInput,
+		},
+	} {
+		var result map[string]interface{}
+		if err := NewStreamingJSONDecoder(tc.opts)(false)(strings.NewReader(streamingInput), &result); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		var expected map[string]interface{}
+		if err := JSONDecoder(strings.NewReader(tc.expected), &expected); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("%s: unexpected result: %v", tc.name, result)
+		}
+	}
+}
+
+func TestNewStreamingJSONDecoder_collection(t *testing.T) {
+	in := `[{"id": 1, "secret": "a"}, {"id": 2.5}, "three", [4], null]`
+	collection := []interface{}{
+		map[string]interface{}{"id": json.Number("1"), "secret": "a"},
+		map[string]interface{}{"id": json.Number("2.5")},
+		"three",
+		[]interface{}{json.Number("4")},
+		nil,
+	}
+
+	for _, tc := range []struct {
+		name     string
+		opts     StreamingJSONOptions
+		expected map[string]interface{}
+	}{
+		{
+			name:     "no options",
+			expected: map[string]interface{}{"collection": collection},
+		},
+		{
+			name:     "allow",
+			opts:     StreamingJSONOptions{AllowList: []string{"collection"}},
+			expected: map[string]interface{}{"collection": collection},
+		},
+		{
+			name:     "allow nested",
+			opts:     StreamingJSONOptions{AllowList: []string{"collection.id"}},
+			expected: map[string]interface{}{},
+		},
+		{
+			name:     "deny",
+			opts:     StreamingJSONOptions{DenyList: []string{"collection"}},
+			expected: map[string]interface{}{},
+		},
+		{
+			name:     "deny nested",
+			opts:     StreamingJSONOptions{DenyList: []string{"collection.secret"}},
+			expected: map[string]interface{}{"collection": collection},
+		},
+		{
+			name:     "target",
+			opts:     StreamingJSONOptions{Target: "collection"},
+			expected: map[string]interface{}{},
+		},
+	} {
+		var result map[string]interface{}
+		if err := NewStreamingJSONDecoder(tc.opts)(true)(strings.NewReader(in), &result); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, tc.expected) {
+			t.Errorf("%s: unexpected result: %v", tc.name, result)
+		}
+	}
+}
+
+func TestNewStreamingJSONDecoder_maxBytes(t *testing.T) {
+	for _, isCollection := range []bool{true, false} {
+		in := streamingInput
+		if isCollection {
+			in = "[" + streamingInput + "]"
+		}
+		opts := StreamingJSONOptions{MaxBytes: int64(len(in)) - 1, DenyList: []string{"debug"}}
+
+		var result map[string]interface{}
+		if err := NewStreamingJSONDecoder(opts)(isCollection)(strings.NewReader(in), &result); !errors.Is(err, ErrMaxBytesExceeded) {
+			t.Errorf("collection %v: unexpected error: %v", isCollection, err)
+		}
+		if result != nil {
+			t.Errorf("collection %v: unexpected result: %v", isCollection, result)
+		}
+	}
+}
+
+func TestNewStreamingJSONDecoder_maxDepth(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		in           string
+		isCollection bool
+		opts         StreamingJSONOptions
+		ok           bool
+	}{
+		{name: "flat", in: `{"a": 1, "b": "c"}`, opts: StreamingJSONOptions{MaxDepth: 1}, ok: true},
+		{name: "nested", in: `{"a": {"b": [1]}}`, opts: StreamingJSONOptions{MaxDepth: 3}, ok: true},
+		{name: "too nested", in: `{"a": {"b": [1]}}`, opts: StreamingJSONOptions{MaxDepth: 2}},
+		{name: "too nested and skipped", in: `{"a": {"b": [1]}}`, opts: StreamingJSONOptions{MaxDepth: 2, DenyList: []string{"a"}}},
+		{name: "too nested target", in: `{"a": {"b": {"c": 1}}}`, opts: StreamingJSONOptions{MaxDepth: 2, Target: "a.b"}},
+		{name: "collection", in: `[{"a": 1}]`, isCollection: true, opts: StreamingJSONOptions{MaxDepth: 2}, ok: true},
+		{name: "too nested collection", in: `[[[1]]]`, isCollection: true, opts: StreamingJSONOptions{MaxDepth: 2}},
+	} {
+		var result map[string]interface{}
+		err := NewStreamingJSONDecoder(tc.opts)(tc.isCollection)(strings.NewReader(tc.in), &result)
+		if tc.ok {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tc.name, err)
+			}
+			continue
+		}
+		if le, ok := err.(*LimitError); !ok || le.Limit != LimitJSONDepth || le.Max != int64(tc.opts.MaxDepth) {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+		if errors.Is(err, ErrMaxBytesExceeded) {
+			t.Errorf("%s: the depth limit is not a size limit", tc.name)
+		}
+	}
+}
+
+func TestNewStreamingJSONDecoder_maxElements(t *testing.T) {
+	in := `{"a": 1, "b": [1, 2, 3], "c": {"d": true}}`
+	for _, tc := range []struct {
+		opts StreamingJSONOptions
+		ok   bool
+	}{
+		{opts: StreamingJSONOptions{MaxElements: 7}, ok: true},
+		{opts: StreamingJSONOptions{MaxElements: 6}},
+		{opts: StreamingJSONOptions{MaxElements: 3, DenyList: []string{"b"}}, ok: true},
+		{opts: StreamingJSONOptions{MaxElements: 1, Target: "c"}, ok: true},
+	} {
+		var result map[string]interface{}
+		err := NewStreamingJSONDecoder(tc.opts)(false)(strings.NewReader(in), &result)
+		if tc.ok {
+			if err != nil {
+				t.Errorf("%+v: unexpected error: %v", tc.opts, err)
+			}
+			continue
+		}
+		if le, ok := err.(*LimitError); !ok || le.Limit != LimitJSONElements {
+			t.Errorf("%+v: unexpected error: %v", tc.opts, err)
+		}
+	}
+}
+
+func TestNewStreamingJSONDecoder_ko(t *testing.T) {
+	for _, tc := range []struct {
+		in           string
+		isCollection bool
+		opts         StreamingJSONOptions
+	}{
+		{in: `[]`},
+		{in: `{"a": }`},
+		{in: `{"a": 1`},
+		{in: `{"a": {"b": [1, 2}}`, opts: StreamingJSONOptions{DenyList: []string{"a"}}},
+		{in: `{"a": {"b": [1, 2}}`, opts: StreamingJSONOptions{Target: "a"}},
+		{in: `{"a": {"b": [1, 2}}`, opts: StreamingJSONOptions{AllowList: []string{"a.b"}}},
+		{in: `{}`, isCollection: true},
+		{in: `[1, 2`, isCollection: true},
+		{in: `[{"a": }]`, isCollection: true, opts: StreamingJSONOptions{Target: "a"}},
+	} {
+		var result map[string]interface{}
+		if err := NewStreamingJSONDecoder(tc.opts)(tc.isCollection)(strings.NewReader(tc.in), &result); err == nil {
+			t.Errorf("%s: error expected", tc.in)
+		}
+	}
+}
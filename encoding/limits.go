@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package encoding
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Names of the limits reported by the LimitError
+const (
+	LimitBodySize         = "max_body_size"
+	LimitDecompressedSize = "max_decompressed_size"
+	LimitJSONDepth        = "max_json_depth"
+	LimitJSONElements     = "max_json_elements"
+	// LimitSize is the limit of the streaming JSON decoders
+	LimitSize = "max_size"
+)
+
+// ErrMaxBytesExceeded matches the LimitError returned when a document is bigger than the allowed size
+var ErrMaxBytesExceeded = errors.New("encoding: max response size exceeded")
+
+// LimitError is the error returned when a document exceeds one of the configured limits
+type LimitError struct {
+	// Limit is the name of the exceeded limit
+	Limit string
+	// Max is the value of the exceeded limit
+	Max int64
+}
+
+// Error implements the error interface
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("encoding: %s of %d exceeded", e.Limit, e.Max)
+}
+
+// Is reports the size limits as ErrMaxBytesExceeded
+func (e *LimitError) Is(target error) bool {
+	return target == ErrMaxBytesExceeded && e.Limit != LimitJSONDepth && e.Limit != LimitJSONElements
+}
+
+// LimitedReader reads from the wrapped reader and fails with a LimitError when it has more
+// bytes than allowed
+type LimitedReader struct {
+	r         io.Reader
+	err       *LimitError
+	remaining int64
+	exceeded  bool
+}
+
+// NewLimitedReader returns a LimitedReader allowing max bytes and reporting the received limit name
+func NewLimitedReader(r io.Reader, limit string, max int64) *LimitedReader {
+	return &LimitedReader{r: r, remaining: max, err: &LimitError{Limit: limit, Max: max}}
+}
+
+// Read implements the io.Reader interface
+func (l *LimitedReader) Read(p []byte) (int, error) {
+	if l.exceeded {
+		return 0, l.err
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		l.exceeded = true
+		return n, l.err
+	}
+	return n, err
+}
+
+// Err returns the LimitError if the limit was exceeded, so the errors of the decoders caused by
+// the truncated document are not reported. Otherwise, it returns the received error.
+func (l *LimitedReader) Err(err error) error {
+	if l != nil && l.exceeded {
+		return l.err
+	}
+	return err
+}
+
+// CheckJSONLimits returns a LimitError if the decoded document has more nesting levels or values
+// than allowed, counting them as the streaming JSON decoders do: the root is the first level and
+// it is not counted as a value. The zero values disable the related limit.
+func CheckJSONLimits(v interface{}, maxDepth, maxElements int) error {
+	if maxDepth <= 0 && maxElements <= 0 {
+		return nil
+	}
+	c := limitsChecker{maxDepth: maxDepth, maxElements: maxElements}
+	return c.check(v, 0)
+}
+
+type limitsChecker struct {
+	maxDepth    int
+	maxElements int
+	elements    int
+}
+
+func (c *limitsChecker) check(v interface{}, depth int) error {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if err := c.enter(depth); err != nil {
+			return err
+		}
+		for _, child := range t {
+			if err := c.checkChild(child, depth+1); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if err := c.enter(depth); err != nil {
+			return err
+		}
+		for _, child := range t {
+			if err := c.checkChild(child, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *limitsChecker) enter(depth int) error {
+	if c.maxDepth > 0 && depth+1 > c.maxDepth {
+		return &LimitError{Limit: LimitJSONDepth, Max: int64(c.maxDepth)}
+	}
+	return nil
+}
+
+func (c *limitsChecker) checkChild(v interface{}, depth int) error {
+	c.elements++
+	if c.maxElements > 0 && c.elements > c.maxElements {
+		return &LimitError{Limit: LimitJSONElements, Max: int64(c.maxElements)}
+	}
+	return c.check(v, depth)
+}
@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package encoding
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLimitedReader(t *testing.T) {
+	for _, tc := range []struct {
+		in  string
+		max int64
+		ok  bool
+	}{
+		{in: "", max: 1, ok: true},
+		{in: "12345", max: 5, ok: true},
+		{in: "123456", max: 5},
+		{in: strings.Repeat("x", 10000), max: 4096},
+	} {
+		r := NewLimitedReader(strings.NewReader(tc.in), LimitBodySize, tc.max)
+		b, err := io.ReadAll(r)
+		if tc.ok {
+			if err != nil || string(b) != tc.in {
+				t.Errorf("%d: unexpected result: %v %q", tc.max, err, b)
+			}
+			if r.Err(nil) != nil {
+				t.Errorf("%d: unexpected error: %v", tc.max, r.Err(nil))
+			}
+			continue
+		}
+		le, ok := err.(*LimitError)
+		if !ok || le.Limit != LimitBodySize || le.Max != tc.max {
+			t.Errorf("%d: unexpected error: %v", tc.max, err)
+		}
+		if int64(len(b)) > tc.max+1 {
+			t.Errorf("%d: too many bytes read: %d", tc.max, len(b))
+		}
+		if err := r.Err(errors.New("decoder error")); err != le {
+			t.Errorf("%d: unexpected error: %v", tc.max, err)
+		}
+		if _, err := r.Read(make([]byte, 10)); err != le {
+			t.Errorf("%d: unexpected error: %v", tc.max, err)
+		}
+	}
+}
+
+func TestLimitError(t *testing.T) {
+	for _, tc := range []struct {
+		limit string
+		size  bool
+	}{
+		{limit: LimitBodySize, size: true},
+		{limit: LimitDecompressedSize, size: true},
+		{limit: LimitSize, size: true},
+		{limit: LimitJSONDepth},
+		{limit: LimitJSONElements},
+	} {
+		var err error = &LimitError{Limit: tc.limit, Max: 10}
+		if errors.Is(err, ErrMaxBytesExceeded) != tc.size {
+			t.Errorf("%s: unexpected match", tc.limit)
+		}
+		if msg := err.Error(); msg != "encoding: "+tc.limit+" of 10 exceeded" {
+			t.Errorf("%s: unexpected message: %s", tc.limit, msg)
+		}
+	}
+}
+
+func TestCheckJSONLimits(t *testing.T) {
+	data := map[string]interface{}{
+		"a": map[string]interface{}{"b": []interface{}{true, 1}},
+		"c": "d",
+	}
+	for _, tc := range []struct {
+		maxDepth    int
+		maxElements int
+		limit       string
+	}{
+		{},
+		{maxDepth: 3, maxElements: 5},
+		{maxDepth: 2, limit: LimitJSONDepth},
+		{maxElements: 4, limit: LimitJSONElements},
+	} {
+		err := CheckJSONLimits(data, tc.maxDepth, tc.maxElements)
+		if tc.limit == "" {
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			continue
+		}
+		if le, ok := err.(*LimitError); !ok || le.Limit != tc.limit {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package encoding
+
+import (
+	"io"
+	"reflect"
+
+	"github.com/ugorji/go/codec"
+)
+
+// MSGPACK is the key for the msgpack encoding
+const MSGPACK = "msgpack"
+
+var msgpackHandle = newMsgpackHandle()
+
+func newMsgpackHandle() *codec.MsgpackHandle {
+	h := &codec.MsgpackHandle{}
+	// decode the nested objects as the rest of decoders do and the raw values as strings
+	h.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	h.RawToString = true
+	h.WriteExt = true
+	return h
+}
+
+// NewMsgpackDecoder returns the right MessagePack decoder
+func NewMsgpackDecoder(isCollection bool) func(io.Reader, *map[string]interface{}) error {
+	if isCollection {
+		return MsgpackCollectionDecoder
+	}
+	return MsgpackDecoder
+}
+
+// MsgpackDecoder decodes a MessagePack map into a map
+func MsgpackDecoder(r io.Reader, v *map[string]interface{}) error {
+	return codec.NewDecoder(r, msgpackHandle).Decode(v)
+}
+
+// MsgpackCollectionDecoder decodes a MessagePack array and returns a map with the items
+// under the key 'collection'
+func MsgpackCollectionDecoder(r io.Reader, v *map[string]interface{}) error {
+	var collection []interface{}
+	if err := codec.NewDecoder(r, msgpackHandle).Decode(&collection); err != nil {
+		return err
+	}
+	*(v) = map[string]interface{}{"collection": collection}
+	return nil
+}
+
+// EncodeMsgpack writes the MessagePack representation of the value
+func EncodeMsgpack(w io.Writer, v interface{}) error {
+	return codec.NewEncoder(w, msgpackHandle).Encode(v)
+}
@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package encoding
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestMsgpackDecoder(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := EncodeMsgpack(buf, map[string]interface{}{
+		"name":  "lura",
+		"owner": map[string]interface{}{"login": "devops"},
+		"tags":  []interface{}{"a", "b"},
+	}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var result map[string]interface{}
+	if err := GetRegister().Get(MSGPACK)(false)(buf, &result); err != nil {
+		t.Error(err)
+		return
+	}
+	expected := map[string]interface{}{
+		"name":  "lura",
+		"owner": map[string]interface{}{"login": "devops"},
+		"tags":  []interface{}{"a", "b"},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("unexpected result: %#v", result)
+	}
+}
+
+func TestMsgpackCollectionDecoder(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := EncodeMsgpack(buf, []interface{}{map[string]interface{}{"id": "a"}, "b"}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	var result map[string]interface{}
+	if err := NewMsgpackDecoder(true)(buf, &result); err != nil {
+		t.Error(err)
+		return
+	}
+	expected := map[string]interface{}{"collection": []interface{}{map[string]interface{}{"id": "a"}, "b"}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("unexpected result: %#v", result)
+	}
+}
+
+func TestMsgpackDecoder_ko(t *testing.T) {
+	for _, isCollection := range []bool{true, false} {
+		var result map[string]interface{}
+		if err := NewMsgpackDecoder(isCollection)(bytes.NewReader([]byte{0xc1}), &result); err == nil {
+			t.Errorf("collection %v: error expected", isCollection)
+		}
+	}
+}
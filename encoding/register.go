@@ -30,6 +30,12 @@ func (r *DecoderRegister) Register(name string, dec func(bool) func(io.Reader, *
 	return nil
 }
 
+// Has returns true if there is a decoder factory registered with the name
+func (r *DecoderRegister) Has(name string) bool {
+	_, ok := r.data.Get(name)
+	return ok
+}
+
 // Get returns a decoder factory from the register by name. If no factory is found, it returns a JSON decoder factory
 func (r *DecoderRegister) Get(name string) func(bool) func(io.Reader, *map[string]interface{}) error {
 	for _, n := range []string{name, JSON} {
@@ -49,6 +55,11 @@ var (
 		SAFE_JSON: NewSafeJSONDecoder,
 		STRING:    NewStringDecoder,
 		NOOP:      noOpDecoderFactory,
+		XML:       NewXMLDecoder,
+		YAML:      NewYAMLDecoder,
+		CSV:       NewCSVDecoder,
+		FORM:      NewFormDecoder,
+		MSGPACK:   NewMsgpackDecoder,
 	}
 )
 
@@ -0,0 +1,243 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package encoding
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// XML is the key for the xml encoding
+const XML = "xml"
+
+// ErrEmptyXMLDocument is the error returned when the XML document has no root element
+var ErrEmptyXMLDocument = errors.New("xml: no root element found")
+
+// XMLOptions defines how the XML documents are converted into maps
+type XMLOptions struct {
+	// AttributePrefix is added to the name of the attributes, so they do not collide with the
+	// child elements
+	AttributePrefix string
+	// IgnoreAttributes drops all the attributes
+	IgnoreAttributes bool
+	// TextKey is the key for the text of the elements with attributes or child elements
+	TextKey string
+	// ForceArrays lists the names of the elements always decoded as arrays, even when they
+	// appear just once. The repeated elements are always decoded as arrays.
+	ForceArrays []string
+	// StripNamespaces removes the namespace prefixes from the names of the elements and the
+	// attributes, and drops the namespace declarations
+	StripNamespaces bool
+}
+
+// DefaultXMLOptions are the options used by the decoder registered under the XML key
+var DefaultXMLOptions = XMLOptions{
+	AttributePrefix: "-",
+	TextKey:         "#text",
+	StripNamespaces: true,
+}
+
+// NewXMLDecoder returns the XML decoder using the DefaultXMLOptions
+func NewXMLDecoder(isCollection bool) func(io.Reader, *map[string]interface{}) error {
+	return NewXMLDecoderFactory(DefaultXMLOptions)(isCollection)
+}
+
+// NewXMLDecoderFactory returns a DecoderFactory for XML documents with the received options,
+// so it can be registered under a custom name:
+//
+//	encoding.GetRegister().Register("soap", encoding.NewXMLDecoderFactory(opts))
+//
+// The entity decoders return a map with the root element as the only key. The collection
+// decoders return a map with the children of the root element at the 'collection' key.
+// All the values are decoded as strings.
+func NewXMLDecoderFactory(opts XMLOptions) DecoderFactory {
+	forced := make(map[string]struct{}, len(opts.ForceArrays))
+	for _, name := range opts.ForceArrays {
+		forced[name] = struct{}{}
+	}
+	x := xmlDecoder{opts: opts, forceArrays: forced}
+
+	return func(isCollection bool) func(io.Reader, *map[string]interface{}) error {
+		if isCollection {
+			return x.decodeCollection
+		}
+		return x.decode
+	}
+}
+
+type xmlDecoder struct {
+	opts        XMLOptions
+	forceArrays map[string]struct{}
+}
+
+type xmlNode struct {
+	name     string
+	raw      xml.Name
+	children map[string]interface{}
+	text     strings.Builder
+}
+
+func (x xmlDecoder) decode(r io.Reader, v *map[string]interface{}) error {
+	name, value, err := x.parse(r)
+	if err != nil {
+		return err
+	}
+	*v = map[string]interface{}{name: value}
+	return nil
+}
+
+func (x xmlDecoder) decodeCollection(r io.Reader, v *map[string]interface{}) error {
+	_, value, err := x.parse(r)
+	if err != nil {
+		return err
+	}
+
+	collection := []interface{}{}
+	if m, ok := value.(map[string]interface{}); ok {
+		children := make([]string, 0, len(m))
+		for k := range m {
+			if k != x.opts.TextKey && (x.opts.AttributePrefix == "" || !strings.HasPrefix(k, x.opts.AttributePrefix)) {
+				children = append(children, k)
+			}
+		}
+		switch len(children) {
+		case 0:
+		case 1:
+			if items, ok := m[children[0]].([]interface{}); ok {
+				collection = items
+			} else {
+				collection = append(collection, m[children[0]])
+			}
+		default:
+			collection = append(collection, m)
+		}
+	}
+	*v = map[string]interface{}{"collection": collection}
+	return nil
+}
+
+// parse returns the name and the value of the root element
+func (x xmlDecoder) parse(r io.Reader) (string, interface{}, error) {
+	d := xml.NewDecoder(r)
+	d.CharsetReader = charset.NewReaderLabel
+
+	var stack []*xmlNode
+	for {
+		// the raw tokens keep the namespace prefixes instead of the namespace URLs
+		token, err := d.RawToken()
+		if err == io.EOF {
+			return "", nil, ErrEmptyXMLDocument
+		}
+		if err != nil {
+			return "", nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{name: x.name(t.Name), raw: t.Name, children: map[string]interface{}{}}
+			if !x.opts.IgnoreAttributes {
+				for _, attr := range t.Attr {
+					if x.opts.StripNamespaces && (attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns")) {
+						continue
+					}
+					node.children[x.opts.AttributePrefix+x.name(attr.Name)] = attr.Value
+				}
+			}
+			stack = append(stack, node)
+
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(t)
+			}
+
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return "", nil, errors.New("xml: unexpected end element " + t.Name.Local)
+			}
+			node := stack[len(stack)-1]
+			if t.Name != node.raw {
+				return "", nil, fmt.Errorf("xml: element <%s> closed by </%s>", xmlName(node.raw), xmlName(t.Name))
+			}
+			stack = stack[:len(stack)-1]
+			value := x.value(node)
+			if len(stack) == 0 {
+				if err := checkXMLTrailer(d); err != nil {
+					return "", nil, err
+				}
+				return node.name, value, nil
+			}
+			x.addChild(stack[len(stack)-1], node.name, value)
+		}
+	}
+}
+
+// checkXMLTrailer returns an error if there is something else than whitespaces or comments
+// after the root element
+func checkXMLTrailer(d *xml.Decoder) error {
+	for {
+		token, err := d.RawToken()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.Comment:
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) > 0 {
+				return errors.New("xml: unexpected data after the root element")
+			}
+		default:
+			return errors.New("xml: unexpected element after the root element")
+		}
+	}
+}
+
+func xmlName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+func (x xmlDecoder) name(n xml.Name) string {
+	if x.opts.StripNamespaces {
+		return n.Local
+	}
+	return xmlName(n)
+}
+
+func (x xmlDecoder) value(node *xmlNode) interface{} {
+	text := strings.TrimSpace(node.text.String())
+	if len(node.children) == 0 {
+		return text
+	}
+	if text != "" {
+		node.children[x.opts.TextKey] = text
+	}
+	return node.children
+}
+
+func (x xmlDecoder) addChild(parent *xmlNode, name string, value interface{}) {
+	current, ok := parent.children[name]
+	if !ok {
+		if _, force := x.forceArrays[name]; force {
+			parent.children[name] = []interface{}{value}
+			return
+		}
+		parent.children[name] = value
+		return
+	}
+	if items, ok := current.([]interface{}); ok {
+		parent.children[name] = append(items, value)
+		return
+	}
+	parent.children[name] = []interface{}{current, value}
+}
@@ -0,0 +1,148 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package encoding
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const xmlFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns="urn:items">
+	<soap:Body>
+		<items count="2">
+			<item id="a"><name>first</name></item>
+			<item id="b"><name lang="en">second</name></item>
+		</items>
+	</soap:Body>
+</soap:Envelope>`
+
+func TestNewXMLDecoder_entity(t *testing.T) {
+	var result map[string]interface{}
+	if err := GetRegister().Get(XML)(false)(strings.NewReader(xmlFeed), &result); err != nil {
+		t.Error(err)
+		return
+	}
+
+	expected := map[string]interface{}{
+		"Envelope": map[string]interface{}{
+			"Body": map[string]interface{}{
+				"items": map[string]interface{}{
+					"-count": "2",
+					"item": []interface{}{
+						map[string]interface{}{"-id": "a", "name": "first"},
+						map[string]interface{}{"-id": "b", "name": map[string]interface{}{"-lang": "en", "#text": "second"}},
+					},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("unexpected result: %v", result)
+	}
+}
+
+func TestNewXMLDecoderFactory(t *testing.T) {
+	decoder := NewXMLDecoderFactory(XMLOptions{
+		IgnoreAttributes: true,
+		ForceArrays:      []string{"soap:Body"},
+	})(false)
+
+	var result map[string]interface{}
+	if err := decoder(strings.NewReader(xmlFeed), &result); err != nil {
+		t.Error(err)
+		return
+	}
+
+	expected := map[string]interface{}{
+		"soap:Envelope": map[string]interface{}{
+			"soap:Body": []interface{}{
+				map[string]interface{}{
+					"items": map[string]interface{}{
+						"item": []interface{}{
+							map[string]interface{}{"name": "first"},
+							map[string]interface{}{"name": "second"},
+						},
+					},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("unexpected result: %v", result)
+	}
+}
+
+func TestNewXMLDecoder_collection(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		in       string
+		expected []interface{}
+	}{
+		{
+			name:     "repeated",
+			in:       `<list total="2"><user>a</user><user>b</user></list>`,
+			expected: []interface{}{"a", "b"},
+		},
+		{
+			name:     "single",
+			in:       `<list><user>a</user></list>`,
+			expected: []interface{}{"a"},
+		},
+		{
+			name:     "empty",
+			in:       `<list/>`,
+			expected: []interface{}{},
+		},
+		{
+			name:     "mixed",
+			in:       `<list><user>a</user><group>b</group></list>`,
+			expected: []interface{}{map[string]interface{}{"user": "a", "group": "b"}},
+		},
+	} {
+		var result map[string]interface{}
+		if err := NewXMLDecoder(true)(strings.NewReader(tc.in), &result); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, map[string]interface{}{"collection": tc.expected}) {
+			t.Errorf("%s: unexpected result: %v", tc.name, result)
+		}
+	}
+}
+
+func TestNewXMLDecoder_charset(t *testing.T) {
+	in := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><city>M\xe1laga</city>"
+	var result map[string]interface{}
+	if err := NewXMLDecoder(false)(strings.NewReader(in), &result); err != nil {
+		t.Error(err)
+		return
+	}
+	if result["city"] != "Málaga" {
+		t.Errorf("unexpected result: %v", result)
+	}
+}
+
+func TestNewXMLDecoder_trailer(t *testing.T) {
+	var result map[string]interface{}
+	if err := NewXMLDecoder(false)(strings.NewReader("<a>b</a>\n<!-- trailing comment -->\n"), &result); err != nil {
+		t.Error(err)
+		return
+	}
+	if result["a"] != "b" {
+		t.Errorf("unexpected result: %v", result)
+	}
+}
+
+func TestNewXMLDecoder_ko(t *testing.T) {
+	for _, in := range []string{"", "<a><b></a>", "<!-- only a comment -->", "</a>", "<a><b></c></a>", "<x:a></y:a>", "<a/>garbage", "<a/><b/>", "<a/>\n<?pi x?>"} {
+		var result map[string]interface{}
+		if err := NewXMLDecoder(false)(strings.NewReader(in), &result); err == nil {
+			t.Errorf("%q: error expected", in)
+		}
+		if result != nil {
+			t.Errorf("%q: unexpected result: %v", in, result)
+		}
+	}
+}
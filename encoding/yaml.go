@@ -0,0 +1,36 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package encoding
+
+import (
+	"io"
+
+	"github.com/goccy/go-yaml"
+)
+
+// YAML is the key for the yaml encoding
+const YAML = "yaml"
+
+// NewYAMLDecoder returns the right YAML decoder
+func NewYAMLDecoder(isCollection bool) func(io.Reader, *map[string]interface{}) error {
+	if isCollection {
+		return YAMLCollectionDecoder
+	}
+	return YAMLDecoder
+}
+
+// YAMLDecoder decodes a YAML mapping into a map
+func YAMLDecoder(r io.Reader, v *map[string]interface{}) error {
+	return yaml.NewDecoder(r).Decode(v)
+}
+
+// YAMLCollectionDecoder decodes a YAML sequence and returns a map with the items under the
+// key 'collection'
+func YAMLCollectionDecoder(r io.Reader, v *map[string]interface{}) error {
+	var collection []interface{}
+	if err := yaml.NewDecoder(r).Decode(&collection); err != nil {
+		return err
+	}
+	*(v) = map[string]interface{}{"collection": collection}
+	return nil
+}
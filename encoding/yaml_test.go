@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package encoding
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewYAMLDecoder_entity(t *testing.T) {
+	in := `
+id: 42
+name: lura
+tags:
+  - a
+  - b
+owner:
+  login: devops
+`
+	var result map[string]interface{}
+	if err := GetRegister().Get(YAML)(false)(strings.NewReader(in), &result); err != nil {
+		t.Error(err)
+		return
+	}
+	if result["id"] != uint64(42) || result["name"] != "lura" {
+		t.Errorf("unexpected result: %v", result)
+	}
+	if !reflect.DeepEqual(result["tags"], []interface{}{"a", "b"}) {
+		t.Errorf("unexpected tags: %v", result["tags"])
+	}
+	if owner, ok := result["owner"].(map[string]interface{}); !ok || owner["login"] != "devops" {
+		t.Errorf("unexpected owner: %v", result["owner"])
+	}
+}
+
+func TestNewYAMLDecoder_collection(t *testing.T) {
+	in := "- name: a\n- name: b\n"
+	var result map[string]interface{}
+	if err := NewYAMLDecoder(true)(strings.NewReader(in), &result); err != nil {
+		t.Error(err)
+		return
+	}
+	expected := map[string]interface{}{"collection": []interface{}{
+		map[string]interface{}{"name": "a"},
+		map[string]interface{}{"name": "b"},
+	}}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("unexpected result: %v", result)
+	}
+}
+
+func TestNewYAMLDecoder_ko(t *testing.T) {
+	for _, isCollection := range []bool{true, false} {
+		var result map[string]interface{}
+		if err := NewYAMLDecoder(isCollection)(strings.NewReader("a: [b"), &result); err == nil {
+			t.Errorf("collection %v: error expected", isCollection)
+		}
+	}
+}
@@ -0,0 +1,664 @@
+// SPDX-License-Identifier: Apache-2.0
+
+/*
+Package jsonschema provides a dependency free validator supporting the most common
+subset of the JSON Schema (draft-07) validation keywords.
+
+Schemas are compiled from their decoded representation:
+
+	var raw interface{}
+	json.Unmarshal(schemaDefinition, &raw)
+	s, err := jsonschema.Compile(raw)
+	...
+	if violations := s.Validate(data); len(violations) > 0 {
+		return &jsonschema.ValidationError{Violations: violations}
+	}
+
+Supported keywords: type, enum, const, required, properties, patternProperties,
+additionalProperties, minProperties, maxProperties, items, additionalItems, minItems,
+maxItems, uniqueItems, minLength, maxLength, pattern, minimum, maximum, exclusiveMinimum,
+exclusiveMaximum, multipleOf, allOf, anyOf, oneOf, not, definitions, $defs and local $ref.
+
+The annotations (title, description, format, default, examples...) are ignored, while the
+schemas using unsupported assertion keywords (contains, propertyNames, dependencies,
+if/then/else...) are rejected with ErrInvalidSchema, so they never accept every value.
+*/
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Violation describes a single validation failure. Path is a JSON pointer to the
+// offending value.
+type Violation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	if v.Path == "" {
+		return v.Message
+	}
+	return v.Path + ": " + v.Message
+}
+
+// ValidationError is the error returned when a document does not match its schema
+type ValidationError struct {
+	Violations []Violation `json:"errors"`
+}
+
+// Error implements the error interface
+func (v *ValidationError) Error() string {
+	msgs := make([]string, len(v.Violations))
+	for i, violation := range v.Violations {
+		msgs[i] = violation.String()
+	}
+	return "schema validation failed: " + strings.Join(msgs, "; ")
+}
+
+// ErrInvalidSchema is returned when a schema can not be compiled
+var ErrInvalidSchema = errors.New("invalid schema")
+
+// Schema is a compiled JSON Schema
+type Schema struct {
+	always *bool
+
+	types    []string
+	enum     []interface{}
+	hasConst bool
+	constVal interface{}
+
+	required             []string
+	properties           map[string]*Schema
+	patternProperties    []patternSchema
+	additionalProperties *Schema
+	minProperties        *float64
+	maxProperties        *float64
+
+	items           *Schema
+	tupleItems      []*Schema
+	additionalItems *Schema
+	minItems        *float64
+	maxItems        *float64
+	uniqueItems     bool
+
+	minLength *float64
+	maxLength *float64
+	pattern   *regexp.Regexp
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+	multipleOf       *float64
+
+	allOf []*Schema
+	anyOf []*Schema
+	oneOf []*Schema
+	not   *Schema
+
+	ref *Schema
+}
+
+type patternSchema struct {
+	re     *regexp.Regexp
+	schema *Schema
+}
+
+// MustCompile is like Compile but panics if the schema can not be compiled
+func MustCompile(v interface{}) *Schema {
+	s, err := Compile(v)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Compile compiles the decoded representation of a JSON Schema
+func Compile(v interface{}) (*Schema, error) {
+	c := compiler{root: v, refs: map[string]*Schema{}}
+	return c.compile(v, "#")
+}
+
+// CompileJSON compiles the JSON encoded schema
+func CompileJSON(b []byte) (*Schema, error) {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSchema, err.Error())
+	}
+	return Compile(v)
+}
+
+// unsupportedKeywords are the assertion keywords of the JSON Schema not supported by the package
+var unsupportedKeywords = []string{
+	"contains",
+	"propertyNames",
+	"dependencies",
+	"if",
+	"then",
+	"else",
+	"dependentRequired",
+	"dependentSchemas",
+	"minContains",
+	"maxContains",
+	"prefixItems",
+	"unevaluatedItems",
+	"unevaluatedProperties",
+	"$dynamicRef",
+	"$recursiveRef",
+}
+
+type compiler struct {
+	root interface{}
+	refs map[string]*Schema
+}
+
+func (c *compiler) compile(v interface{}, location string) (*Schema, error) { // skipcq: GO-R1005
+	s := &Schema{}
+	switch t := v.(type) {
+	case bool:
+		s.always = &t
+		return s, nil
+	case map[string]interface{}:
+	default:
+		return nil, fmt.Errorf("%w: %s is not an object nor a boolean", ErrInvalidSchema, location)
+	}
+	m := v.(map[string]interface{})
+
+	for _, k := range unsupportedKeywords {
+		if _, ok := m[k]; ok {
+			return nil, fmt.Errorf("%w: %s/%s: unsupported keyword", ErrInvalidSchema, location, k)
+		}
+	}
+
+	if ref, ok := m["$ref"].(string); ok {
+		target, err := c.resolve(ref)
+		if err != nil {
+			return nil, err
+		}
+		s.ref = target
+		return s, nil
+	}
+
+	switch t := m["type"].(type) {
+	case string:
+		s.types = []string{t}
+	case []interface{}:
+		for _, tt := range t {
+			if name, ok := tt.(string); ok {
+				s.types = append(s.types, name)
+			}
+		}
+	}
+
+	if e, ok := m["enum"].([]interface{}); ok {
+		s.enum = e
+	}
+	if cv, ok := m["const"]; ok {
+		s.hasConst = true
+		s.constVal = cv
+	}
+
+	if r, ok := m["required"].([]interface{}); ok {
+		for _, name := range r {
+			if n, ok := name.(string); ok {
+				s.required = append(s.required, n)
+			}
+		}
+	}
+
+	var err error
+	if props, ok := m["properties"].(map[string]interface{}); ok {
+		s.properties = make(map[string]*Schema, len(props))
+		for k, p := range props {
+			if s.properties[k], err = c.compile(p, location+"/properties/"+k); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if props, ok := m["patternProperties"].(map[string]interface{}); ok {
+		keys := make([]string, 0, len(props))
+		for k := range props {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			re, err := regexp.Compile(k)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s/patternProperties: %s", ErrInvalidSchema, location, err.Error())
+			}
+			ps, err := c.compile(props[k], location+"/patternProperties/"+k)
+			if err != nil {
+				return nil, err
+			}
+			s.patternProperties = append(s.patternProperties, patternSchema{re, ps})
+		}
+	}
+	if s.additionalProperties, err = c.optional(m, "additionalProperties", location); err != nil {
+		return nil, err
+	}
+	s.minProperties = number(m, "minProperties")
+	s.maxProperties = number(m, "maxProperties")
+
+	switch items := m["items"].(type) {
+	case []interface{}:
+		s.tupleItems = make([]*Schema, len(items))
+		for i, item := range items {
+			if s.tupleItems[i], err = c.compile(item, fmt.Sprintf("%s/items/%d", location, i)); err != nil {
+				return nil, err
+			}
+		}
+	case nil:
+	default:
+		if s.items, err = c.compile(items, location+"/items"); err != nil {
+			return nil, err
+		}
+	}
+	if s.additionalItems, err = c.optional(m, "additionalItems", location); err != nil {
+		return nil, err
+	}
+	s.minItems = number(m, "minItems")
+	s.maxItems = number(m, "maxItems")
+	s.uniqueItems, _ = m["uniqueItems"].(bool)
+
+	s.minLength = number(m, "minLength")
+	s.maxLength = number(m, "maxLength")
+	if p, ok := m["pattern"].(string); ok {
+		if s.pattern, err = regexp.Compile(p); err != nil {
+			return nil, fmt.Errorf("%w: %s/pattern: %s", ErrInvalidSchema, location, err.Error())
+		}
+	}
+
+	s.minimum = number(m, "minimum")
+	s.maximum = number(m, "maximum")
+	s.exclusiveMinimum = number(m, "exclusiveMinimum")
+	s.exclusiveMaximum = number(m, "exclusiveMaximum")
+	s.multipleOf = number(m, "multipleOf")
+
+	for _, k := range []string{"allOf", "anyOf", "oneOf"} {
+		list, ok := m[k].([]interface{})
+		if !ok {
+			continue
+		}
+		compiled := make([]*Schema, len(list))
+		for i, item := range list {
+			if compiled[i], err = c.compile(item, fmt.Sprintf("%s/%s/%d", location, k, i)); err != nil {
+				return nil, err
+			}
+		}
+		switch k {
+		case "allOf":
+			s.allOf = compiled
+		case "anyOf":
+			s.anyOf = compiled
+		default:
+			s.oneOf = compiled
+		}
+	}
+	if s.not, err = c.optional(m, "not", location); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (c *compiler) optional(m map[string]interface{}, key, location string) (*Schema, error) {
+	v, ok := m[key]
+	if !ok {
+		return nil, nil
+	}
+	return c.compile(v, location+"/"+key)
+}
+
+func (c *compiler) resolve(ref string) (*Schema, error) {
+	if s, ok := c.refs[ref]; ok {
+		return s, nil
+	}
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("%w: only local references are supported, got %s", ErrInvalidSchema, ref)
+	}
+
+	var target interface{} = c.root
+	for _, part := range strings.Split(strings.TrimPrefix(ref, "#"), "/") {
+		if part == "" {
+			continue
+		}
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		m, ok := target.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: unresolvable reference %s", ErrInvalidSchema, ref)
+		}
+		if target, ok = m[part]; !ok {
+			return nil, fmt.Errorf("%w: unresolvable reference %s", ErrInvalidSchema, ref)
+		}
+	}
+
+	// register a placeholder before compiling the target so recursive schemas
+	// point to the same instance
+	placeholder := &Schema{}
+	c.refs[ref] = placeholder
+	s, err := c.compile(target, ref)
+	if err != nil {
+		return nil, err
+	}
+	*placeholder = *s
+	return placeholder, nil
+}
+
+func number(m map[string]interface{}, key string) *float64 {
+	if f, ok := toFloat(m[key]); ok {
+		return &f
+	}
+	return nil
+}
+
+// Validate checks the received value against the schema and returns the list of
+// violations found. The value is expected to use the types generated by the encoding/json
+// package when decoding into an interface{}, although other numeric types are accepted.
+func (s *Schema) Validate(v interface{}) []Violation {
+	var violations []Violation
+	s.validate(v, "", &violations)
+	return violations
+}
+
+func (s *Schema) validate(v interface{}, path string, out *[]Violation) { // skipcq: GO-R1005
+	if s.ref != nil {
+		s.ref.validate(v, path, out)
+		return
+	}
+	if s.always != nil {
+		if !*s.always {
+			addViolation(out, path, "no value is allowed")
+		}
+		return
+	}
+
+	if len(s.types) > 0 {
+		valid := false
+		for _, t := range s.types {
+			if isType(v, t) {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			addViolation(out, path, fmt.Sprintf("expected %s, got %s", strings.Join(s.types, " or "), typeName(v)))
+			return
+		}
+	}
+
+	if s.enum != nil {
+		found := false
+		for _, e := range s.enum {
+			if equal(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			addViolation(out, path, "value is not one of the allowed values")
+		}
+	}
+	if s.hasConst && !equal(s.constVal, v) {
+		addViolation(out, path, "value does not match the expected constant")
+	}
+
+	switch t := v.(type) {
+	case map[string]interface{}:
+		s.validateObject(t, path, out)
+	case []interface{}:
+		s.validateArray(t, path, out)
+	case string:
+		s.validateString(t, path, out)
+	default:
+		if f, ok := toFloat(v); ok {
+			s.validateNumber(f, path, out)
+		}
+	}
+
+	for _, sub := range s.allOf {
+		sub.validate(v, path, out)
+	}
+	if len(s.anyOf) > 0 {
+		valid := false
+		for _, sub := range s.anyOf {
+			if len(sub.Validate(v)) == 0 {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			addViolation(out, path, "value does not match any of the allowed schemas")
+		}
+	}
+	if len(s.oneOf) > 0 {
+		matches := 0
+		for _, sub := range s.oneOf {
+			if len(sub.Validate(v)) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			addViolation(out, path, fmt.Sprintf("value must match exactly one schema, matched %d", matches))
+		}
+	}
+	if s.not != nil && len(s.not.Validate(v)) == 0 {
+		addViolation(out, path, "value matches a forbidden schema")
+	}
+}
+
+func (s *Schema) validateObject(m map[string]interface{}, path string, out *[]Violation) {
+	for _, k := range s.required {
+		if _, ok := m[k]; !ok {
+			addViolation(out, path, fmt.Sprintf("missing required property %q", k))
+		}
+	}
+	if s.minProperties != nil && float64(len(m)) < *s.minProperties {
+		addViolation(out, path, fmt.Sprintf("expected at least %v properties", *s.minProperties))
+	}
+	if s.maxProperties != nil && float64(len(m)) > *s.maxProperties {
+		addViolation(out, path, fmt.Sprintf("expected at most %v properties", *s.maxProperties))
+	}
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		childPath := path + "/" + escape(k)
+		matched := false
+		if p, ok := s.properties[k]; ok {
+			matched = true
+			p.validate(m[k], childPath, out)
+		}
+		for _, pp := range s.patternProperties {
+			if pp.re.MatchString(k) {
+				matched = true
+				pp.schema.validate(m[k], childPath, out)
+			}
+		}
+		if matched || s.additionalProperties == nil {
+			continue
+		}
+		if s.additionalProperties.always != nil && !*s.additionalProperties.always {
+			addViolation(out, childPath, "additional property is not allowed")
+			continue
+		}
+		s.additionalProperties.validate(m[k], childPath, out)
+	}
+}
+
+func (s *Schema) validateArray(a []interface{}, path string, out *[]Violation) {
+	if s.minItems != nil && float64(len(a)) < *s.minItems {
+		addViolation(out, path, fmt.Sprintf("expected at least %v items", *s.minItems))
+	}
+	if s.maxItems != nil && float64(len(a)) > *s.maxItems {
+		addViolation(out, path, fmt.Sprintf("expected at most %v items", *s.maxItems))
+	}
+	if s.uniqueItems {
+	UniqueLoop:
+		for i := range a {
+			for j := i + 1; j < len(a); j++ {
+				if equal(a[i], a[j]) {
+					addViolation(out, path, fmt.Sprintf("items %d and %d are equal", i, j))
+					break UniqueLoop
+				}
+			}
+		}
+	}
+
+	for i, item := range a {
+		childPath := fmt.Sprintf("%s/%d", path, i)
+		switch {
+		case s.items != nil:
+			s.items.validate(item, childPath, out)
+		case i < len(s.tupleItems):
+			s.tupleItems[i].validate(item, childPath, out)
+		case s.tupleItems != nil && s.additionalItems != nil:
+			if s.additionalItems.always != nil && !*s.additionalItems.always {
+				addViolation(out, childPath, "additional item is not allowed")
+				continue
+			}
+			s.additionalItems.validate(item, childPath, out)
+		}
+	}
+}
+
+func (s *Schema) validateString(str string, path string, out *[]Violation) {
+	l := float64(utf8.RuneCountInString(str))
+	if s.minLength != nil && l < *s.minLength {
+		addViolation(out, path, fmt.Sprintf("expected at least %v characters", *s.minLength))
+	}
+	if s.maxLength != nil && l > *s.maxLength {
+		addViolation(out, path, fmt.Sprintf("expected at most %v characters", *s.maxLength))
+	}
+	if s.pattern != nil && !s.pattern.MatchString(str) {
+		addViolation(out, path, fmt.Sprintf("value does not match the pattern %q", s.pattern.String()))
+	}
+}
+
+func (s *Schema) validateNumber(f float64, path string, out *[]Violation) {
+	if s.minimum != nil && f < *s.minimum {
+		addViolation(out, path, fmt.Sprintf("expected a value greater than or equal to %v", *s.minimum))
+	}
+	if s.maximum != nil && f > *s.maximum {
+		addViolation(out, path, fmt.Sprintf("expected a value less than or equal to %v", *s.maximum))
+	}
+	if s.exclusiveMinimum != nil && f <= *s.exclusiveMinimum {
+		addViolation(out, path, fmt.Sprintf("expected a value greater than %v", *s.exclusiveMinimum))
+	}
+	if s.exclusiveMaximum != nil && f >= *s.exclusiveMaximum {
+		addViolation(out, path, fmt.Sprintf("expected a value less than %v", *s.exclusiveMaximum))
+	}
+	if s.multipleOf != nil && *s.multipleOf != 0 {
+		if q := f / *s.multipleOf; math.Abs(q-math.Round(q)) > 1e-9 {
+			addViolation(out, path, fmt.Sprintf("expected a multiple of %v", *s.multipleOf))
+		}
+	}
+}
+
+func addViolation(out *[]Violation, path, msg string) {
+	*out = append(*out, Violation{Path: path, Message: msg})
+}
+
+func escape(k string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(k, "~", "~0"), "/", "~1")
+}
+
+func isType(v interface{}, t string) bool {
+	switch t {
+	case "object":
+		_, ok := v.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := v.([]interface{})
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "null":
+		return v == nil
+	case "number":
+		_, ok := toFloat(v)
+		return ok
+	case "integer":
+		f, ok := toFloat(v)
+		return ok && f == math.Trunc(f)
+	}
+	return false
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	if _, ok := toFloat(v); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func equal(a, b interface{}) bool {
+	fa, okA := toFloat(a)
+	fb, okB := toFloat(b)
+	if okA && okB {
+		return fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,163 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package jsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSchema_Validate(t *testing.T) {
+	s, err := CompileJSON([]byte(`{
+		"type": "object",
+		"required": ["name", "age"],
+		"additionalProperties": false,
+		"definitions": {
+			"tag": {"type": "string", "minLength": 2, "pattern": "^[a-z]+$"}
+		},
+		"properties": {
+			"name": {"type": "string", "maxLength": 5},
+			"age": {"type": "integer", "minimum": 18, "maximum": 99},
+			"score": {"type": "number", "exclusiveMaximum": 10, "multipleOf": 0.5},
+			"role": {"enum": ["admin", "user"]},
+			"tags": {"type": "array", "items": {"$ref": "#/definitions/tag"}, "maxItems": 3, "uniqueItems": true},
+			"contact": {
+				"oneOf": [
+					{"type": "object", "required": ["email"]},
+					{"type": "object", "required": ["phone"]}
+				]
+			},
+			"nullable": {"type": ["string", "null"]},
+			"forbidden": {"not": {"type": "string"}}
+		}
+	}`))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for i, tc := range []struct {
+		doc        string
+		violations []Violation
+	}{
+		{
+			doc: `{"name":"foo","age":20,"score":9.5,"role":"admin","tags":["aa","bb"],"contact":{"email":"a@b.c"},"nullable":null,"forbidden":1}`,
+		},
+		{
+			doc: `{"name":"foobar","age":20.5}`,
+			violations: []Violation{
+				{"/age", "expected integer, got number"},
+				{"/name", "expected at most 5 characters"},
+			},
+		},
+		{
+			doc: `{"age":10,"extra":true}`,
+			violations: []Violation{
+				{"", `missing required property "name"`},
+				{"/age", "expected a value greater than or equal to 18"},
+				{"/extra", "additional property is not allowed"},
+			},
+		},
+		{
+			doc: `{"name":"a","age":30,"score":10,"role":"root","tags":["a","B1","cc","cc"],"contact":{"email":"x","phone":"y"},"forbidden":"x"}`,
+			violations: []Violation{
+				{"/contact", "value must match exactly one schema, matched 2"},
+				{"/forbidden", "value matches a forbidden schema"},
+				{"/role", "value is not one of the allowed values"},
+				{"/score", "expected a value less than 10"},
+				{"/tags", "expected at most 3 items"},
+				{"/tags", "items 2 and 3 are equal"},
+				{"/tags/0", "expected at least 2 characters"},
+				{"/tags/1", `value does not match the pattern "^[a-z]+$"`},
+			},
+		},
+		{
+			doc:        `[]`,
+			violations: []Violation{{"", "expected object, got array"}},
+		},
+	} {
+		var doc interface{}
+		if err := json.Unmarshal([]byte(tc.doc), &doc); err != nil {
+			t.Error(err)
+			continue
+		}
+		if violations := s.Validate(doc); !reflect.DeepEqual(violations, tc.violations) {
+			t.Errorf("#%d: unexpected violations:\n\thave: %v\n\twant: %v", i, violations, tc.violations)
+		}
+	}
+}
+
+func TestSchema_recursive(t *testing.T) {
+	s, err := CompileJSON([]byte(`{
+		"$defs": {
+			"node": {
+				"type": "object",
+				"properties": {"children": {"type": "array", "items": {"$ref": "#/$defs/node"}}},
+				"required": ["id"]
+			}
+		},
+		"$ref": "#/$defs/node"
+	}`))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var doc interface{}
+	json.Unmarshal([]byte(`{"id":1,"children":[{"id":2},{"children":[]}]}`), &doc)
+	violations := s.Validate(doc)
+	if len(violations) != 1 || violations[0].Path != "/children/1" {
+		t.Errorf("unexpected violations: %v", violations)
+	}
+}
+
+func TestCompile_ko(t *testing.T) {
+	for i, schema := range []string{
+		`"string"`,
+		`{"pattern": "("}`,
+		`{"$ref": "#/definitions/unknown"}`,
+		`{"$ref": "http://example.com/schema.json"}`,
+		`{"properties": {"a": 42}}`,
+		`{]`,
+		`{"type": "array", "contains": {"type": "string"}}`,
+		`{"properties": {"a": {"propertyNames": {"maxLength": 3}}}}`,
+		`{"dependencies": {"a": ["b"]}}`,
+		`{"if": {"required": ["a"]}, "then": {"required": ["b"]}}`,
+	} {
+		if _, err := CompileJSON([]byte(schema)); !errors.Is(err, ErrInvalidSchema) {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+		}
+	}
+}
+
+func TestCompile_annotations(t *testing.T) {
+	s, err := CompileJSON([]byte(`{"title": "t", "description": "d", "$comment": "c", "type": "string", "format": "email", "default": "a", "examples": ["b"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if violations := s.Validate("not an email"); len(violations) > 0 {
+		t.Errorf("unexpected violations: %v", violations)
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	err := &ValidationError{Violations: []Violation{{"", "first"}, {"/a", "second"}}}
+	if msg := err.Error(); msg != "schema validation failed: first; /a: second" {
+		t.Errorf("unexpected error message: %s", msg)
+	}
+	b, _ := json.Marshal(err)
+	if string(b) != `{"errors":[{"path":"","message":"first"},{"path":"/a","message":"second"}]}` {
+		t.Errorf("unexpected serialization: %s", string(b))
+	}
+}
+
+func TestSchema_booleans(t *testing.T) {
+	if v := MustCompile(true).Validate(42); len(v) != 0 {
+		t.Errorf("unexpected violations: %v", v)
+	}
+	if v := MustCompile(false).Validate(42); len(v) != 1 {
+		t.Errorf("unexpected violations: %v", v)
+	}
+}
@@ -0,0 +1,147 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/logging"
+)
+
+const (
+	bodyTemplateKey         = "body_template"
+	bodyTemplateContentType = "application/json"
+	// defaultBodyTemplateMaxBodySize is the size limit of the client request bodies read by
+	// the body templates when the max_body_size option is not set
+	defaultBodyTemplateMaxBodySize = 10 << 20
+)
+
+// BodyTemplateData is the data available to the backend body templates. Body contains
+// the client request body decoded as JSON (nil if it is empty or not valid JSON) and
+// RawBody its original content.
+//
+// When used in a sequential pipe, the values of the previous backend responses are
+// available as params, the same way they are for the url patterns (e.g. {{.Params.Resp0_id}})
+type BodyTemplateData struct {
+	RequestTemplateData
+	Body    interface{}
+	RawBody string
+}
+
+// NewBodyTemplateMiddleware returns a middleware with or without a proxy replacing the body
+// of the request sent to the backend (depending on the configuration). The new body is
+// generated from the template defined in the backend extra config, that can be either a
+// string (rendered as is) or an object (rendered and encoded as JSON). The values of the
+// object rendered as strings can keep their JSON type with the json function, as in
+// {"count": "{{ json .Body.count }}"}.
+//
+// The client request body is bounded by the max_body_size option (in bytes, 10MB by default),
+// and the bigger bodies are rejected with a RequestBodyTooLargeError.
+func NewBodyTemplateMiddleware(logger logging.Logger, remote *config.Backend) Middleware {
+	cfg, ok := getBodyTemplateConfig(remote.ExtraConfig)
+	if !ok {
+		return emptyMiddlewareFallback(logger)
+	}
+
+	logPrefix := fmt.Sprintf("[BACKEND: %s %s -> %s][BodyTemplate]", remote.ParentEndpointMethod, remote.ParentEndpoint, remote.URLPattern)
+	tmpl, err := newValueTemplate("body", cfg.Template)
+	if err != nil {
+		logger.Error(logPrefix, "Parsing the body template:", err.Error())
+		return emptyMiddlewareFallback(logger)
+	}
+	_, isRaw := cfg.Template.(string)
+
+	logger.Debug(logPrefix, "Generating the backend request body with Content-Type", cfg.ContentType)
+
+	return func(next ...Proxy) Proxy {
+		if len(next) > 1 {
+			logger.Fatal("too many proxies for this %s %s -> %s proxy middleware: NewBodyTemplateMiddleware only accepts 1 proxy, got %d",
+				remote.ParentEndpointMethod, remote.ParentEndpoint, remote.URLPattern, len(next))
+			return nil
+		}
+
+		return func(ctx context.Context, req *Request) (*Response, error) {
+			data := BodyTemplateData{RequestTemplateData: NewRequestTemplateData(req)}
+			if req.Body != nil {
+				b, err := readRequestBody(req.Body, cfg.MaxBodySize)
+				if err != nil {
+					return nil, err
+				}
+				data.RawBody = string(b)
+				if len(b) > 0 {
+					json.Unmarshal(b, &data.Body)
+				}
+			}
+
+			v, err := tmpl.Execute(data)
+			if err != nil {
+				return nil, err
+			}
+
+			var b []byte
+			if isRaw {
+				b = []byte(v.(string))
+			} else if b, err = json.Marshal(v); err != nil {
+				return nil, err
+			}
+
+			req.Body = io.NopCloser(bytes.NewReader(b))
+			if req.Headers == nil {
+				req.Headers = map[string][]string{}
+			}
+			req.Headers["Content-Length"] = []string{strconv.Itoa(len(b))}
+			req.Headers["Content-Type"] = []string{cfg.ContentType}
+
+			return next[0](ctx, req)
+		}
+	}
+}
+
+type bodyTemplateConfig struct {
+	Template    interface{}
+	ContentType string
+	MaxBodySize int64
+}
+
+func getBodyTemplateConfig(extra config.ExtraConfig) (bodyTemplateConfig, bool) {
+	cfg := bodyTemplateConfig{ContentType: bodyTemplateContentType, MaxBodySize: defaultBodyTemplateMaxBodySize}
+	e, ok := extra[Namespace].(map[string]interface{})
+	if !ok {
+		return cfg, false
+	}
+	tmp, ok := e[bodyTemplateKey].(map[string]interface{})
+	if !ok {
+		return cfg, false
+	}
+	cfg.Template, ok = tmp["template"]
+	if !ok || cfg.Template == nil {
+		return cfg, false
+	}
+	if ct, ok := tmp["content_type"].(string); ok && ct != "" {
+		cfg.ContentType = ct
+	}
+	if size := sizeValue(tmp["max_body_size"]); size > 0 {
+		cfg.MaxBodySize = size
+	}
+	return cfg, true
+}
+
+// bodyTemplateSource returns the raw definition of the body template of the backend, so
+// other components can inspect it
+func bodyTemplateSource(remote *config.Backend) string {
+	cfg, ok := getBodyTemplateConfig(remote.ExtraConfig)
+	if !ok {
+		return ""
+	}
+	if s, ok := cfg.Template.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(cfg.Template)
+	return string(b)
+}
@@ -0,0 +1,224 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/logging"
+)
+
+func TestNewBodyTemplateMiddleware_object(t *testing.T) {
+	mw := NewBodyTemplateMiddleware(logging.NoOp, &config.Backend{
+		ExtraConfig: config.ExtraConfig{
+			Namespace: map[string]interface{}{
+				bodyTemplateKey: map[string]interface{}{
+					"template": map[string]interface{}{
+						"user_name": "{{.Body.name}}",
+						"account":   "{{.Params.Account}}",
+						"source":    `{{index .Headers "X-Source"}}`,
+						"fixed":     true,
+					},
+				},
+			},
+		},
+	})
+
+	expectedBody := map[string]interface{}{
+		"user_name": "foo",
+		"account":   "42",
+		"source":    "",
+		"fixed":     true,
+	}
+	p := mw(func(_ context.Context, req *Request) (*Response, error) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(body, expectedBody) {
+			t.Errorf("unexpected body: %v", body)
+		}
+		if ct := req.Headers["Content-Type"]; len(ct) != 1 || ct[0] != "application/json" {
+			t.Errorf("unexpected content type: %v", ct)
+		}
+		return &Response{Data: map[string]interface{}{"ok": true}}, nil
+	})
+
+	resp, err := p(context.Background(), &Request{
+		Body:    io.NopCloser(strings.NewReader(`{"name":"foo","password":"secret"}`)),
+		Params:  map[string]string{"Account": "42"},
+		Headers: map[string][]string{},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if resp == nil || resp.Data["ok"] != true {
+		t.Errorf("unexpected response: %v", resp)
+	}
+}
+
+func TestNewBodyTemplateMiddleware_jsonValues(t *testing.T) {
+	mw := NewBodyTemplateMiddleware(logging.NoOp, &config.Backend{
+		ExtraConfig: config.ExtraConfig{
+			Namespace: map[string]interface{}{
+				bodyTemplateKey: map[string]interface{}{
+					"template": map[string]interface{}{
+						"count":    "{{ json .Body.count }}",
+						"enabled":  "{{json .Body.enabled}}",
+						"address":  "{{ .Body.address | json }}",
+						"ids":      []interface{}{"{{ json .Body.id }}", "{{ .Body.id }}"},
+						"as_text":  "{{ .Body.count }}",
+						"embedded": "count: {{ json .Body.count }}",
+					},
+				},
+			},
+		},
+	})
+
+	expectedBody := map[string]interface{}{
+		"count":    3.0,
+		"enabled":  true,
+		"address":  map[string]interface{}{"city": "foo"},
+		"ids":      []interface{}{42.0, "42"},
+		"as_text":  "3",
+		"embedded": "count: 3",
+	}
+	p := mw(func(_ context.Context, req *Request) (*Response, error) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(body, expectedBody) {
+			t.Errorf("unexpected body: %v", body)
+		}
+		return &Response{}, nil
+	})
+
+	if _, err := p(context.Background(), &Request{
+		Body: io.NopCloser(strings.NewReader(`{"count":3,"enabled":true,"address":{"city":"foo"},"id":42}`)),
+	}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNewBodyTemplateMiddleware_raw(t *testing.T) {
+	mw := NewBodyTemplateMiddleware(logging.NoOp, &config.Backend{
+		ExtraConfig: config.ExtraConfig{
+			Namespace: map[string]interface{}{
+				bodyTemplateKey: map[string]interface{}{
+					"template":     `<user id="{{.Query.id}}">{{.Body.name}}</user>`,
+					"content_type": "application/xml",
+				},
+			},
+		},
+	})
+
+	p := mw(func(_ context.Context, req *Request) (*Response, error) {
+		b, _ := io.ReadAll(req.Body)
+		if string(b) != `<user id="1">foo</user>` {
+			t.Errorf("unexpected body: %s", string(b))
+		}
+		if cl := req.Headers["Content-Length"]; len(cl) != 1 || cl[0] != "23" {
+			t.Errorf("unexpected content length: %v", cl)
+		}
+		if ct := req.Headers["Content-Type"]; len(ct) != 1 || ct[0] != "application/xml" {
+			t.Errorf("unexpected content type: %v", ct)
+		}
+		return &Response{}, nil
+	})
+
+	if _, err := p(context.Background(), &Request{
+		Body:  io.NopCloser(strings.NewReader(`{"name":"foo"}`)),
+		Query: map[string][]string{"id": {"1"}},
+	}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNewBodyTemplateMiddleware_sequential(t *testing.T) {
+	var received string
+	backendFactory := func(b *config.Backend) Proxy {
+		return func(_ context.Context, req *Request) (*Response, error) {
+			if b.URLPattern == "/first" {
+				return &Response{Data: map[string]interface{}{"id": "abc"}, IsComplete: true}, nil
+			}
+			buf, _ := io.ReadAll(req.Body)
+			received = string(buf)
+			return &Response{Data: map[string]interface{}{"second": true}, IsComplete: true}, nil
+		}
+	}
+
+	endpoint := &config.EndpointConfig{
+		Endpoint: "/sequential",
+		Timeout:  time.Second,
+		ExtraConfig: config.ExtraConfig{
+			Namespace: map[string]interface{}{"sequential": true},
+		},
+		Backend: []*config.Backend{
+			{URLPattern: "/first", Host: []string{"http://example.com"}},
+			{
+				URLPattern: "/second",
+				Method:     "POST",
+				Host:       []string{"http://example.com"},
+				ExtraConfig: config.ExtraConfig{
+					Namespace: map[string]interface{}{
+						bodyTemplateKey: map[string]interface{}{
+							"template": `{"parent":"{{.Params.Resp0_id}}"}`,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	p, err := NewDefaultFactory(backendFactory, logging.NoOp).New(endpoint)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := p(context.Background(), &Request{Params: map[string]string{}, Headers: map[string][]string{}}); err != nil {
+		t.Error(err)
+		return
+	}
+	if received != `{"parent":"abc"}` {
+		t.Errorf("unexpected body: %s", received)
+	}
+}
+
+func TestNewBodyTemplateMiddleware_maxBodySize(t *testing.T) {
+	mw := NewBodyTemplateMiddleware(logging.NoOp, &config.Backend{
+		ExtraConfig: config.ExtraConfig{
+			Namespace: map[string]interface{}{
+				bodyTemplateKey: map[string]interface{}{
+					"template":      "{{.RawBody}}",
+					"max_body_size": 5,
+				},
+			},
+		},
+	})
+	calls := 0
+	p := mw(func(_ context.Context, _ *Request) (*Response, error) {
+		calls++
+		return &Response{}, nil
+	})
+
+	if _, err := p(context.Background(), &Request{Body: io.NopCloser(strings.NewReader("12345"))}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	_, err := p(context.Background(), &Request{Body: io.NopCloser(strings.NewReader("123456"))})
+	if sizeErr, ok := err.(RequestBodyTooLargeError); !ok || sizeErr.StatusCode() != 413 {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("unexpected number of calls to the backend: %d", calls)
+	}
+}
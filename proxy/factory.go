@@ -58,7 +58,12 @@ type defaultFactory struct {
 func (pf defaultFactory) New(cfg *config.EndpointConfig) (p Proxy, err error) {
 	switch len(cfg.Backend) {
 	case 0:
-		err = ErrNoBackends
+		if !cfg.Mocked() {
+			err = ErrNoBackends
+			return
+		}
+		// the mock middleware serves the responses without calling the next proxy
+		p = NoopProxy
 	case 1:
 		p, err = pf.newSingle(cfg)
 	default:
@@ -68,8 +73,11 @@ func (pf defaultFactory) New(cfg *config.EndpointConfig) (p Proxy, err error) {
 		return
 	}
 
+	p = NewHeaderRulesMiddleware(pf.logger, cfg)(p)
 	p = NewPluginMiddleware(pf.logger, cfg)(p)
+	p = NewMockMiddleware(pf.logger, cfg)(p)
 	p = NewStaticMiddleware(pf.logger, cfg)(p)
+	p = NewRequestValidationMiddleware(pf.logger, cfg)(p)
 	return
 }
 
@@ -89,10 +97,16 @@ func (pf defaultFactory) newSingle(cfg *config.EndpointConfig) (Proxy, error) {
 
 func (pf defaultFactory) newStack(backend *config.Backend) (p Proxy) {
 	p = pf.backendFactory(backend)
+	p = NewResponseSchemaMiddleware(pf.logger, backend)(p)
 	p = NewBackendPluginMiddleware(pf.logger, backend)(p)
 	p = NewGraphQLMiddleware(pf.logger, backend)(p)
+	p = NewBodyTemplateMiddleware(pf.logger, backend)(p)
+	p = NewBackendHeaderRulesMiddleware(pf.logger, backend)(p)
 	p = NewFilterHeadersMiddleware(pf.logger, backend)(p)
 	p = NewLoadBalancedMiddlewareWithSubscriberAndLogger(pf.logger, pf.subscriberFactory(backend))(p)
+	// the query rules must be applied after the query strings filter and before
+	// the balancer encodes the query strings into the URL:
+	p = NewQueryRulesMiddleware(pf.logger, backend)(p)
 	if backend.ConcurrentCalls > 1 {
 		p = NewConcurrentMiddlewareWithLogger(pf.logger, backend)(p)
 	}
@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/logging"
+	"github.com/luraproject/lura/v2/transport/grpc"
+)
+
+// NewGRPCBackendFactory returns a BackendFactory creating proxies that call unary gRPC methods
+// for the backends with a gRPC config, and delegating the rest of backends to the next factory.
+//
+// The input message is built from the body, the query strings and the params of the request (see
+// grpc.NewRequestMessage) and the reply is decoded into the Data of the response, so the usual
+// manipulations (allow, deny, mapping, group, target...) are available. The metadata of the reply
+// is returned as the headers of the response, without the ones of the gRPC protocol, and the
+// replies are bounded by the response limits of the backend. The gRPC status codes are returned
+// as grpc.ResponseError, which behave like client.HTTPResponseError errors with the equivalent
+// HTTP status code.
+func NewGRPCBackendFactory(logger logging.Logger, next BackendFactory) BackendFactory {
+	c := grpc.NewClient(nil)
+	return func(remote *config.Backend) Proxy {
+		opt, err := grpc.GetOptions(remote.ExtraConfig)
+		if err != nil {
+			if err != grpc.ErrNoConfigFound {
+				logger.Error(fmt.Sprintf("[BACKEND: %s %s -> %s][gRPC] %s", remote.ParentEndpointMethod, remote.ParentEndpoint, remote.URLPattern, err.Error()))
+			}
+			return next(remote)
+		}
+
+		logPrefix := fmt.Sprintf("[BACKEND: %s %s -> %s][gRPC]", remote.ParentEndpointMethod, remote.ParentEndpoint, remote.URLPattern)
+		method, err := grpc.LoadMethod(opt.DescriptorSet, opt.Method)
+		if err != nil {
+			// calling the backend as a regular HTTP service makes no sense, so all
+			// the requests will fail
+			logger.Error(logPrefix, err.Error())
+			return func(_ context.Context, _ *Request) (*Response, error) {
+				return nil, err
+			}
+		}
+		logger.Debug(logPrefix, "Calling the method", opt.Method)
+
+		ef := NewEntityFormatter(remote)
+		return func(ctx context.Context, request *Request) (*Response, error) {
+			in, err := grpc.NewRequestMessage(method, request.Body, request.Params, request.Query)
+			if request.Body != nil {
+				request.Body.Close()
+			}
+			if err != nil {
+				return nil, grpc.NewResponseError(grpc.InvalidArgument, err.Error())
+			}
+
+			out, headers, err := c.InvokeWithLimits(ctx, request.URL, method, in, request.Headers, remote.ResponseLimits)
+			if err != nil {
+				return nil, err
+			}
+
+			data, err := grpc.MessageToData(out, *opt)
+			if err != nil {
+				return nil, err
+			}
+
+			r := ef.Format(Response{
+				Data:       data,
+				IsComplete: true,
+				Metadata: Metadata{
+					StatusCode: http.StatusOK,
+					Headers:    headers,
+				},
+			})
+			return &r, nil
+		}
+	}
+}
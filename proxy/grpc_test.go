@@ -0,0 +1,209 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package proxy
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/encoding"
+	"github.com/luraproject/lura/v2/logging"
+	"github.com/luraproject/lura/v2/transport/grpc"
+)
+
+func TestNewGRPCBackendFactory(t *testing.T) {
+	descriptorSet := writeUsersDescriptorSet(t)
+	s := httptest.NewServer(h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users.Users/Get" {
+			w.Header().Set("Grpc-Status", "12")
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		// the request message has a single string field (id) with the tag 1, so
+		// the reply just appends the name field (tag 2) to the received message
+		payload := append(b[5:], protowireString(2, "user "+r.Header.Get("X-User"))...)
+		frame := make([]byte, 5, 5+len(payload))
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(payload)))
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.Header().Set("X-Backend", "users")
+		w.Write(append(frame, payload...))
+		w.Header().Set("Grpc-Status", "0")
+	}), &http2.Server{}))
+	defer s.Close()
+
+	nextCalled := false
+	next := func(_ *config.Backend) Proxy {
+		nextCalled = true
+		return NoopProxy
+	}
+	factory := NewGRPCBackendFactory(logging.NoOp, next)
+
+	factory(&config.Backend{})
+	if !nextCalled {
+		t.Error("the next factory should be called for regular backends")
+	}
+	nextCalled = false
+
+	p := factory(&config.Backend{
+		URLPattern: "/",
+		Mapping:    map[string]string{"name": "full_name"},
+		ExtraConfig: config.ExtraConfig{
+			grpc.Namespace: map[string]interface{}{
+				"descriptor_set": descriptorSet,
+				"method":         "users.Users/Get",
+			},
+		},
+	})
+	if nextCalled {
+		t.Error("the next factory should not be called for gRPC backends")
+	}
+
+	u, _ := url.Parse(s.URL + "/")
+	resp, err := p(context.Background(), &Request{
+		URL:     u,
+		Params:  map[string]string{"Id": "42"},
+		Headers: map[string][]string{"X-User": {"lura"}},
+		Body:    io.NopCloser(strings.NewReader("")),
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !resp.IsComplete || resp.Metadata.StatusCode != http.StatusOK {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if resp.Data["id"] != "42" || resp.Data["full_name"] != "user lura" {
+		t.Errorf("unexpected data: %v", resp.Data)
+	}
+	if h := resp.Metadata.Headers; h["X-Backend"][0] != "users" || h["Content-Type"] != nil || h["Trailer"] != nil {
+		t.Errorf("unexpected headers: %v", h)
+	}
+
+	// replies bigger than the response limits
+	p = factory(&config.Backend{
+		ResponseLimits: &config.ResponseLimits{MaxBodySize: 4},
+		ExtraConfig: config.ExtraConfig{
+			grpc.Namespace: map[string]interface{}{
+				"descriptor_set": descriptorSet,
+				"method":         "users.Users/Get",
+			},
+		},
+	})
+	_, err = p(context.Background(), &Request{URL: u, Params: map[string]string{"Id": "42"}})
+	if !errors.Is(err, encoding.ErrMaxBytesExceeded) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// unknown method on the server side
+	p = factory(&config.Backend{
+		ExtraConfig: config.ExtraConfig{
+			grpc.Namespace: map[string]interface{}{
+				"descriptor_set": descriptorSet,
+				"method":         "users.Users/Delete",
+			},
+		},
+	})
+	_, err = p(context.Background(), &Request{URL: u})
+	if re, ok := err.(grpc.ResponseError); !ok || re.StatusCode() != http.StatusNotImplemented {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestNewGRPCBackendFactory_ko(t *testing.T) {
+	descriptorSet := writeUsersDescriptorSet(t)
+	factory := NewGRPCBackendFactory(logging.NoOp, func(_ *config.Backend) Proxy {
+		t.Error("the next factory should not be called")
+		return NoopProxy
+	})
+	u, _ := url.Parse("http://127.0.0.1:1")
+
+	p := factory(&config.Backend{
+		ExtraConfig: config.ExtraConfig{
+			grpc.Namespace: map[string]interface{}{
+				"descriptor_set": descriptorSet,
+				"method":         "users.Users/Unknown",
+			},
+		},
+	})
+	if _, err := p(context.Background(), &Request{URL: u}); err == nil {
+		t.Error("error expected")
+	}
+
+	p = factory(&config.Backend{
+		ExtraConfig: config.ExtraConfig{
+			grpc.Namespace: map[string]interface{}{
+				"descriptor_set": descriptorSet,
+				"method":         "users.Users/Get",
+			},
+		},
+	})
+	_, err := p(context.Background(), &Request{URL: u, Body: io.NopCloser(strings.NewReader("{"))})
+	if re, ok := err.(grpc.ResponseError); !ok || re.StatusCode() != http.StatusBadRequest {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func protowireString(tag int, s string) []byte {
+	return append([]byte{byte(tag<<3 | 2), byte(len(s))}, s...)
+}
+
+// writeUsersDescriptorSet stores the descriptor set of a users.Users service with the Get
+// and Delete methods receiving a GetRequest{string id = 1} and returning a
+// User{string id = 1; string name = 2}
+func writeUsersDescriptorSet(t *testing.T) string {
+	str := func(name string, number int32) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+	}
+	method := func(name string) *descriptorpb.MethodDescriptorProto {
+		return &descriptorpb.MethodDescriptorProto{
+			Name:       proto.String(name),
+			InputType:  proto.String(".users.GetRequest"),
+			OutputType: proto.String(".users.User"),
+		}
+	}
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{{
+		Name:    proto.String("users.proto"),
+		Package: proto.String("users"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("GetRequest"), Field: []*descriptorpb.FieldDescriptorProto{str("id", 1)}},
+			{Name: proto.String("User"), Field: []*descriptorpb.FieldDescriptorProto{str("id", 1), str("name", 2)}},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name:   proto.String("Users"),
+			Method: []*descriptorpb.MethodDescriptorProto{method("Get"), method("Delete")},
+		}},
+	}}}
+
+	b, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "users.pb")
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,204 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/textproto"
+	"sort"
+
+	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/logging"
+)
+
+const headerRulesKey = "header_rules"
+
+// NewHeaderRulesMiddleware returns an endpoint middleware wrapped (if required) with a proxy
+// manipulating the headers of the request sent to the backends and the headers of the response
+// returned to the client, following the rules defined in the endpoint extra config.
+//
+// The rules are grouped under "request" and "response", and they are applied in this order:
+// "remove" (list of names), "rename" (old name -> new name), "set" and "append" (name -> value).
+// The values can be templates rendered with the RequestTemplateData of the received request.
+// Remember the backend input_headers filter is applied after the endpoint request rules.
+func NewHeaderRulesMiddleware(logger logging.Logger, endpoint *config.EndpointConfig) Middleware {
+	return newHeaderRulesMiddleware(logger, "ENDPOINT", endpoint.Endpoint, endpoint.ExtraConfig)
+}
+
+// NewBackendHeaderRulesMiddleware returns a backend middleware wrapped (if required) with a proxy
+// manipulating the headers of the request sent to the backend and the headers of its response,
+// following the rules defined in the backend extra config. See NewHeaderRulesMiddleware for
+// details about the rules. Response headers of the backends are only exposed to the client by
+// endpoints with a single backend.
+func NewBackendHeaderRulesMiddleware(logger logging.Logger, remote *config.Backend) Middleware {
+	return newHeaderRulesMiddleware(logger, "BACKEND",
+		fmt.Sprintf("%s %s -> %s", remote.ParentEndpointMethod, remote.ParentEndpoint, remote.URLPattern), remote.ExtraConfig)
+}
+
+func newHeaderRulesMiddleware(logger logging.Logger, tag, pattern string, extra config.ExtraConfig) Middleware {
+	e, ok := extra[Namespace].(map[string]interface{})
+	if !ok {
+		return emptyMiddlewareFallback(logger)
+	}
+	cfg, ok := e[headerRulesKey].(map[string]interface{})
+	if !ok {
+		return emptyMiddlewareFallback(logger)
+	}
+
+	logPrefix := fmt.Sprintf("[%s: %s][HeaderRules]", tag, pattern)
+	reqRules, err := newHeaderRules(cfg["request"])
+	if err != nil {
+		logger.Error(logPrefix, "Parsing the request rules:", err.Error())
+		return emptyMiddlewareFallback(logger)
+	}
+	respRules, err := newHeaderRules(cfg["response"])
+	if err != nil {
+		logger.Error(logPrefix, "Parsing the response rules:", err.Error())
+		return emptyMiddlewareFallback(logger)
+	}
+	if reqRules.isEmpty() && respRules.isEmpty() {
+		return emptyMiddlewareFallback(logger)
+	}
+
+	logger.Debug(logPrefix, "Adding the header manipulation rules")
+
+	return func(next ...Proxy) Proxy {
+		if len(next) > 1 {
+			logger.Fatal("too many proxies for this %s proxy middleware: newHeaderRulesMiddleware only accepts 1 proxy, got %d", pattern, len(next))
+			return nil
+		}
+		return func(ctx context.Context, request *Request) (*Response, error) {
+			data := NewRequestTemplateData(request)
+
+			if !reqRules.isEmpty() {
+				headers := CloneRequestHeaders(request.Headers)
+				if err := reqRules.apply(headers, data); err != nil {
+					return nil, err
+				}
+				r := request.Clone()
+				r.Headers = headers
+				request = &r
+			}
+
+			resp, err := next[0](ctx, request)
+			if resp == nil || respRules.isEmpty() {
+				return resp, err
+			}
+
+			if resp.Metadata.Headers == nil {
+				resp.Metadata.Headers = map[string][]string{}
+			}
+			if rErr := respRules.apply(resp.Metadata.Headers, data); rErr != nil {
+				logger.Error(logPrefix, "Applying the response rules:", rErr.Error())
+			}
+			return resp, err
+		}
+	}
+}
+
+type namedValue struct {
+	name  string
+	value valueTemplate
+}
+
+type headerRules struct {
+	remove []string
+	rename [][2]string
+	set    []namedValue
+	add    []namedValue
+}
+
+func (h headerRules) isEmpty() bool {
+	return len(h.remove) == 0 && len(h.rename) == 0 && len(h.set) == 0 && len(h.add) == 0
+}
+
+func (h headerRules) apply(headers map[string][]string, data RequestTemplateData) error {
+	for _, k := range h.remove {
+		delete(headers, k)
+	}
+	for _, r := range h.rename {
+		if vs, ok := headers[r[0]]; ok {
+			delete(headers, r[0])
+			headers[r[1]] = vs
+		}
+	}
+	for _, s := range h.set {
+		v, err := s.value.Execute(data)
+		if err != nil {
+			return err
+		}
+		headers[s.name] = []string{v.(string)}
+	}
+	for _, a := range h.add {
+		v, err := a.value.Execute(data)
+		if err != nil {
+			return err
+		}
+		headers[a.name] = append(headers[a.name], v.(string))
+	}
+	return nil
+}
+
+func newHeaderRules(v interface{}) (headerRules, error) {
+	rules := headerRules{}
+	cfg, ok := v.(map[string]interface{})
+	if !ok {
+		return rules, nil
+	}
+
+	if names, ok := cfg["remove"].([]interface{}); ok {
+		for _, name := range names {
+			if n, ok := name.(string); ok {
+				rules.remove = append(rules.remove, textproto.CanonicalMIMEHeaderKey(n))
+			}
+		}
+	}
+	if renames, ok := cfg["rename"].(map[string]interface{}); ok {
+		for _, from := range sortedKeys(renames) {
+			if to, ok := renames[from].(string); ok {
+				rules.rename = append(rules.rename, [2]string{
+					textproto.CanonicalMIMEHeaderKey(from),
+					textproto.CanonicalMIMEHeaderKey(to),
+				})
+			}
+		}
+	}
+
+	var err error
+	if rules.set, err = newHeaderValues(cfg["set"]); err != nil {
+		return rules, err
+	}
+	rules.add, err = newHeaderValues(cfg["append"])
+	return rules, err
+}
+
+func newHeaderValues(v interface{}) ([]namedValue, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	res := make([]namedValue, 0, len(m))
+	for _, name := range sortedKeys(m) {
+		value, ok := m[name].(string)
+		if !ok {
+			continue
+		}
+		name = textproto.CanonicalMIMEHeaderKey(name)
+		t, err := newValueTemplate(name, value)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, namedValue{name: name, value: t})
+	}
+	return res, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
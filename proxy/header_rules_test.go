@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package proxy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/logging"
+)
+
+func TestNewHeaderRulesMiddleware(t *testing.T) {
+	endpoint := &config.EndpointConfig{
+		Endpoint: "/tenants/{tenant}",
+		ExtraConfig: config.ExtraConfig{
+			Namespace: map[string]interface{}{
+				headerRulesKey: map[string]interface{}{
+					"request": map[string]interface{}{
+						"remove": []interface{}{"cookie"},
+						"rename": map[string]interface{}{"authorization": "x-original-auth"},
+						"set": map[string]interface{}{
+							"x-tenant":    "{{.Params.Tenant}}",
+							"x-client-ip": "{{.ClientIP}}",
+						},
+						"append": map[string]interface{}{"x-trace": "gw-{{.Query.trace}}"},
+					},
+					"response": map[string]interface{}{
+						"remove": []interface{}{"server"},
+						"set":    map[string]interface{}{"x-served-for": "{{.Params.Tenant}}"},
+					},
+				},
+			},
+		},
+	}
+
+	originalHeaders := map[string][]string{
+		"Cookie":          {"session=secret"},
+		"Authorization":   {"Bearer 123"},
+		"X-Trace":         {"client"},
+		"X-Forwarded-For": {"10.0.0.1"},
+	}
+	p := NewHeaderRulesMiddleware(logging.NoOp, endpoint)(func(_ context.Context, r *Request) (*Response, error) {
+		expected := map[string][]string{
+			"X-Original-Auth": {"Bearer 123"},
+			"X-Trace":         {"client", "gw-abc"},
+			"X-Forwarded-For": {"10.0.0.1"},
+			"X-Tenant":        {"acme"},
+			"X-Client-Ip":     {"10.0.0.1"},
+		}
+		if !reflect.DeepEqual(r.Headers, expected) {
+			t.Errorf("unexpected request headers: %v", r.Headers)
+		}
+		return &Response{
+			Data:     map[string]interface{}{"ok": true},
+			Metadata: Metadata{Headers: map[string][]string{"Server": {"nginx"}, "Content-Type": {"application/json"}}},
+		}, nil
+	})
+
+	resp, err := p(context.Background(), &Request{
+		Params:  map[string]string{"Tenant": "acme"},
+		Query:   map[string][]string{"trace": {"abc"}},
+		Headers: originalHeaders,
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	expected := map[string][]string{
+		"Content-Type": {"application/json"},
+		"X-Served-For": {"acme"},
+	}
+	if !reflect.DeepEqual(resp.Metadata.Headers, expected) {
+		t.Errorf("unexpected response headers: %v", resp.Metadata.Headers)
+	}
+	if len(originalHeaders) != 4 || len(originalHeaders["X-Trace"]) != 1 {
+		t.Errorf("the original headers have been modified: %v", originalHeaders)
+	}
+}
+
+func TestNewBackendHeaderRulesMiddleware(t *testing.T) {
+	remote := &config.Backend{
+		ExtraConfig: config.ExtraConfig{
+			Namespace: map[string]interface{}{
+				headerRulesKey: map[string]interface{}{
+					"response": map[string]interface{}{
+						"rename": map[string]interface{}{"x-powered-by": "x-backend"},
+					},
+				},
+			},
+		},
+	}
+	p := NewBackendHeaderRulesMiddleware(logging.NoOp, remote)(dummyProxy(&Response{
+		Metadata: Metadata{Headers: map[string][]string{"X-Powered-By": {"php"}}},
+	}))
+	resp, err := p(context.Background(), &Request{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !reflect.DeepEqual(resp.Metadata.Headers, map[string][]string{"X-Backend": {"php"}}) {
+		t.Errorf("unexpected response headers: %v", resp.Metadata.Headers)
+	}
+}
+
+func TestNewHeaderRulesMiddleware_noRules(t *testing.T) {
+	for _, extra := range []config.ExtraConfig{
+		{},
+		{Namespace: map[string]interface{}{headerRulesKey: map[string]interface{}{}}},
+		{Namespace: map[string]interface{}{headerRulesKey: map[string]interface{}{
+			"request": map[string]interface{}{"set": map[string]interface{}{"a": "{{.Params"}},
+		}}},
+	} {
+		expected := &Response{}
+		p := NewHeaderRulesMiddleware(logging.NoOp, &config.EndpointConfig{ExtraConfig: extra})(dummyProxy(expected))
+		if resp, _ := p(context.Background(), &Request{}); resp != expected {
+			t.Errorf("unexpected response: %v", resp)
+		}
+	}
+}
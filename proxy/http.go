@@ -31,19 +31,27 @@ func CustomHTTPProxyFactory(cf client.HTTPClientFactory) BackendFactory {
 	}
 }
 
-// NewHTTPProxy creates a http proxy with the injected configuration, HTTPClientFactory and Decoder
+// NewHTTPProxy creates a http proxy with the injected configuration, HTTPClientFactory and Decoder.
+// The proxies decoding the responses advertise the enabled content codings to the backends.
 func NewHTTPProxy(remote *config.Backend, cf client.HTTPClientFactory, decode encoding.Decoder) Proxy {
-	return NewHTTPProxyWithHTTPExecutor(remote, client.DefaultHTTPRequestExecutor(cf), decode)
+	if remote.Encoding == encoding.NOOP {
+		return NewHTTPProxyWithHTTPExecutor(remote, client.DefaultHTTPRequestExecutor(cf), decode)
+	}
+	return NewHTTPProxyWithHTTPExecutor(remote, client.NewContentEncodingHTTPRequestExecutor(cf), decode)
 }
 
 // NewHTTPProxyWithHTTPExecutor creates a http proxy with the injected configuration, HTTPRequestExecutor and Decoder
 func NewHTTPProxyWithHTTPExecutor(remote *config.Backend, re client.HTTPRequestExecutor, dec encoding.Decoder) Proxy {
 	if remote.Encoding == encoding.NOOP {
-		return NewHTTPProxyDetailed(remote, re, client.NoOpHTTPStatusHandler, NoOpHTTPResponseParser)
+		return NewHTTPProxyDetailed(remote, re, client.NoOpHTTPStatusHandler, NewNoOpHTTPResponseParser(remote.ResponseLimits))
+	}
+
+	if cfg, ok := NewStreamingJSONParserConfig(remote); ok {
+		return NewHTTPProxyDetailed(remote, re, client.GetHTTPStatusHandler(remote), DefaultHTTPResponseParserFactory(cfg))
 	}
 
 	ef := NewEntityFormatter(remote)
-	rp := DefaultHTTPResponseParserFactory(HTTPResponseParserConfig{dec, ef})
+	rp := DefaultHTTPResponseParserFactory(HTTPResponseParserConfig{Decoder: dec, EntityFormatter: ef, Limits: remote.ResponseLimits})
 	return NewHTTPProxyDetailed(remote, re, client.GetHTTPStatusHandler(remote), rp)
 }
 
@@ -52,6 +60,14 @@ const (
 	clientHTTPOptionRedirectPost string = "send_body_on_redirect"
 )
 
+func init() {
+	config.MustRegisterNamespace(config.Namespace{
+		Name:   clientHTTPOptions,
+		Scope:  config.BackendScope,
+		Schema: []byte(`{"type": "object", "additionalProperties": false, "properties": {"` + clientHTTPOptionRedirectPost + `": {"type": "boolean"}}}`),
+	})
+}
+
 // redirectPostReaderFactory checks if the clientHTTPOptionRedirectPost is enabled
 // This will read the body and return a bytes.Buffer with the body content, so we
 // delegate to http.NewRequest the population of request.GetBody so a redirect (307
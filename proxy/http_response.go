@@ -3,52 +3,138 @@
 package proxy
 
 import (
-	"compress/gzip"
 	"context"
 	"io"
 	"net/http"
+	"strings"
 
+	"github.com/luraproject/lura/v2/config"
 	"github.com/luraproject/lura/v2/encoding"
+	"github.com/luraproject/lura/v2/transport/http/client"
 )
 
+func init() {
+	config.MustRegisterNamespace(config.Namespace{
+		Name:  encoding.Namespace,
+		Scope: config.BackendScope,
+		Schema: []byte(`{
+			"type": "object",
+			"additionalProperties": false,
+			"properties": {"streaming": {"type": "boolean"}, "max_size": {"type": "integer", "minimum": 0}}
+		}`),
+	})
+}
+
 // HTTPResponseParser defines how the response is parsed from http.Response to Response object
 type HTTPResponseParser func(context.Context, *http.Response) (*Response, error)
 
 // DefaultHTTPResponseParserConfig defines a default HTTPResponseParserConfig
 var DefaultHTTPResponseParserConfig = HTTPResponseParserConfig{
-	func(_ io.Reader, _ *map[string]interface{}) error { return nil },
-	EntityFormatterFunc(func(r Response) Response { return r }),
+	Decoder:         func(_ io.Reader, _ *map[string]interface{}) error { return nil },
+	EntityFormatter: EntityFormatterFunc(func(r Response) Response { return r }),
 }
 
 // HTTPResponseParserConfig contains the config for a given HttpResponseParser
 type HTTPResponseParserConfig struct {
 	Decoder         encoding.Decoder
 	EntityFormatter EntityFormatter
+	// Limits defines the max sizes of the response bodies. If nil, the bodies are not bounded.
+	Limits *config.ResponseLimits
+}
+
+// NewStreamingJSONParserConfig returns a HTTPResponseParserConfig decoding the JSON responses
+// with the streaming decoder if it is enabled in the extra config of the backend or the backend
+// has limits for the JSON depth or number of elements. The target extraction and the allow and
+// deny lists are applied by the decoder while tokenizing the response, so the entity formatter
+// just applies the mappings and the group. The backends using other encodings or a flatmap
+// filter are not supported.
+func NewStreamingJSONParserConfig(remote *config.Backend) (HTTPResponseParserConfig, bool) {
+	cfg, _ := remote.ExtraConfig[encoding.Namespace].(map[string]interface{})
+	enabled, _ := cfg["streaming"].(bool)
+	limits := remote.ResponseLimits
+	if !enabled && (limits == nil || (limits.MaxJSONDepth == 0 && limits.MaxJSONElements == 0)) {
+		return HTTPResponseParserConfig{}, false
+	}
+	if e := strings.ToLower(remote.Encoding); e != "" && e != encoding.JSON {
+		return HTTPResponseParserConfig{}, false
+	}
+	if newFlatmapFormatter(remote.ExtraConfig, "", "") != nil {
+		return HTTPResponseParserConfig{}, false
+	}
+
+	opts := encoding.StreamingJSONOptions{
+		Target:    remote.Target,
+		AllowList: remote.AllowList,
+		DenyList:  remote.DenyList,
+	}
+	if limits != nil {
+		opts.MaxDepth = limits.MaxJSONDepth
+		opts.MaxElements = limits.MaxJSONElements
+	}
+	opts.MaxBytes = sizeValue(cfg["max_size"])
+
+	formatterCfg := *remote
+	formatterCfg.Target = ""
+	formatterCfg.AllowList = nil
+	formatterCfg.DenyList = nil
+
+	// the streaming decoder checks the JSON limits while decoding, so the parser does not
+	// have to check them again
+	var parserLimits *config.ResponseLimits
+	if limits != nil {
+		l := *limits
+		l.MaxJSONDepth = 0
+		l.MaxJSONElements = 0
+		parserLimits = &l
+	}
+
+	return HTTPResponseParserConfig{
+		Decoder:         encoding.NewStreamingJSONDecoder(opts)(remote.IsCollection),
+		EntityFormatter: NewEntityFormatter(&formatterCfg),
+		Limits:          parserLimits,
+	}, true
 }
 
 // HTTPResponseParserFactory creates HTTPResponseParser from a given HTTPResponseParserConfig
 type HTTPResponseParserFactory func(HTTPResponseParserConfig) HTTPResponseParser
 
-// DefaultHTTPResponseParserFactory is the default implementation of HTTPResponseParserFactory
+// DefaultHTTPResponseParserFactory is the default implementation of HTTPResponseParserFactory.
+// The bodies are decoded with the content decoders registered in the client package. The
+// responses with an unsupported Content-Encoding fail with client.ErrUnsupportedContentEncoding.
+// The responses exceeding the limits of the config fail with an *encoding.LimitError. The JSON
+// depth and elements limits are checked on the decoded data, whatever the encoding of the backend.
 func DefaultHTTPResponseParserFactory(cfg HTTPResponseParserConfig) HTTPResponseParser {
+	var limits config.ResponseLimits
+	if cfg.Limits != nil {
+		limits = *cfg.Limits
+	}
 	return func(_ context.Context, resp *http.Response) (*Response, error) {
 		defer resp.Body.Close()
 
-		var reader io.ReadCloser
-		switch resp.Header.Get("Content-Encoding") {
-		case "gzip":
-			gzipReader, err := gzip.NewReader(resp.Body)
-			if err != nil {
-				return nil, err
-			}
-			reader = gzipReader
-			defer reader.Close()
-		default:
-			reader = resp.Body
+		body, bodyLimit, err := limitBody(resp, limits)
+		if err != nil {
+			return nil, err
+		}
+
+		decoded, err := client.NewContentDecoder(body, resp.Header.Get("Content-Encoding"))
+		if err != nil {
+			return nil, bodyLimit.Err(err)
+		}
+		defer decoded.Close()
+
+		var reader io.Reader = decoded
+
+		var decompressedLimit *encoding.LimitedReader
+		if limits.MaxDecompressedSize > 0 {
+			decompressedLimit = encoding.NewLimitedReader(reader, encoding.LimitDecompressedSize, limits.MaxDecompressedSize)
+			reader = decompressedLimit
 		}
 
 		var data map[string]interface{}
 		if err := cfg.Decoder(reader, &data); err != nil {
+			return nil, bodyLimit.Err(decompressedLimit.Err(err))
+		}
+		if err := encoding.CheckJSONLimits(data, limits.MaxJSONDepth, limits.MaxJSONElements); err != nil {
 			return nil, err
 		}
 
@@ -58,6 +144,19 @@ func DefaultHTTPResponseParserFactory(cfg HTTPResponseParserConfig) HTTPResponse
 	}
 }
 
+// limitBody wraps the body of the response with the max body size limit, failing in advance if
+// the declared content length is already too big
+func limitBody(resp *http.Response, limits config.ResponseLimits) (io.Reader, *encoding.LimitedReader, error) {
+	if limits.MaxBodySize <= 0 {
+		return resp.Body, nil, nil
+	}
+	if resp.ContentLength > limits.MaxBodySize {
+		return nil, nil, &encoding.LimitError{Limit: encoding.LimitBodySize, Max: limits.MaxBodySize}
+	}
+	l := encoding.NewLimitedReader(resp.Body, encoding.LimitBodySize, limits.MaxBodySize)
+	return l, l, nil
+}
+
 // NoOpHTTPResponseParser is a HTTPResponseParser implementation that just copies the
 // http response body into the proxy response IO
 func NoOpHTTPResponseParser(ctx context.Context, resp *http.Response) (*Response, error) {
@@ -71,3 +170,30 @@ func NoOpHTTPResponseParser(ctx context.Context, resp *http.Response) (*Response
 		},
 	}, nil
 }
+
+// NewNoOpHTTPResponseParser returns a NoOpHTTPResponseParser bounding the size of the response
+// bodies. The responses declaring a bigger content length fail with an *encoding.LimitError and
+// the reads of the proxy response IO fail with it once the limit is exceeded. The decompressed
+// size limit only applies to the responses transparently decompressed by the http client.
+func NewNoOpHTTPResponseParser(limits *config.ResponseLimits) HTTPResponseParser {
+	if limits == nil || (limits.MaxBodySize <= 0 && limits.MaxDecompressedSize <= 0) {
+		return NoOpHTTPResponseParser
+	}
+	return func(ctx context.Context, resp *http.Response) (*Response, error) {
+		body, _, err := limitBody(resp, *limits)
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		if resp.Uncompressed && limits.MaxDecompressedSize > 0 {
+			body = encoding.NewLimitedReader(body, encoding.LimitDecompressedSize, limits.MaxDecompressedSize)
+		}
+		resp.Body = limitedReadCloser{Reader: body, Closer: resp.Body}
+		return NoOpHTTPResponseParser(ctx, resp)
+	}
+}
+
+type limitedReadCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -3,14 +3,24 @@
 package proxy
 
 import (
+	"bytes"
 	"compress/gzip"
+	"compress/zlib"
 	"context"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/luraproject/lura/v2/config"
 	"github.com/luraproject/lura/v2/encoding"
+	"github.com/luraproject/lura/v2/transport/http/client"
 )
 
 func TestNopHTTPResponseParser(t *testing.T) {
@@ -121,6 +131,50 @@ func TestDefaultHTTPResponseParser_gzipped_bad_header(t *testing.T) {
 	}
 }
 
+func TestDefaultHTTPResponseParser_contentEncodings(t *testing.T) {
+	payload := `{"msg":"some nice, interesting and long content"}`
+	brotliBody := &bytes.Buffer{}
+	bw := brotli.NewWriter(brotliBody)
+	bw.Write([]byte(payload))
+	bw.Close()
+
+	zstdBody := &bytes.Buffer{}
+	zw, _ := zstd.NewWriter(zstdBody)
+	zw.Write([]byte(payload))
+	zw.Close()
+
+	deflateBody := &bytes.Buffer{}
+	dw := zlib.NewWriter(deflateBody)
+	dw.Write([]byte(payload))
+	dw.Close()
+
+	parser := DefaultHTTPResponseParserFactory(HTTPResponseParserConfig{
+		Decoder:         encoding.JSONDecoder,
+		EntityFormatter: DefaultHTTPResponseParserConfig.EntityFormatter,
+	})
+
+	for contentEncoding, body := range map[string][]byte{
+		"br":      brotliBody.Bytes(),
+		"zstd":    zstdBody.Bytes(),
+		"deflate": deflateBody.Bytes(),
+	} {
+		resp := &http.Response{Header: http.Header{"Content-Encoding": {contentEncoding}}, Body: io.NopCloser(bytes.NewReader(body))}
+		result, err := parser(context.Background(), resp)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", contentEncoding, err)
+			continue
+		}
+		if m, ok := result.Data["msg"]; !ok || m != "some nice, interesting and long content" {
+			t.Errorf("%s: unexpected result: %v", contentEncoding, result.Data)
+		}
+	}
+
+	resp := &http.Response{Header: http.Header{"Content-Encoding": {"compress"}}, Body: io.NopCloser(strings.NewReader(payload))}
+	if _, err := parser(context.Background(), resp); !errors.Is(err, client.ErrUnsupportedContentEncoding) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
 func TestDefaultHTTPResponseParser_plain(t *testing.T) {
 	w := httptest.NewRecorder()
 	handler := func(w http.ResponseWriter, _ *http.Request) {
@@ -149,3 +203,256 @@ func TestDefaultHTTPResponseParser_plain(t *testing.T) {
 		t.Error("unexpected result")
 	}
 }
+
+func TestNewStreamingJSONParserConfig(t *testing.T) {
+	body := `{"data": {"user": {"id": 1, "name": "lura", "password": "secret"}, "debug": [1, 2, 3]}, "meta": {}}`
+	streaming := config.ExtraConfig{encoding.Namespace: map[string]interface{}{"streaming": true}}
+
+	for _, backend := range []*config.Backend{
+		{Target: "data", DenyList: []string{"debug", "user.password"}, Mapping: map[string]string{"user": "account"}, Group: "group"},
+		{Target: "data", AllowList: []string{"user.name"}},
+		{AllowList: []string{"data.user.id", "meta"}, Encoding: "JSON"},
+	} {
+		expected, err := DefaultHTTPResponseParserFactory(HTTPResponseParserConfig{
+			Decoder:         encoding.JSONDecoder,
+			EntityFormatter: NewEntityFormatter(backend),
+		})(context.Background(), &http.Response{Body: io.NopCloser(strings.NewReader(body))})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		backend.ExtraConfig = streaming
+		cfg, ok := NewStreamingJSONParserConfig(backend)
+		if !ok {
+			t.Errorf("%+v: the streaming decoder should be enabled", backend)
+			continue
+		}
+		resp, err := DefaultHTTPResponseParserFactory(cfg)(context.Background(), &http.Response{Body: io.NopCloser(strings.NewReader(body))})
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if !reflect.DeepEqual(resp.Data, expected.Data) {
+			t.Errorf("unexpected data. have: %v, want: %v", resp.Data, expected.Data)
+		}
+	}
+}
+
+func TestNewStreamingJSONParserConfig_maxSize(t *testing.T) {
+	cfg, ok := NewStreamingJSONParserConfig(&config.Backend{
+		ExtraConfig: config.ExtraConfig{encoding.Namespace: map[string]interface{}{"streaming": true, "max_size": 10.0}},
+	})
+	if !ok {
+		t.Error("the streaming decoder should be enabled")
+		return
+	}
+	_, err := DefaultHTTPResponseParserFactory(cfg)(context.Background(), &http.Response{
+		Body: io.NopCloser(strings.NewReader(`{"message": "too long"}`)),
+	})
+	if !errors.Is(err, encoding.ErrMaxBytesExceeded) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestNewStreamingJSONParserConfig_disabled(t *testing.T) {
+	for i, backend := range []*config.Backend{
+		{},
+		{ExtraConfig: config.ExtraConfig{encoding.Namespace: map[string]interface{}{"streaming": false}}},
+		{ExtraConfig: config.ExtraConfig{encoding.Namespace: map[string]interface{}{"streaming": true}}, Encoding: encoding.XML},
+		{ExtraConfig: config.ExtraConfig{
+			encoding.Namespace: map[string]interface{}{"streaming": true},
+			Namespace: map[string]interface{}{flatmapKey: []interface{}{
+				map[string]interface{}{"type": "del", "args": []interface{}{"a"}},
+			}},
+		}},
+	} {
+		if _, ok := NewStreamingJSONParserConfig(backend); ok {
+			t.Errorf("#%d: the streaming decoder should be disabled", i)
+		}
+	}
+}
+
+func TestDefaultHTTPResponseParser_limits(t *testing.T) {
+	body := `{"msg":"` + strings.Repeat("x", 1000) + `"}`
+	gzipped := &bytes.Buffer{}
+	gw := gzip.NewWriter(gzipped)
+	gw.Write([]byte(body))
+	gw.Close()
+
+	newResponse := func(compressed, chunked bool) *http.Response {
+		resp := &http.Response{Header: http.Header{}, ContentLength: int64(len(body))}
+		resp.Body = io.NopCloser(strings.NewReader(body))
+		if compressed {
+			resp.Header.Set("Content-Encoding", "gzip")
+			resp.ContentLength = int64(gzipped.Len())
+			resp.Body = io.NopCloser(bytes.NewReader(gzipped.Bytes()))
+		}
+		if chunked {
+			resp.ContentLength = -1
+		}
+		return resp
+	}
+
+	for _, tc := range []struct {
+		name       string
+		limits     *config.ResponseLimits
+		compressed bool
+		chunked    bool
+		limit      string
+	}{
+		{name: "no limits"},
+		{name: "big enough", limits: &config.ResponseLimits{MaxBodySize: int64(len(body)), MaxDecompressedSize: int64(len(body))}},
+		{name: "content length", limits: &config.ResponseLimits{MaxBodySize: 100}, limit: encoding.LimitBodySize},
+		{name: "chunked", limits: &config.ResponseLimits{MaxBodySize: 100}, chunked: true, limit: encoding.LimitBodySize},
+		{name: "compressed", limits: &config.ResponseLimits{MaxBodySize: 100}, compressed: true},
+		{name: "compressed and chunked", limits: &config.ResponseLimits{MaxBodySize: 10}, compressed: true, chunked: true, limit: encoding.LimitBodySize},
+		{name: "decompressed", limits: &config.ResponseLimits{MaxBodySize: 100, MaxDecompressedSize: 100}, compressed: true, limit: encoding.LimitDecompressedSize},
+		{name: "plain decompressed", limits: &config.ResponseLimits{MaxDecompressedSize: 100}, limit: encoding.LimitDecompressedSize},
+	} {
+		resp, err := DefaultHTTPResponseParserFactory(HTTPResponseParserConfig{
+			Decoder:         encoding.JSONDecoder,
+			EntityFormatter: DefaultHTTPResponseParserConfig.EntityFormatter,
+			Limits:          tc.limits,
+		})(context.Background(), newResponse(tc.compressed, tc.chunked))
+
+		if tc.limit == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tc.name, err)
+				continue
+			}
+			if len(resp.Data["msg"].(string)) != 1000 {
+				t.Errorf("%s: unexpected response: %v", tc.name, resp.Data)
+			}
+			continue
+		}
+		le, ok := err.(*encoding.LimitError)
+		if !ok || le.Limit != tc.limit {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+		if resp != nil {
+			t.Errorf("%s: unexpected response: %v", tc.name, resp)
+		}
+	}
+}
+
+func TestNewStreamingJSONParserConfig_jsonLimits(t *testing.T) {
+	backend := &config.Backend{ResponseLimits: &config.ResponseLimits{MaxJSONDepth: 2}}
+	cfg, ok := NewStreamingJSONParserConfig(backend)
+	if !ok {
+		t.Error("the streaming decoder should be enabled")
+		return
+	}
+	if cfg.Limits == nil || cfg.Limits.MaxJSONDepth != 0 {
+		t.Errorf("unexpected limits: %v", cfg.Limits)
+	}
+
+	_, err := DefaultHTTPResponseParserFactory(cfg)(context.Background(), &http.Response{
+		Body: io.NopCloser(strings.NewReader(`{"a": {"b": {"c": true}}}`)),
+	})
+	if le, ok := err.(*encoding.LimitError); !ok || le.Limit != encoding.LimitJSONDepth {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	backend.Encoding = encoding.SAFE_JSON
+	if _, ok := NewStreamingJSONParserConfig(backend); ok {
+		t.Error("the streaming decoder should be disabled")
+	}
+	if _, ok := NewStreamingJSONParserConfig(&config.Backend{ResponseLimits: &config.ResponseLimits{MaxBodySize: 10}}); ok {
+		t.Error("the streaming decoder should be disabled")
+	}
+}
+
+func TestDefaultHTTPResponseParser_jsonLimits(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		limits *config.ResponseLimits
+		limit  string
+	}{
+		{name: "no limits"},
+		{name: "in bounds", limits: &config.ResponseLimits{MaxJSONDepth: 3, MaxJSONElements: 3}},
+		{name: "depth", limits: &config.ResponseLimits{MaxJSONDepth: 2}, limit: encoding.LimitJSONDepth},
+		{name: "elements", limits: &config.ResponseLimits{MaxJSONElements: 2}, limit: encoding.LimitJSONElements},
+	} {
+		resp, err := DefaultHTTPResponseParserFactory(HTTPResponseParserConfig{
+			Decoder:         encoding.SafeJSONDecoder,
+			EntityFormatter: DefaultHTTPResponseParserConfig.EntityFormatter,
+			Limits:          tc.limits,
+		})(context.Background(), &http.Response{
+			Body: io.NopCloser(strings.NewReader(`{"a": {"b": [true]}}`)),
+		})
+
+		if tc.limit == "" {
+			if err != nil || resp == nil {
+				t.Errorf("%s: unexpected error: %v", tc.name, err)
+			}
+			continue
+		}
+		if le, ok := err.(*encoding.LimitError); !ok || le.Limit != tc.limit {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+func TestNewNoOpHTTPResponseParser(t *testing.T) {
+	body := strings.Repeat("x", 1000)
+
+	if p := NewNoOpHTTPResponseParser(&config.ResponseLimits{MaxJSONDepth: 1}); reflect.ValueOf(p).Pointer() != reflect.ValueOf(NoOpHTTPResponseParser).Pointer() {
+		t.Error("the default no-op parser should be used")
+	}
+
+	p := NewNoOpHTTPResponseParser(&config.ResponseLimits{MaxBodySize: 100})
+	closed := false
+	_, err := p(context.Background(), &http.Response{
+		ContentLength: int64(len(body)),
+		Body:          closeNotifier{Reader: strings.NewReader(body), closed: &closed},
+	})
+	if le, ok := err.(*encoding.LimitError); !ok || le.Limit != encoding.LimitBodySize {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !closed {
+		t.Error("the body should be closed")
+	}
+
+	for _, tc := range []struct {
+		name         string
+		limits       *config.ResponseLimits
+		uncompressed bool
+		limit        string
+	}{
+		{name: "big enough", limits: &config.ResponseLimits{MaxBodySize: 1000}},
+		{name: "body", limits: &config.ResponseLimits{MaxBodySize: 100}, limit: encoding.LimitBodySize},
+		{name: "compressed", limits: &config.ResponseLimits{MaxDecompressedSize: 100}},
+		{name: "uncompressed", limits: &config.ResponseLimits{MaxDecompressedSize: 100}, uncompressed: true, limit: encoding.LimitDecompressedSize},
+	} {
+		resp, err := NewNoOpHTTPResponseParser(tc.limits)(context.Background(), &http.Response{
+			StatusCode:    http.StatusOK,
+			ContentLength: -1,
+			Uncompressed:  tc.uncompressed,
+			Body:          io.NopCloser(strings.NewReader(body)),
+		})
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		b, err := io.ReadAll(resp.Io)
+		if tc.limit == "" {
+			if err != nil || len(b) != len(body) {
+				t.Errorf("%s: unexpected result: %v %d", tc.name, err, len(b))
+			}
+			continue
+		}
+		if le, ok := err.(*encoding.LimitError); !ok || le.Limit != tc.limit {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+		}
+	}
+}
+
+type closeNotifier struct {
+	io.Reader
+	closed *bool
+}
+
+func (c closeNotifier) Close() error {
+	*c.closed = true
+	return nil
+}
@@ -17,6 +17,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/andybalholm/brotli"
+
 	"github.com/luraproject/lura/v2/config"
 	"github.com/luraproject/lura/v2/encoding"
 	"github.com/luraproject/lura/v2/transport/http/client"
@@ -97,6 +99,49 @@ func TestNewHTTPProxy_ok(t *testing.T) {
 	}
 }
 
+func TestNewHTTPProxy_contentEncoding(t *testing.T) {
+	backendServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "br") {
+			w.Write([]byte(`{"encoding":"identity"}`))
+			return
+		}
+		w.Header().Set("Content-Encoding", "br")
+		bw := brotli.NewWriter(w)
+		bw.Write([]byte(`{"encoding":"br"}`))
+		bw.Close()
+	}))
+	defer backendServer.Close()
+
+	client.SetAcceptEncoding("br", "gzip")
+	defer client.SetAcceptEncoding("gzip")
+
+	rpURL, _ := url.Parse(backendServer.URL)
+	for _, tc := range []struct {
+		backend  config.Backend
+		expected string
+	}{
+		{backend: config.Backend{Decoder: encoding.JSONDecoder}, expected: `{"encoding":"br"}`},
+		{backend: config.Backend{Decoder: encoding.NoOpDecoder, Encoding: encoding.NOOP}, expected: `{"encoding":"identity"}`},
+	} {
+		request := Request{Method: "GET", Path: "/", URL: rpURL, Headers: map[string][]string{}}
+		result, err := HTTPProxyFactory(http.DefaultClient)(&tc.backend)(context.Background(), &request)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.backend.Encoding, err)
+			continue
+		}
+		if tc.backend.Encoding == encoding.NOOP {
+			b, _ := io.ReadAll(result.Io)
+			if string(b) != tc.expected {
+				t.Errorf("%s: unexpected body: %s", tc.backend.Encoding, string(b))
+			}
+			continue
+		}
+		if v, ok := result.Data["encoding"]; !ok || v != "br" {
+			t.Errorf("%s: unexpected result: %v", tc.backend.Encoding, result.Data)
+		}
+	}
+}
+
 func TestNewHTTPProxy_cancel(t *testing.T) {
 	expectedMethod := "GET"
 	backendServer := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
@@ -207,6 +207,7 @@ func sequentialMergerConfig(cfg *config.EndpointConfig) [][]sequentialBackendRep
 	}
 	rePropagatedParams := regexp.MustCompile(`[Rr]esp(\d+)_?([\w-.]+)?`)
 	reUrlPatterns := regexp.MustCompile(`\{\{\.Resp(\d+)_([\w-.]+)\}\}`)
+	reBodyTemplates := regexp.MustCompile(`Resp(\d+)_([\w-.]+)`)
 	destKeyGenerator := func(i string, t string) string {
 		key := "Resp" + i
 		if t != "" {
@@ -233,6 +234,19 @@ func sequentialMergerConfig(cfg *config.EndpointConfig) [][]sequentialBackendRep
 		}
 
 		if i > 0 {
+			for _, match := range reBodyTemplates.FindAllStringSubmatch(bodyTemplateSource(b), -1) {
+				backendIndex, err := strconv.Atoi(match[1])
+				if err != nil || backendIndex >= i {
+					continue
+				}
+
+				sequentialReplacements[i] = append(sequentialReplacements[i], sequentialBackendReplacement{
+					backendIndex: backendIndex,
+					destination:  destKeyGenerator(match[1], match[2]),
+					source:       strings.Split(match[2], "."),
+				})
+			}
+
 			for _, p := range propagatedParams {
 				for _, match := range rePropagatedParams.FindAllStringSubmatch(p, -1) {
 					if len(match) > 1 {
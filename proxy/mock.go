@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/textproto"
+
+	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/encoding"
+	"github.com/luraproject/lura/v2/logging"
+)
+
+const mockKey = "mock"
+
+// NewMockMiddleware creates a proxy middleware that serves the mocked response defined in the
+// endpoint extra config without calling the next proxy, so the backends are never reached.
+// The mocked data, body and header values can be templates, rendered with the RequestTemplateData
+// of every request.
+//
+// The mocked response contains the data, the status code and the headers as metadata and it is
+// flagged as mocked, so the routers render it with its status code and headers whatever the
+// output encoding of the endpoint. The mocked body is returned as the response Io, so it is
+// rendered as it is. Without body, the endpoints using the no-op encoding get the data encoded
+// as JSON in the response Io, while the rest of endpoints render the data with their encoding.
+// The mocked endpoints do not require backends.
+func NewMockMiddleware(logger logging.Logger, endpointConfig *config.EndpointConfig) Middleware {
+	cfg, ok := getMockConfig(endpointConfig.ExtraConfig)
+	if !ok {
+		return emptyMiddlewareFallback(logger)
+	}
+
+	logPrefix := fmt.Sprintf("[ENDPOINT: %s][Mock]", endpointConfig.Endpoint)
+
+	data, err := newValueTemplate("mock.data", cfg.Data)
+	if err != nil {
+		logger.Error(logPrefix, "Parsing the mock data templates:", err.Error())
+		return emptyMiddlewareFallback(logger)
+	}
+	headers, err := newValueTemplate("mock.headers", cfg.Headers)
+	if err != nil {
+		logger.Error(logPrefix, "Parsing the mock headers templates:", err.Error())
+		return emptyMiddlewareFallback(logger)
+	}
+	var body *valueTemplate
+	if cfg.Body != nil {
+		b, err := newValueTemplate("mock.body", *cfg.Body)
+		if err != nil {
+			logger.Error(logPrefix, "Parsing the mock body template:", err.Error())
+			return emptyMiddlewareFallback(logger)
+		}
+		body = &b
+	}
+
+	logger.Debug(logPrefix, fmt.Sprintf("Serving a mocked response with status code %d", cfg.StatusCode))
+
+	return func(next ...Proxy) Proxy {
+		if len(next) > 1 {
+			logger.Fatal("too many proxies for this proxy middleware: NewMockMiddleware only accepts 1 proxy, got %d", len(next))
+			return nil
+		}
+		return func(_ context.Context, request *Request) (*Response, error) {
+			tmplData := NewRequestTemplateData(request)
+
+			d, err := data.Execute(tmplData)
+			if err != nil {
+				return nil, err
+			}
+			h, err := headers.Execute(tmplData)
+			if err != nil {
+				return nil, err
+			}
+
+			resp := &Response{
+				Data:       d.(map[string]interface{}),
+				IsComplete: true,
+				Metadata: Metadata{
+					StatusCode: cfg.StatusCode,
+					Headers:    make(map[string][]string, len(cfg.Headers)+1),
+					Mocked:     true,
+				},
+			}
+			for k, v := range h.(map[string]interface{}) {
+				resp.Metadata.Headers[textproto.CanonicalMIMEHeaderKey(k)] = []string{fmt.Sprintf("%v", v)}
+			}
+
+			if body != nil {
+				b, err := body.Execute(tmplData)
+				if err != nil {
+					return nil, err
+				}
+				resp.Io = bytes.NewBufferString(b.(string))
+				return resp, nil
+			}
+			if endpointConfig.OutputEncoding != encoding.NOOP {
+				return resp, nil
+			}
+
+			b, err := json.Marshal(resp.Data)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := resp.Metadata.Headers["Content-Type"]; !ok {
+				resp.Metadata.Headers["Content-Type"] = []string{"application/json"}
+			}
+			resp.Io = bytes.NewBuffer(b)
+			return resp, nil
+		}
+	}
+}
+
+type mockConfig struct {
+	StatusCode int
+	Headers    map[string]interface{}
+	Data       map[string]interface{}
+	Body       *string
+}
+
+func getMockConfig(extra config.ExtraConfig) (mockConfig, bool) {
+	cfg := mockConfig{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]interface{}{},
+		Data:       map[string]interface{}{},
+	}
+	e, ok := extra[Namespace].(map[string]interface{})
+	if !ok {
+		return cfg, false
+	}
+	tmp, ok := e[mockKey].(map[string]interface{})
+	if !ok {
+		return cfg, false
+	}
+
+	if v, ok := tmp["status_code"].(float64); ok && v > 0 {
+		cfg.StatusCode = int(v)
+	}
+	if v, ok := tmp["headers"].(map[string]interface{}); ok {
+		cfg.Headers = v
+	}
+	if v, ok := tmp["data"].(map[string]interface{}); ok {
+		cfg.Data = v
+	}
+	if v, ok := tmp["body"].(string); ok {
+		cfg.Body = &v
+	}
+	return cfg, true
+}
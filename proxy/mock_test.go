@@ -0,0 +1,173 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package proxy
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/encoding"
+	"github.com/luraproject/lura/v2/logging"
+)
+
+func TestNewMockMiddleware_data(t *testing.T) {
+	endpoint := config.EndpointConfig{
+		Endpoint:       "/mock/{id}",
+		OutputEncoding: encoding.NOOP,
+		ExtraConfig: config.ExtraConfig{
+			Namespace: map[string]interface{}{
+				mockKey: map[string]interface{}{
+					"status_code": 201.0,
+					"headers":     map[string]interface{}{"x-mocked-id": "{{.Params.Id}}"},
+					"data":        map[string]interface{}{"id": "{{.Params.Id}}", "ok": true},
+				},
+			},
+		},
+	}
+	p := NewMockMiddleware(logging.NoOp, &endpoint)(func(_ context.Context, _ *Request) (*Response, error) {
+		t.Error("the backend should not be called")
+		return nil, nil
+	})
+
+	resp, err := p(context.Background(), &Request{Params: map[string]string{"Id": "42"}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !reflect.DeepEqual(resp.Data, map[string]interface{}{"id": "42", "ok": true}) {
+		t.Errorf("unexpected data: %v", resp.Data)
+	}
+	if !resp.IsComplete || !resp.Metadata.Mocked {
+		t.Error("the response should be complete and mocked")
+	}
+	if resp.Metadata.StatusCode != 201 {
+		t.Errorf("unexpected status code: %d", resp.Metadata.StatusCode)
+	}
+	expectedHeaders := map[string][]string{
+		"X-Mocked-Id":  {"42"},
+		"Content-Type": {"application/json"},
+	}
+	if !reflect.DeepEqual(resp.Metadata.Headers, expectedHeaders) {
+		t.Errorf("unexpected headers: %v", resp.Metadata.Headers)
+	}
+	b, _ := io.ReadAll(resp.Io)
+	if string(b) != `{"id":"42","ok":true}` {
+		t.Errorf("unexpected body: %s", string(b))
+	}
+}
+
+func TestNewMockMiddleware_body(t *testing.T) {
+	endpoint := config.EndpointConfig{
+		ExtraConfig: config.ExtraConfig{
+			Namespace: map[string]interface{}{
+				mockKey: map[string]interface{}{
+					"headers": map[string]interface{}{"Content-Type": "text/plain"},
+					"body":    "hello, {{.Query.name}}",
+				},
+			},
+		},
+	}
+	p := NewMockMiddleware(logging.NoOp, &endpoint)(NoopProxy)
+
+	resp, err := p(context.Background(), &Request{Query: map[string][]string{"name": {"lura"}}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if resp.Metadata.StatusCode != 200 {
+		t.Errorf("unexpected status code: %d", resp.Metadata.StatusCode)
+	}
+	if ct := resp.Metadata.Headers["Content-Type"]; len(ct) != 1 || ct[0] != "text/plain" {
+		t.Errorf("unexpected content type: %v", ct)
+	}
+	b, _ := io.ReadAll(resp.Io)
+	if string(b) != "hello, lura" {
+		t.Errorf("unexpected body: %s", string(b))
+	}
+}
+
+func TestNewMockMiddleware_noConfig(t *testing.T) {
+	expected := &Response{Data: map[string]interface{}{"supu": 42}}
+	p := NewMockMiddleware(logging.NoOp, &config.EndpointConfig{})(dummyProxy(expected))
+	resp, err := p(context.Background(), &Request{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if resp != expected {
+		t.Errorf("unexpected response: %v", resp)
+	}
+}
+
+func TestNewMockMiddleware_badTemplate(t *testing.T) {
+	endpoint := config.EndpointConfig{
+		ExtraConfig: config.ExtraConfig{
+			Namespace: map[string]interface{}{
+				mockKey: map[string]interface{}{
+					"body": "{{.Query.name",
+				},
+			},
+		},
+	}
+	expected := &Response{Data: map[string]interface{}{"supu": 42}}
+	p := NewMockMiddleware(logging.NoOp, &endpoint)(dummyProxy(expected))
+	resp, _ := p(context.Background(), &Request{})
+	if resp != expected {
+		t.Errorf("unexpected response: %v", resp)
+	}
+}
+
+func TestNewMockMiddleware_encodedData(t *testing.T) {
+	endpoint := config.EndpointConfig{
+		Endpoint: "/mock",
+		ExtraConfig: config.ExtraConfig{
+			Namespace: map[string]interface{}{
+				mockKey: map[string]interface{}{"data": map[string]interface{}{"ok": true}},
+			},
+		},
+	}
+	resp, err := NewMockMiddleware(logging.NoOp, &endpoint)(NoopProxy)(context.Background(), &Request{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !reflect.DeepEqual(resp.Data, map[string]interface{}{"ok": true}) {
+		t.Errorf("unexpected data: %v", resp.Data)
+	}
+	if resp.Io != nil || len(resp.Metadata.Headers) != 0 {
+		t.Errorf("the data should be encoded by the router: %+v", resp)
+	}
+}
+
+func TestDefaultFactory_mockWithoutBackends(t *testing.T) {
+	endpoint := config.EndpointConfig{
+		Endpoint: "/mock",
+		ExtraConfig: config.ExtraConfig{
+			Namespace: map[string]interface{}{
+				mockKey: map[string]interface{}{"status_code": 202.0},
+			},
+		},
+	}
+	p, err := NewDefaultFactory(func(_ *config.Backend) Proxy {
+		t.Error("the backend factory should not be called")
+		return NoopProxy
+	}, logging.NoOp).New(&endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := p(context.Background(), &Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Metadata.StatusCode != 202 || !resp.Metadata.Mocked {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	endpoint.ExtraConfig = config.ExtraConfig{}
+	if _, err := NewDefaultFactory(nil, logging.NoOp).New(&endpoint); err != ErrNoBackends {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -13,6 +13,15 @@ import (
 	"github.com/luraproject/lura/v2/proxy/plugin"
 )
 
+func init() {
+	config.MustRegisterNamespace(config.Namespace{
+		Name:  plugin.Namespace,
+		Scope: config.EndpointScope | config.BackendScope,
+		// the config of every plugin is stored under its name
+		Schema: []byte(`{"type": "object", "required": ["name"], "properties": {"name": {"type": "array", "items": {"type": "string"}}}}`),
+	})
+}
+
 // NewPluginMiddleware returns an endpoint middleware wrapped (if required) with the plugin middleware.
 // The plugin middleware will try to load all the required plugins from the register and execute them in order.
 // RequestModifiers are executed before passing the request to the next middlware. ResponseModifiers are executed
@@ -7,6 +7,7 @@ package proxy
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"io"
 
@@ -17,10 +18,72 @@ import (
 // Namespace to be used in extra config
 const Namespace = "github.com/devopsfaith/krakend/proxy"
 
+func init() {
+	config.MustRegisterNamespace(config.Namespace{
+		Name:   Namespace,
+		Scope:  config.EndpointScope | config.BackendScope,
+		Schema: namespaceSchema(),
+	})
+}
+
+// namespaceSchema returns the JSON Schema of the namespace, with the keys of the options read by
+// the components of the package
+func namespaceSchema() json.RawMessage {
+	var (
+		object  = map[string]interface{}{"type": "object"}
+		str     = map[string]interface{}{"type": "string"}
+		boolean = map[string]interface{}{"type": "boolean"}
+		strList = map[string]interface{}{"type": "array", "items": str}
+	)
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": map[string]interface{}{
+			mergeKey:               str,
+			typeKey:                str,
+			isSequentialKey:        boolean,
+			sequentialPropagateKey: strList,
+			forceDeepCloneKey:      boolean,
+			flatmapKey: map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type":       "object",
+					"required":   []string{"type", "args"},
+					"properties": map[string]interface{}{"type": str, "args": strList},
+				},
+			},
+			shadowKey:        boolean,
+			shadowTimeoutKey: str,
+			staticKey: map[string]interface{}{
+				"type":       "object",
+				"required":   []string{"data"},
+				"properties": map[string]interface{}{"data": object, "strategy": str},
+			},
+			mockKey: object,
+			splitKey: map[string]interface{}{
+				"type":       "object",
+				"required":   []string{splitVariantsKey},
+				"properties": map[string]interface{}{splitVariantsKey: map[string]interface{}{"type": "array"}},
+			},
+			splitVariantKey:   str,
+			validationKey:     object,
+			responseSchemaKey: object,
+			headerRulesKey:    object,
+			queryRulesKey:     object,
+			bodyTemplateKey:   object,
+		},
+	}
+	b, _ := json.Marshal(schema)
+	return b
+}
+
 // Metadata is the Metadata of the Response which contains Headers and StatusCode
 type Metadata struct {
 	Headers    map[string][]string
 	StatusCode int
+	// Mocked flags the responses served by the mock middleware, so the routers render them with
+	// their status code and headers whatever the output encoding of the endpoint
+	Mocked bool
 }
 
 // Response is the entity returned by the proxy
@@ -11,6 +11,8 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/luraproject/lura/v2/config"
 )
 
 func TestEmptyMiddleware_ok(t *testing.T) {
@@ -133,3 +135,49 @@ func (d *dummyRC) IsClosed() bool {
 	res := d.closed
 	return res
 }
+
+func TestNamespace_strict(t *testing.T) {
+	cfg := config.ServiceConfig{
+		Version: config.ConfigVersion,
+		Host:    []string{"http://127.0.0.1:8080"},
+		Endpoints: []*config.EndpointConfig{
+			{
+				Endpoint: "/",
+				Method:   "GET",
+				ExtraConfig: config.ExtraConfig{
+					Namespace: map[string]interface{}{
+						"sequential": true,
+						"static":     map[string]interface{}{"data": map[string]interface{}{"a": 1}, "strategy": "always"},
+					},
+				},
+				Backend: []*config.Backend{
+					{
+						URLPattern: "/",
+						ExtraConfig: config.ExtraConfig{
+							Namespace: map[string]interface{}{
+								"flatmap_filter": []interface{}{
+									map[string]interface{}{"type": "del", "args": []interface{}{"a"}},
+								},
+							},
+							clientHTTPOptions: map[string]interface{}{clientHTTPOptionRedirectPost: true},
+						},
+					},
+				},
+			},
+		},
+	}
+	if err := cfg.ValidateStrict().Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	cfg.Endpoints[0].ExtraConfig[Namespace].(map[string]interface{})["sequental"] = true
+	cfg.Endpoints[0].ExtraConfig[Namespace].(map[string]interface{})["static"] = map[string]interface{}{}
+	r := cfg.ValidateStrict()
+	if len(r.Errors) != 1 {
+		t.Fatalf("unexpected errors: %v", r.Errors)
+	}
+	if msg := r.Errors[0].Error(); !strings.Contains(msg, `/sequental: additional property is not allowed`) ||
+		!strings.Contains(msg, `/static: missing required property "data"`) {
+		t.Errorf("unexpected error: %s", msg)
+	}
+}
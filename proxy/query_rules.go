@@ -0,0 +1,150 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/logging"
+)
+
+const queryRulesKey = "query_rules"
+
+// NewQueryRulesMiddleware returns a middleware with or without a proxy manipulating the query
+// strings sent to the backend (depending on the configuration). It must be placed between the
+// query strings filter and the load balancer, so the resulting query strings are the ones
+// encoded into the URL of the backend request.
+//
+// The rules are applied in this order:
+//   - rename: the query strings are renamed (old name -> new name)
+//   - set: the values are replaced by the rendered template (name -> value)
+//   - defaults: the values are added only if the query string is not present (name -> value)
+//   - remove_empty: the query strings without values or with empty values are dropped
+//
+// The set and defaults values can be constants or templates rendered with the
+// RequestTemplateData of the request (e.g. "{{.Params.Id}}" or "{{.Headers.Authorization}}").
+func NewQueryRulesMiddleware(logger logging.Logger, remote *config.Backend) Middleware {
+	cfg, ok := getQueryRulesConfig(remote.ExtraConfig)
+	if !ok {
+		return emptyMiddlewareFallback(logger)
+	}
+
+	logPrefix := fmt.Sprintf("[BACKEND: %s %s -> %s][QueryRules]", remote.ParentEndpointMethod, remote.ParentEndpoint, remote.URLPattern)
+	set, err := newQueryValues(cfg["set"])
+	if err != nil {
+		logger.Error(logPrefix, "Parsing the set rules:", err.Error())
+		return emptyMiddlewareFallback(logger)
+	}
+	defaults, err := newQueryValues(cfg["defaults"])
+	if err != nil {
+		logger.Error(logPrefix, "Parsing the defaults rules:", err.Error())
+		return emptyMiddlewareFallback(logger)
+	}
+	var rename [][2]string
+	if m, ok := cfg["rename"].(map[string]interface{}); ok {
+		for _, from := range sortedKeys(m) {
+			if to, ok := m[from].(string); ok {
+				rename = append(rename, [2]string{from, to})
+			}
+		}
+	}
+	removeEmpty, _ := cfg["remove_empty"].(bool)
+
+	logger.Debug(logPrefix, "Adding the query string manipulation rules")
+
+	return func(next ...Proxy) Proxy {
+		if len(next) > 1 {
+			logger.Fatal("too many proxies for this %s %s -> %s proxy middleware: NewQueryRulesMiddleware only accepts 1 proxy, got %d",
+				remote.ParentEndpointMethod, remote.ParentEndpoint, remote.URLPattern, len(next))
+			return nil
+		}
+		return func(ctx context.Context, request *Request) (*Response, error) {
+			// the query strings are not cloned by Request.Clone, so we need a fresh copy
+			query := make(url.Values, len(request.Query)+len(set)+len(defaults))
+			for k, vs := range request.Query {
+				query[k] = append([]string{}, vs...)
+			}
+
+			for _, r := range rename {
+				if vs, ok := query[r[0]]; ok {
+					delete(query, r[0])
+					query[r[1]] = vs
+				}
+			}
+
+			var data RequestTemplateData
+			if len(set) > 0 || len(defaults) > 0 {
+				data = NewRequestTemplateData(request)
+			}
+			for _, s := range set {
+				v, err := s.value.Execute(data)
+				if err != nil {
+					return nil, err
+				}
+				query[s.name] = []string{v.(string)}
+			}
+			for _, d := range defaults {
+				if _, ok := query[d.name]; ok {
+					continue
+				}
+				v, err := d.value.Execute(data)
+				if err != nil {
+					return nil, err
+				}
+				query[d.name] = []string{v.(string)}
+			}
+
+			if removeEmpty {
+				for k, vs := range query {
+					values := vs[:0]
+					for _, v := range vs {
+						if v != "" {
+							values = append(values, v)
+						}
+					}
+					if len(values) == 0 {
+						delete(query, k)
+						continue
+					}
+					query[k] = values
+				}
+			}
+
+			r := request.Clone()
+			r.Query = query
+			return next[0](ctx, &r)
+		}
+	}
+}
+
+func newQueryValues(v interface{}) ([]namedValue, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	res := make([]namedValue, 0, len(m))
+	for _, name := range sortedKeys(m) {
+		value, ok := m[name].(string)
+		if !ok {
+			value = fmt.Sprintf("%v", m[name])
+		}
+		t, err := newValueTemplate(name, value)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, namedValue{name: name, value: t})
+	}
+	return res, nil
+}
+
+func getQueryRulesConfig(extra config.ExtraConfig) (map[string]interface{}, bool) {
+	e, ok := extra[Namespace].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	cfg, ok := e[queryRulesKey].(map[string]interface{})
+	return cfg, ok && len(cfg) > 0
+}
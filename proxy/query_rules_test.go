@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package proxy
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/logging"
+)
+
+func TestNewQueryRulesMiddleware(t *testing.T) {
+	remote := &config.Backend{
+		URLPattern:         "/search",
+		QueryStringsToPass: []string{"q", "page", "empty"},
+		ExtraConfig: config.ExtraConfig{
+			Namespace: map[string]interface{}{
+				queryRulesKey: map[string]interface{}{
+					"rename":       map[string]interface{}{"q": "search_term"},
+					"set":          map[string]interface{}{"tenant": "{{.Params.Tenant}}", "api_version": 2.0},
+					"defaults":     map[string]interface{}{"page": "1", "size": "20"},
+					"remove_empty": true,
+				},
+			},
+		},
+	}
+
+	var urls []string
+	backendFactory := func(_ *config.Backend) Proxy {
+		return func(_ context.Context, r *Request) (*Response, error) {
+			urls = append(urls, r.URL.String())
+			return &Response{Data: map[string]interface{}{}, IsComplete: true}, nil
+		}
+	}
+	remote.Host = []string{"http://example.com"}
+	p, err := NewDefaultFactory(backendFactory, logging.NoOp).New(&config.EndpointConfig{
+		Endpoint: "/search/{tenant}",
+		Backend:  []*config.Backend{remote},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	query := url.Values{"q": {"lura"}, "page": {"3"}, "empty": {""}, "ignored": {"true"}}
+	if _, err := p(context.Background(), &Request{Params: map[string]string{"Tenant": "acme"}, Query: query}); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := p(context.Background(), &Request{Params: map[string]string{}, Query: url.Values{}}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	expected := []string{
+		"http://example.com/search?api_version=2&page=3&search_term=lura&size=20&tenant=acme",
+		"http://example.com/search?api_version=2&page=1&size=20",
+	}
+	if len(urls) != len(expected) {
+		t.Errorf("unexpected number of calls: %v", urls)
+		return
+	}
+	for i := range urls {
+		if urls[i] != expected[i] {
+			t.Errorf("#%d: unexpected url: %s", i, urls[i])
+		}
+	}
+	if len(query) != 4 || query.Get("q") != "lura" {
+		t.Errorf("the original query strings have been modified: %v", query)
+	}
+}
+
+func TestNewQueryRulesMiddleware_noConfig(t *testing.T) {
+	expected := &Response{}
+	p := NewQueryRulesMiddleware(logging.NoOp, &config.Backend{})(dummyProxy(expected))
+	if resp, _ := p(context.Background(), &Request{}); resp != expected {
+		t.Errorf("unexpected response: %v", resp)
+	}
+}
@@ -4,7 +4,9 @@ package proxy
 
 import (
 	"bytes"
+	"fmt"
 	"io"
+	"net/http"
 	"net/url"
 	"sync"
 )
@@ -113,3 +115,47 @@ func CloneRequestParams(params map[string]string) map[string]string {
 	}
 	return m
 }
+
+// RequestBodyTooLargeError is the error returned when the body of the request received from the
+// client is bigger than the size allowed by the component reading it
+type RequestBodyTooLargeError struct {
+	Max int64
+}
+
+// Error implements the error interface
+func (r RequestBodyTooLargeError) Error() string {
+	return fmt.Sprintf("request body bigger than %d bytes", r.Max)
+}
+
+// StatusCode returns the status code to send to the client
+func (RequestBodyTooLargeError) StatusCode() int { return http.StatusRequestEntityTooLarge }
+
+// readRequestBody reads and closes the body of the request, failing with a
+// RequestBodyTooLargeError if it is bigger than max bytes. A max of zero or less means no limit.
+func readRequestBody(body io.ReadCloser, max int64) ([]byte, error) {
+	defer body.Close()
+	if max <= 0 {
+		return io.ReadAll(body)
+	}
+	b, err := io.ReadAll(io.LimitReader(body, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > max {
+		return nil, RequestBodyTooLargeError{Max: max}
+	}
+	return b, nil
+}
+
+// sizeValue returns the size defined by a numeric config value
+func sizeValue(v interface{}) int64 {
+	switch size := v.(type) {
+	case float64:
+		return int64(size)
+	case int:
+		return int64(size)
+	case int64:
+		return size
+	}
+	return 0
+}
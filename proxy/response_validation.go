@@ -0,0 +1,182 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/jsonschema"
+	"github.com/luraproject/lura/v2/logging"
+)
+
+const (
+	responseSchemaKey = "response_schema"
+
+	responseSchemaLogMode   = "log"
+	responseSchemaStripMode = "strip"
+	responseSchemaFailMode  = "fail"
+)
+
+// ResponseValidationError is the error returned by a backend when its response does not
+// match the expected schema and the validation is configured to fail the backend
+type ResponseValidationError struct {
+	Backend    string
+	Violations []jsonschema.Violation
+}
+
+// Error implements the error interface
+func (r ResponseValidationError) Error() string {
+	msgs := make([]string, len(r.Violations))
+	for i, v := range r.Violations {
+		msgs[i] = v.String()
+	}
+	return fmt.Sprintf("invalid response from %s: %s", r.Backend, strings.Join(msgs, "; "))
+}
+
+// NewResponseSchemaMiddleware returns a middleware with or without a proxy validating the
+// data of the responses returned by the backend against the JSON Schema defined in its extra
+// config (depending on the configuration). It is meant to wrap the proxy returned by the backend
+// factory, so it validates the data once the EntityFormatter has been applied.
+//
+// The supported modes are:
+//   - log: the violations are logged and the response is not modified (default)
+//   - strip: the violations are logged and the non conforming fields are removed
+//   - fail: the backend returns a ResponseValidationError instead of the response
+func NewResponseSchemaMiddleware(logger logging.Logger, remote *config.Backend) Middleware {
+	e, ok := remote.ExtraConfig[Namespace].(map[string]interface{})
+	if !ok {
+		return emptyMiddlewareFallback(logger)
+	}
+	cfg, ok := e[responseSchemaKey].(map[string]interface{})
+	if !ok {
+		return emptyMiddlewareFallback(logger)
+	}
+
+	logPrefix := fmt.Sprintf("[BACKEND: %s %s -> %s][ResponseSchema]", remote.ParentEndpointMethod, remote.ParentEndpoint, remote.URLPattern)
+	schema, err := jsonschema.Compile(cfg["schema"])
+	if err != nil {
+		logger.Error(logPrefix, "Compiling the schema:", err.Error())
+		return emptyMiddlewareFallback(logger)
+	}
+	mode, _ := cfg["mode"].(string)
+	switch mode {
+	case responseSchemaStripMode, responseSchemaFailMode:
+	default:
+		mode = responseSchemaLogMode
+	}
+	logger.Debug(logPrefix, "Validating the responses with mode", mode)
+
+	return func(next ...Proxy) Proxy {
+		if len(next) > 1 {
+			logger.Fatal("too many proxies for this %s %s -> %s proxy middleware: NewResponseSchemaMiddleware only accepts 1 proxy, got %d",
+				remote.ParentEndpointMethod, remote.ParentEndpoint, remote.URLPattern, len(next))
+			return nil
+		}
+		return func(ctx context.Context, request *Request) (*Response, error) {
+			resp, err := next[0](ctx, request)
+			if err != nil || resp == nil || resp.Io != nil {
+				return resp, err
+			}
+
+			violations := schema.Validate(resp.Data)
+			if len(violations) == 0 {
+				return resp, nil
+			}
+
+			vErr := ResponseValidationError{Backend: remote.URLPattern, Violations: violations}
+			if mode == responseSchemaFailMode {
+				return nil, vErr
+			}
+			logger.Warning(logPrefix, vErr.Error())
+			if mode == responseSchemaStripMode {
+				stripViolations(resp.Data, violations)
+			}
+			return resp, nil
+		}
+	}
+}
+
+// stripViolations removes the values pointed by the violations. Violations at the root of
+// the data can not be stripped. The deepest paths and the highest array indexes are removed
+// first, so the remaining paths stay valid.
+func stripViolations(data map[string]interface{}, violations []jsonschema.Violation) {
+	paths := make([][]string, 0, len(violations))
+	for _, v := range violations {
+		if v.Path == "" {
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(v.Path, "/"), "/")
+		for i, p := range parts {
+			parts[i] = strings.ReplaceAll(strings.ReplaceAll(p, "~1", "/"), "~0", "~")
+		}
+		paths = append(paths, parts)
+	}
+	sort.Slice(paths, func(i, j int) bool { return comparePaths(paths[i], paths[j]) > 0 })
+
+	for i, path := range paths {
+		if i > 0 && comparePaths(path, paths[i-1]) == 0 {
+			continue
+		}
+		var parent interface{} = data
+		var grandparent interface{}
+		for _, part := range path[:len(path)-1] {
+			grandparent = parent
+			parent = child(parent, part)
+		}
+		last := path[len(path)-1]
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			delete(p, last)
+		case []interface{}:
+			idx, err := strconv.Atoi(last)
+			if err != nil || idx >= len(p) {
+				continue
+			}
+			p = append(p[:idx], p[idx+1:]...)
+			setChild(grandparent, path[len(path)-2], p)
+		}
+	}
+}
+
+func child(v interface{}, key string) interface{} {
+	switch c := v.(type) {
+	case map[string]interface{}:
+		return c[key]
+	case []interface{}:
+		if idx, err := strconv.Atoi(key); err == nil && idx < len(c) {
+			return c[idx]
+		}
+	}
+	return nil
+}
+
+func setChild(v interface{}, key string, value interface{}) {
+	switch c := v.(type) {
+	case map[string]interface{}:
+		c[key] = value
+	case []interface{}:
+		if idx, err := strconv.Atoi(key); err == nil && idx < len(c) {
+			c[idx] = value
+		}
+	}
+}
+
+func comparePaths(a, b []string) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == b[i] {
+			continue
+		}
+		ai, errA := strconv.Atoi(a[i])
+		bi, errB := strconv.Atoi(b[i])
+		if errA == nil && errB == nil {
+			return ai - bi
+		}
+		return strings.Compare(a[i], b[i])
+	}
+	return len(a) - len(b)
+}
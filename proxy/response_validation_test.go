@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/logging"
+)
+
+func responseSchemaBackend(mode string) *config.Backend {
+	return &config.Backend{
+		URLPattern: "/users",
+		ExtraConfig: config.ExtraConfig{
+			Namespace: map[string]interface{}{
+				responseSchemaKey: map[string]interface{}{
+					"mode": mode,
+					"schema": map[string]interface{}{
+						"type":     "object",
+						"required": []interface{}{"id"},
+						"properties": map[string]interface{}{
+							"id":   map[string]interface{}{"type": "number"},
+							"name": map[string]interface{}{"type": "string"},
+							"tags": map[string]interface{}{
+								"type":  "array",
+								"items": map[string]interface{}{"type": "string"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func invalidResponse() *Response {
+	return &Response{
+		Data: map[string]interface{}{
+			"id":   1,
+			"name": 42,
+			"tags": []interface{}{"a", 1, "b", 2, "c", 3, "d", 4, "e", 5, "f", 6},
+		},
+		IsComplete: true,
+	}
+}
+
+func TestNewResponseSchemaMiddleware_log(t *testing.T) {
+	buff := bytes.NewBuffer(make([]byte, 1024))
+	logger, _ := logging.NewLogger("WARNING", buff, "pref")
+
+	resp, err := NewResponseSchemaMiddleware(logger, responseSchemaBackend("log"))(dummyProxy(invalidResponse()))(context.Background(), &Request{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !reflect.DeepEqual(resp, invalidResponse()) {
+		t.Errorf("unexpected response: %v", resp)
+	}
+	if !strings.Contains(buff.String(), "/name: expected string, got number") {
+		t.Errorf("unexpected log: %s", buff.String())
+	}
+}
+
+func TestNewResponseSchemaMiddleware_strip(t *testing.T) {
+	resp, err := NewResponseSchemaMiddleware(logging.NoOp, responseSchemaBackend("strip"))(dummyProxy(invalidResponse()))(context.Background(), &Request{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expected := map[string]interface{}{
+		"id":   1,
+		"tags": []interface{}{"a", "b", "c", "d", "e", "f"},
+	}
+	if !reflect.DeepEqual(resp.Data, expected) {
+		t.Errorf("unexpected response: %v", resp.Data)
+	}
+}
+
+func TestNewResponseSchemaMiddleware_fail(t *testing.T) {
+	_, err := NewResponseSchemaMiddleware(logging.NoOp, responseSchemaBackend("fail"))(dummyProxy(invalidResponse()))(context.Background(), &Request{})
+	vErr, ok := err.(ResponseValidationError)
+	if !ok {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if len(vErr.Violations) != 7 {
+		t.Errorf("unexpected violations: %v", vErr.Violations)
+	}
+
+	valid := &Response{Data: map[string]interface{}{"id": 1}, IsComplete: true}
+	resp, err := NewResponseSchemaMiddleware(logging.NoOp, responseSchemaBackend("fail"))(dummyProxy(valid))(context.Background(), &Request{})
+	if err != nil || resp != valid {
+		t.Errorf("unexpected result: %v %v", resp, err)
+	}
+}
+
+func TestNewResponseSchemaMiddleware_merge(t *testing.T) {
+	backends := []*config.Backend{
+		responseSchemaBackend("fail"),
+		{URLPattern: "/other"},
+	}
+	for _, b := range backends {
+		b.Host = []string{"http://example.com"}
+	}
+	factory := NewDefaultFactory(func(b *config.Backend) Proxy {
+		if b.URLPattern == "/users" {
+			return dummyProxy(invalidResponse())
+		}
+		return dummyProxy(&Response{Data: map[string]interface{}{"other": true}, IsComplete: true})
+	}, logging.NoOp)
+
+	p, err := factory.New(&config.EndpointConfig{Endpoint: "/merge", Timeout: time.Second, Backend: backends})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	resp, err := p(context.Background(), &Request{Params: map[string]string{}})
+	if err == nil {
+		t.Error("expecting an error")
+	}
+	if resp == nil || resp.IsComplete || resp.Data["other"] != true {
+		t.Errorf("unexpected response: %v", resp)
+	}
+}
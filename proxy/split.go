@@ -0,0 +1,263 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"net/textproto"
+
+	"github.com/valyala/fastrand"
+
+	"github.com/luraproject/lura/v2/config"
+)
+
+const (
+	splitKey               = "traffic_split"
+	splitVariantKey        = "variant"
+	splitVariantsKey       = "variants"
+	splitHashHeaderKey     = "hash_header"
+	splitHashCookieKey     = "hash_cookie"
+	splitResponseHeaderKey = "response_header"
+
+	// DefaultVariantHeaderName is the name of the response header exposing the
+	// selected variant when no other name is configured
+	DefaultVariantHeaderName = "X-Krakend-Variant"
+)
+
+var (
+	// ErrSplitVariantsMismatch is the error returned by the split proxies built without one
+	// proxy per variant
+	ErrSplitVariantsMismatch = errors.New("the number of proxies does not match the number of split variants")
+	// ErrUnknownSplitVariant is the error returned when a backend is tagged with a variant not
+	// defined in the traffic split config of its endpoint
+	ErrUnknownSplitVariant = errors.New("unknown split variant")
+	// ErrSplitHashNotForwarded is the error returned when the header or the cookie used as the
+	// stickiness key of a traffic split is not in the input_headers of its endpoint
+	ErrSplitHashNotForwarded = errors.New("the split hash header is not in the endpoint input_headers")
+)
+
+type splitFactory struct {
+	f Factory
+}
+
+// NewSplitFactory creates a Factory that splits the traffic of an endpoint between
+// several variants of its backends. The variants and their weights are defined at
+// the endpoint extra config, and every backend tagged with a "variant" in its extra
+// config is only used by the requests routed to that variant. Untagged backends are
+// shared by all the variants.
+func NewSplitFactory(f Factory) Factory {
+	return splitFactory{f}
+}
+
+// New implements the Factory interface
+func (s splitFactory) New(cfg *config.EndpointConfig) (Proxy, error) {
+	splitCfg, ok, err := getSplitConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return s.f.New(cfg)
+	}
+	if len(cfg.Backend) == 0 {
+		return nil, ErrNoBackends
+	}
+
+	known := make(map[string]struct{}, len(splitCfg.Variants))
+	for _, v := range splitCfg.Variants {
+		known[v.Name] = struct{}{}
+	}
+
+	var shared []*config.Backend
+	tagged := map[string][]*config.Backend{}
+	for _, b := range cfg.Backend {
+		if name, ok := backendVariant(b); ok {
+			if _, ok := known[name]; !ok {
+				return nil, fmt.Errorf("%w %s in the backend %s", ErrUnknownSplitVariant, name, b.URLPattern)
+			}
+			tagged[name] = append(tagged[name], b)
+			continue
+		}
+		shared = append(shared, b)
+	}
+
+	proxies := make([]Proxy, len(splitCfg.Variants))
+	for i, v := range splitCfg.Variants {
+		cfgCopy := *cfg
+		cfgCopy.Backend = make([]*config.Backend, 0, len(shared)+len(tagged[v.Name]))
+		cfgCopy.Backend = append(cfgCopy.Backend, shared...)
+		cfgCopy.Backend = append(cfgCopy.Backend, tagged[v.Name]...)
+
+		p, err := s.f.New(&cfgCopy)
+		if err != nil {
+			return nil, fmt.Errorf("building the variant %s: %w", v.Name, err)
+		}
+		proxies[i] = p
+	}
+
+	return NewSplitProxy(splitCfg, proxies...), nil
+}
+
+// SplitVariant defines the name and the weight of a traffic split variant
+type SplitVariant struct {
+	Name   string
+	Weight uint32
+}
+
+// SplitConfig defines how the traffic is distributed between the variants
+type SplitConfig struct {
+	Variants []SplitVariant
+	// HashHeader is the name of the request header to use as a stickiness key. The proxies
+	// only see the headers listed in the input_headers of the endpoint, so it must be there.
+	HashHeader string
+	// HashCookie is the name of the request cookie to use as a stickiness key. The Cookie
+	// header must be listed in the input_headers of the endpoint.
+	HashCookie string
+	// ResponseHeader is the name of the header exposing the selected variant
+	ResponseHeader string
+}
+
+// NewSplitProxy returns a Proxy that dispatches every request to one of the received
+// proxies, where next[i] handles the variant cfg.Variants[i]. The variant is selected
+// by weight, either randomly or deterministically using the hash of the configured
+// header or cookie, so the same key always lands in the same variant.
+//
+// If the number of proxies does not match the number of variants, the returned proxy
+// fails with ErrSplitVariantsMismatch.
+func NewSplitProxy(cfg SplitConfig, next ...Proxy) Proxy {
+	if len(next) == 0 || len(next) != len(cfg.Variants) {
+		return func(_ context.Context, _ *Request) (*Response, error) {
+			return nil, ErrSplitVariantsMismatch
+		}
+	}
+
+	var total uint32
+	for _, v := range cfg.Variants {
+		total += v.Weight
+	}
+	if total == 0 {
+		return next[0]
+	}
+	header := cfg.ResponseHeader
+	if header == "" {
+		header = DefaultVariantHeaderName
+	}
+
+	return func(ctx context.Context, request *Request) (*Response, error) {
+		var point uint32
+		if key, ok := cfg.hashKey(request); ok {
+			h := fnv.New32a()
+			h.Write([]byte(key))
+			point = h.Sum32() % total
+		} else {
+			point = fastrand.Uint32n(total)
+		}
+
+		i := 0
+		for ; i < len(cfg.Variants)-1; i++ {
+			if point < cfg.Variants[i].Weight {
+				break
+			}
+			point -= cfg.Variants[i].Weight
+		}
+
+		resp, err := next[i](ctx, request)
+		if resp != nil {
+			if resp.Metadata.Headers == nil {
+				resp.Metadata.Headers = map[string][]string{}
+			}
+			resp.Metadata.Headers[header] = []string{cfg.Variants[i].Name}
+		}
+		return resp, err
+	}
+}
+
+func (s SplitConfig) hashKey(r *Request) (string, bool) {
+	if s.HashHeader != "" {
+		if vs, ok := r.Headers[s.HashHeader]; ok && len(vs) > 0 && vs[0] != "" {
+			return vs[0], true
+		}
+	}
+	if s.HashCookie != "" {
+		req := http.Request{Header: http.Header{"Cookie": r.Headers["Cookie"]}}
+		if c, err := req.Cookie(s.HashCookie); err == nil && c.Value != "" {
+			return c.Value, true
+		}
+	}
+	return "", false
+}
+
+// getSplitConfig returns the traffic split config of the endpoint and an error if the header or
+// the cookie used as the stickiness key is not forwarded to the proxies
+func getSplitConfig(endpoint *config.EndpointConfig) (SplitConfig, bool, error) {
+	cfg := SplitConfig{}
+	e, ok := endpoint.ExtraConfig[Namespace].(map[string]interface{})
+	if !ok {
+		return cfg, false, nil
+	}
+	tmp, ok := e[splitKey].(map[string]interface{})
+	if !ok {
+		return cfg, false, nil
+	}
+	variants, ok := tmp[splitVariantsKey].([]interface{})
+	if !ok {
+		return cfg, false, nil
+	}
+
+	var total uint32
+	for _, v := range variants {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, ok := m["name"].(string)
+		if !ok || name == "" {
+			continue
+		}
+		weight, ok := m["weight"].(float64)
+		if !ok || weight < 0 {
+			continue
+		}
+		cfg.Variants = append(cfg.Variants, SplitVariant{Name: name, Weight: uint32(weight)})
+		total += uint32(weight)
+	}
+	if len(cfg.Variants) == 0 || total == 0 {
+		return cfg, false, nil
+	}
+
+	if h, ok := tmp[splitHashHeaderKey].(string); ok {
+		cfg.HashHeader = textproto.CanonicalMIMEHeaderKey(h)
+	}
+	cfg.HashCookie, _ = tmp[splitHashCookieKey].(string)
+	cfg.ResponseHeader, _ = tmp[splitResponseHeaderKey].(string)
+
+	if cfg.HashHeader != "" && !forwardsHeader(endpoint, cfg.HashHeader) {
+		return cfg, true, fmt.Errorf("%w: %s", ErrSplitHashNotForwarded, cfg.HashHeader)
+	}
+	if cfg.HashCookie != "" && !forwardsHeader(endpoint, "Cookie") {
+		return cfg, true, fmt.Errorf("%w: Cookie (for the cookie %s)", ErrSplitHashNotForwarded, cfg.HashCookie)
+	}
+	return cfg, true, nil
+}
+
+// forwardsHeader returns true if the header is in the input_headers of the endpoint
+func forwardsHeader(endpoint *config.EndpointConfig, name string) bool {
+	for _, h := range endpoint.HeadersToPass {
+		if h == "*" || textproto.CanonicalMIMEHeaderKey(h) == name {
+			return true
+		}
+	}
+	return false
+}
+
+func backendVariant(b *config.Backend) (string, bool) {
+	e, ok := b.ExtraConfig[Namespace].(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	name, ok := e[splitVariantKey].(string)
+	return name, ok && name != ""
+}
@@ -0,0 +1,209 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package proxy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/logging"
+)
+
+func TestNewSplitFactory(t *testing.T) {
+	factory := NewDefaultFactory(func(b *config.Backend) Proxy {
+		return func(_ context.Context, _ *Request) (*Response, error) {
+			return &Response{Data: map[string]interface{}{"url": b.URLPattern}, IsComplete: true}, nil
+		}
+	}, logging.NoOp)
+
+	endpoint := &config.EndpointConfig{
+		Endpoint: "/split",
+		ExtraConfig: config.ExtraConfig{
+			Namespace: map[string]interface{}{
+				splitKey: map[string]interface{}{
+					"variants": []interface{}{
+						map[string]interface{}{"name": "v1", "weight": 0.0},
+						map[string]interface{}{"name": "v2", "weight": 100.0},
+					},
+					"response_header": "X-Variant",
+				},
+			},
+		},
+		Backend: []*config.Backend{
+			{
+				Host:        []string{"http://v1.example.com"},
+				URLPattern:  "/v1",
+				ExtraConfig: config.ExtraConfig{Namespace: map[string]interface{}{"variant": "v1"}},
+			},
+			{
+				Host:        []string{"http://v2.example.com"},
+				URLPattern:  "/v2",
+				ExtraConfig: config.ExtraConfig{Namespace: map[string]interface{}{"variant": "v2"}},
+			},
+		},
+	}
+
+	p, err := NewSplitFactory(factory).New(endpoint)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for i := 0; i < 10; i++ {
+		resp, err := p(context.Background(), &Request{})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if v := resp.Data["url"]; v != "/v2" {
+			t.Errorf("unexpected backend: %v", v)
+		}
+		if h := resp.Metadata.Headers["X-Variant"]; len(h) != 1 || h[0] != "v2" {
+			t.Errorf("unexpected variant header: %v", h)
+		}
+	}
+}
+
+func TestNewSplitFactory_noConfig(t *testing.T) {
+	var counter uint64
+	factory := NewDefaultFactory(func(_ *config.Backend) Proxy { return newAssertionProxy(&counter) }, logging.NoOp)
+
+	if _, err := NewSplitFactory(factory).New(&config.EndpointConfig{}); err != ErrNoBackends {
+		t.Errorf("expecting ErrNoBackends. Got: %v", err)
+	}
+
+	p, err := NewSplitFactory(factory).New(&config.EndpointConfig{Backend: []*config.Backend{{Host: []string{"http://example.com"}}}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	p(context.Background(), &Request{})
+	if counter != 1 {
+		t.Errorf("unexpected number of calls: %d", counter)
+	}
+}
+
+func TestNewSplitFactory_unknownVariant(t *testing.T) {
+	var counter uint64
+	factory := NewDefaultFactory(func(_ *config.Backend) Proxy { return newAssertionProxy(&counter) }, logging.NoOp)
+
+	endpoint := &config.EndpointConfig{
+		Endpoint: "/split",
+		ExtraConfig: config.ExtraConfig{
+			Namespace: map[string]interface{}{
+				splitKey: map[string]interface{}{
+					"variants": []interface{}{
+						map[string]interface{}{"name": "v1", "weight": 100.0},
+					},
+				},
+			},
+		},
+		Backend: []*config.Backend{
+			{
+				Host:        []string{"http://v2.example.com"},
+				URLPattern:  "/v2",
+				ExtraConfig: config.ExtraConfig{Namespace: map[string]interface{}{"variant": "v2"}},
+			},
+		},
+	}
+
+	if _, err := NewSplitFactory(factory).New(endpoint); !errors.Is(err, ErrUnknownSplitVariant) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestNewSplitProxy_variantsMismatch(t *testing.T) {
+	cfg := SplitConfig{Variants: []SplitVariant{{Name: "a", Weight: 1}, {Name: "b", Weight: 1}}}
+	variant := func(_ context.Context, _ *Request) (*Response, error) {
+		t.Error("the variant should not be called")
+		return &Response{}, nil
+	}
+
+	for _, next := range [][]Proxy{nil, {variant}, {variant, variant, variant}} {
+		if _, err := NewSplitProxy(cfg, next...)(context.Background(), &Request{}); err != ErrSplitVariantsMismatch {
+			t.Errorf("unexpected error with %d proxies: %v", len(next), err)
+		}
+	}
+}
+
+func TestNewSplitProxy_sticky(t *testing.T) {
+	cfg := SplitConfig{
+		Variants:   []SplitVariant{{"a", 50}, {"b", 50}},
+		HashHeader: "X-User",
+		HashCookie: "session",
+	}
+	variant := func(name string) Proxy {
+		return func(_ context.Context, _ *Request) (*Response, error) {
+			return &Response{Data: map[string]interface{}{"variant": name}}, nil
+		}
+	}
+	p := NewSplitProxy(cfg, variant("a"), variant("b"))
+
+	for _, headers := range []map[string][]string{
+		{"X-User": {"user-1"}},
+		{"X-User": {"user-2"}},
+		{"Cookie": {"foo=bar; session=abc"}},
+		{"Cookie": {"session=xyz"}},
+	} {
+		first, _ := p(context.Background(), &Request{Headers: headers})
+		name := first.Metadata.Headers[DefaultVariantHeaderName]
+		if len(name) != 1 || name[0] != first.Data["variant"] {
+			t.Errorf("unexpected variant header: %v", name)
+			continue
+		}
+		for i := 0; i < 20; i++ {
+			resp, _ := p(context.Background(), &Request{Headers: headers})
+			if resp.Data["variant"] != first.Data["variant"] {
+				t.Errorf("the request %v was not sticky: %v vs %v", headers, resp.Data["variant"], first.Data["variant"])
+				break
+			}
+		}
+	}
+}
+
+func TestGetSplitConfig_invalid(t *testing.T) {
+	for i, extra := range []config.ExtraConfig{
+		{},
+		{Namespace: map[string]interface{}{}},
+		{Namespace: map[string]interface{}{splitKey: map[string]interface{}{}}},
+		{Namespace: map[string]interface{}{splitKey: map[string]interface{}{"variants": []interface{}{}}}},
+		{Namespace: map[string]interface{}{splitKey: map[string]interface{}{"variants": []interface{}{
+			map[string]interface{}{"name": "a", "weight": 0.0},
+			map[string]interface{}{"weight": 10.0},
+		}}}},
+	} {
+		if _, ok, err := getSplitConfig(&config.EndpointConfig{ExtraConfig: extra}); ok || err != nil {
+			t.Errorf("#%d: unexpected config: %v", i, err)
+		}
+	}
+}
+
+func TestGetSplitConfig_hashNotForwarded(t *testing.T) {
+	variants := []interface{}{map[string]interface{}{"name": "a", "weight": 1.0}}
+	for i, tc := range []struct {
+		split   map[string]interface{}
+		headers []string
+		ok      bool
+	}{
+		{split: map[string]interface{}{"hash_header": "x-user"}, headers: []string{"X-User"}, ok: true},
+		{split: map[string]interface{}{"hash_header": "X-User"}, headers: []string{"*"}, ok: true},
+		{split: map[string]interface{}{"hash_header": "X-User"}, headers: []string{"Authorization"}},
+		{split: map[string]interface{}{"hash_cookie": "session"}, headers: []string{"cookie"}, ok: true},
+		{split: map[string]interface{}{"hash_cookie": "session"}},
+	} {
+		tc.split["variants"] = variants
+		endpoint := &config.EndpointConfig{
+			HeadersToPass: tc.headers,
+			ExtraConfig:   config.ExtraConfig{Namespace: map[string]interface{}{splitKey: tc.split}},
+		}
+		_, _, err := getSplitConfig(endpoint)
+		if tc.ok && err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+		}
+		if !tc.ok && !errors.Is(err, ErrSplitHashNotForwarded) {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+		}
+	}
+}
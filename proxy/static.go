@@ -11,13 +11,21 @@ import (
 	"github.com/luraproject/lura/v2/logging"
 )
 
-// NewStaticMiddleware creates proxy middleware for adding static values to the processed responses
+// NewStaticMiddleware creates proxy middleware for adding static values to the processed responses.
+// String values in the static data can be templates, rendered with the RequestTemplateData
+// of every request (e.g. "{{.Params.Id}}", "{{.Query.q}}" or "{{.Headers.Authorization}}").
 func NewStaticMiddleware(logger logging.Logger, endpointConfig *config.EndpointConfig) Middleware {
 	cfg, ok := getStaticMiddlewareCfg(endpointConfig.ExtraConfig)
 	if !ok {
 		return emptyMiddlewareFallback(logger)
 	}
 
+	tmpl, err := newValueTemplate("static", cfg.Data)
+	if err != nil {
+		logger.Error(fmt.Sprintf("[ENDPOINT: %s][Static] Parsing the static data templates: %s", endpointConfig.Endpoint, err.Error()))
+		return emptyMiddlewareFallback(logger)
+	}
+
 	b, _ := json.Marshal(cfg.Data)
 
 	logger.Debug(
@@ -34,25 +42,46 @@ func NewStaticMiddleware(logger logging.Logger, endpointConfig *config.EndpointC
 			logger.Fatal("too many proxies for this proxy middleware: NewStaticMiddleware only accepts 1 proxy, got %d", len(next))
 			return nil
 		}
+		if tmpl.IsTemplated() {
+			return func(ctx context.Context, request *Request) (*Response, error) {
+				// the request must be captured before the pipe modifies it
+				data := NewRequestTemplateData(request)
+				result, err := next[0](ctx, request)
+				if !cfg.Match(result, err) {
+					return result, err
+				}
+
+				v, tErr := tmpl.Execute(data)
+				if tErr != nil {
+					logger.Error(fmt.Sprintf("[ENDPOINT: %s][Static] Rendering the static data: %s", endpointConfig.Endpoint, tErr.Error()))
+					return result, err
+				}
+				return mergeStaticData(result, v.(map[string]interface{})), err
+			}
+		}
+
 		return func(ctx context.Context, request *Request) (*Response, error) {
 			result, err := next[0](ctx, request)
 			if !cfg.Match(result, err) {
 				return result, err
 			}
+			return mergeStaticData(result, cfg.Data), err
+		}
+	}
+}
 
-			if result == nil {
-				result = &Response{Data: map[string]interface{}{}}
-			} else if result.Data == nil {
-				result.Data = map[string]interface{}{}
-			}
-
-			for k, v := range cfg.Data {
-				result.Data[k] = v
-			}
+func mergeStaticData(result *Response, data map[string]interface{}) *Response {
+	if result == nil {
+		result = &Response{Data: map[string]interface{}{}}
+	} else if result.Data == nil {
+		result.Data = map[string]interface{}{}
+	}
 
-			return result, err
-		}
+	for k, v := range data {
+		result.Data[k] = v
 	}
+
+	return result
 }
 
 const (
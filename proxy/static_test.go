@@ -386,3 +386,42 @@ func Test_getStaticMiddlewareCfg_strategy(t *testing.T) {
 		t.Error("wrong parsing default strategy")
 	}
 }
+
+func TestNewStaticMiddleware_templated(t *testing.T) {
+	endpoint := config.EndpointConfig{
+		ExtraConfig: config.ExtraConfig{
+			Namespace: map[string]interface{}{
+				staticKey: map[string]interface{}{
+					"data": map[string]interface{}{
+						"id":     "{{.Params.Id}}",
+						"search": map[string]interface{}{"q": "{{.Query.q}}", "lang": "{{index .Headers \"Accept-Language\"}}", "missing": "{{.Query.missing}}"},
+						"tags":   []interface{}{"static", "{{.Params.Id}}-tag"},
+						"fixed":  42,
+					},
+				},
+			},
+		},
+	}
+	p := NewStaticMiddleware(logging.NoOp, &endpoint)(dummyProxy(&Response{Data: map[string]interface{}{"supu": 42}, IsComplete: true}))
+
+	out, err := p(context.Background(), &Request{
+		Params:  map[string]string{"Id": "123"},
+		Query:   map[string][]string{"q": {"foo", "bar"}},
+		Headers: map[string][]string{"Accept-Language": {"es"}},
+	})
+	if err != nil {
+		t.Errorf("The middleware propagated an unexpected error: %s", err.Error())
+		return
+	}
+
+	expected := map[string]interface{}{
+		"supu":   42,
+		"id":     "123",
+		"search": map[string]interface{}{"q": "foo", "lang": "es", "missing": ""},
+		"tags":   []interface{}{"static", "123-tag"},
+		"fixed":  42,
+	}
+	if !reflect.DeepEqual(out.Data, expected) {
+		t.Errorf("unexpected response data: %v", out.Data)
+	}
+}
@@ -0,0 +1,209 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// templateFuncs are the helper functions available to all the templates
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// RequestTemplateData is the data available to the templates defined in the
+// proxy extra configurations. Query and Headers only expose the first value
+// of each key. ClientIP is the first value of the X-Forwarded-For header set by
+// the router.
+type RequestTemplateData struct {
+	Params   map[string]string
+	Query    map[string]string
+	Headers  map[string]string
+	ClientIP string
+}
+
+// NewRequestTemplateData extracts the data available to the templates from the
+// received request
+func NewRequestTemplateData(r *Request) RequestTemplateData {
+	data := RequestTemplateData{
+		Params:  make(map[string]string, len(r.Params)),
+		Query:   make(map[string]string, len(r.Query)),
+		Headers: make(map[string]string, len(r.Headers)),
+	}
+	for k, v := range r.Params {
+		data.Params[k] = v
+	}
+	for k, vs := range r.Query {
+		if len(vs) > 0 {
+			data.Query[k] = vs[0]
+		}
+	}
+	for k, vs := range r.Headers {
+		if len(vs) > 0 {
+			data.Headers[k] = vs[0]
+		}
+	}
+	data.ClientIP = data.Headers["X-Forwarded-For"]
+	return data
+}
+
+// valueTemplate is a precompiled representation of a generic value (as decoded
+// from the config) where every string containing a template action is replaced
+// by its parsed template. The object and array items made of a single json action
+// (e.g. "{{ json .Body.count }}") are replaced by the decoded output of the template,
+// so they keep their JSON type instead of being rendered as strings.
+type valueTemplate struct {
+	root      interface{}
+	templated bool
+}
+
+// jsonTemplate is a template made of a single json action
+type jsonTemplate struct {
+	*template.Template
+}
+
+func newValueTemplate(name string, v interface{}) (valueTemplate, error) {
+	t := valueTemplate{}
+	root, err := t.compile(name, v)
+	if err != nil {
+		return t, err
+	}
+	t.root = root
+	return t, nil
+}
+
+// IsTemplated returns true if the value contains at least one template
+func (t valueTemplate) IsTemplated() bool { return t.templated }
+
+// Execute returns a copy of the original value with all the templates rendered
+// with the received data
+func (t valueTemplate) Execute(data interface{}) (interface{}, error) {
+	return execute(t.root, data)
+}
+
+func (t *valueTemplate) compile(name string, v interface{}) (interface{}, error) {
+	switch c := v.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(c))
+		for k, v := range c {
+			compiled, err := t.compileItem(name+"."+k, v)
+			if err != nil {
+				return nil, err
+			}
+			res[k] = compiled
+		}
+		return res, nil
+	case []interface{}:
+		res := make([]interface{}, len(c))
+		for i, v := range c {
+			compiled, err := t.compileItem(name, v)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = compiled
+		}
+		return res, nil
+	case string:
+		if !strings.Contains(c, "{{") {
+			return c, nil
+		}
+		tmpl, err := template.New(name).Funcs(templateFuncs).Option("missingkey=zero").Parse(c)
+		if err != nil {
+			return nil, err
+		}
+		t.templated = true
+		return tmpl, nil
+	default:
+		return v, nil
+	}
+}
+
+// compileItem compiles the items of the objects and arrays, flagging the templates made of
+// a single json action
+func (t *valueTemplate) compileItem(name string, v interface{}) (interface{}, error) {
+	compiled, err := t.compile(name, v)
+	if err != nil {
+		return nil, err
+	}
+	if tmpl, ok := compiled.(*template.Template); ok && isJSONAction(tmpl) {
+		return jsonTemplate{tmpl}, nil
+	}
+	return compiled, nil
+}
+
+// isJSONAction returns true if the template just contains an action piping its value to
+// the json function
+func isJSONAction(tmpl *template.Template) bool {
+	var action *parse.ActionNode
+	for _, n := range tmpl.Tree.Root.Nodes {
+		switch node := n.(type) {
+		case *parse.TextNode:
+			if len(bytes.TrimSpace(node.Text)) > 0 {
+				return false
+			}
+		case *parse.ActionNode:
+			if action != nil {
+				return false
+			}
+			action = node
+		default:
+			return false
+		}
+	}
+	if action == nil || len(action.Pipe.Decl) > 0 || len(action.Pipe.Cmds) == 0 {
+		return false
+	}
+	cmd := action.Pipe.Cmds[len(action.Pipe.Cmds)-1]
+	ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+	return ok && ident.Ident == "json"
+}
+
+func execute(v interface{}, data interface{}) (interface{}, error) {
+	switch c := v.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(c))
+		for k, v := range c {
+			rendered, err := execute(v, data)
+			if err != nil {
+				return nil, err
+			}
+			res[k] = rendered
+		}
+		return res, nil
+	case []interface{}:
+		res := make([]interface{}, len(c))
+		for i, v := range c {
+			rendered, err := execute(v, data)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = rendered
+		}
+		return res, nil
+	case *template.Template:
+		buf := new(bytes.Buffer)
+		if err := c.Execute(buf, data); err != nil {
+			return nil, err
+		}
+		return buf.String(), nil
+	case jsonTemplate:
+		buf := new(bytes.Buffer)
+		if err := c.Execute(buf, data); err != nil {
+			return nil, err
+		}
+		var res interface{}
+		if err := json.Unmarshal(buf.Bytes(), &res); err != nil {
+			return nil, err
+		}
+		return res, nil
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/jsonschema"
+	"github.com/luraproject/lura/v2/logging"
+)
+
+const (
+	validationKey = "validation"
+	// defaultValidationMaxBodySize is the size limit of the validated bodies when the
+	// max_body_size option is not set
+	defaultValidationMaxBodySize = 10 << 20
+)
+
+// RequestValidationError is the error returned when the request received from the client
+// does not match the schemas defined for the endpoint. Its message is the JSON encoded
+// list of violations, so the routers return it to the client as is with a 400 status code.
+type RequestValidationError struct {
+	Violations []jsonschema.Violation `json:"errors"`
+}
+
+// Error implements the error interface
+func (r RequestValidationError) Error() string {
+	b, _ := json.Marshal(r)
+	return string(b)
+}
+
+// StatusCode returns the status code to send to the client
+func (RequestValidationError) StatusCode() int { return http.StatusBadRequest }
+
+// Encoding returns the content type of the error message
+func (RequestValidationError) Encoding() string { return "application/json" }
+
+// NewRequestValidationMiddleware returns a middleware with or without a proxy validating the
+// body, the query strings and the params of the received requests against the JSON Schemas
+// defined in the endpoint extra config (depending on the configuration). Invalid requests
+// are rejected with a RequestValidationError before calling the next proxy.
+//
+// The body is buffered so the next proxies can consume it as usual. Its size is bounded by the
+// max_body_size option (in bytes, 10MB by default), and the bigger bodies are rejected with a
+// RequestBodyTooLargeError. The query strings are validated as an object where single values
+// are strings and repeated values are arrays.
+func NewRequestValidationMiddleware(logger logging.Logger, endpointConfig *config.EndpointConfig) Middleware {
+	cfg, ok := getRequestValidationConfig(endpointConfig.ExtraConfig)
+	if !ok {
+		return emptyMiddlewareFallback(logger)
+	}
+
+	logPrefix := fmt.Sprintf("[ENDPOINT: %s][Validation]", endpointConfig.Endpoint)
+	schemas := map[string]*jsonschema.Schema{}
+	for k, v := range cfg.Schemas {
+		s, err := jsonschema.Compile(v)
+		if err != nil {
+			logger.Error(logPrefix, "Compiling the", k, "schema:", err.Error())
+			return emptyMiddlewareFallback(logger)
+		}
+		schemas[k] = s
+	}
+	logger.Debug(logPrefix, "Validating the requests with", len(schemas), "schemas")
+
+	return func(next ...Proxy) Proxy {
+		if len(next) > 1 {
+			logger.Fatal("too many proxies for this proxy middleware: NewRequestValidationMiddleware only accepts 1 proxy, got %d", len(next))
+			return nil
+		}
+		return func(ctx context.Context, request *Request) (*Response, error) {
+			var violations []jsonschema.Violation
+
+			if s, ok := schemas["params"]; ok {
+				params := make(map[string]interface{}, len(request.Params))
+				for k, v := range request.Params {
+					params[k] = v
+				}
+				violations = appendViolations(violations, "/params", s.Validate(params))
+			}
+
+			if s, ok := schemas["query"]; ok {
+				query := make(map[string]interface{}, len(request.Query))
+				for k, vs := range request.Query {
+					switch len(vs) {
+					case 0:
+					case 1:
+						query[k] = vs[0]
+					default:
+						values := make([]interface{}, len(vs))
+						for i, v := range vs {
+							values[i] = v
+						}
+						query[k] = values
+					}
+				}
+				violations = appendViolations(violations, "/query", s.Validate(query))
+			}
+
+			if s, ok := schemas["body"]; ok {
+				var b []byte
+				if request.Body != nil {
+					var err error
+					b, err = readRequestBody(request.Body, cfg.MaxBodySize)
+					if err != nil {
+						return nil, err
+					}
+					request.Body = io.NopCloser(bytes.NewReader(b))
+				}
+
+				var body interface{}
+				if len(b) > 0 {
+					if err := json.Unmarshal(b, &body); err != nil {
+						violations = append(violations, jsonschema.Violation{Path: "/body", Message: "invalid JSON: " + err.Error()})
+					} else {
+						violations = appendViolations(violations, "/body", s.Validate(body))
+					}
+				} else {
+					violations = appendViolations(violations, "/body", s.Validate(nil))
+				}
+			}
+
+			if len(violations) > 0 {
+				return nil, RequestValidationError{Violations: violations}
+			}
+			return next[0](ctx, request)
+		}
+	}
+}
+
+func appendViolations(dst []jsonschema.Violation, prefix string, violations []jsonschema.Violation) []jsonschema.Violation {
+	for _, v := range violations {
+		v.Path = prefix + v.Path
+		dst = append(dst, v)
+	}
+	return dst
+}
+
+type requestValidationConfig struct {
+	Schemas     map[string]interface{}
+	MaxBodySize int64
+}
+
+func getRequestValidationConfig(extra config.ExtraConfig) (requestValidationConfig, bool) {
+	cfg := requestValidationConfig{Schemas: map[string]interface{}{}}
+	e, ok := extra[Namespace].(map[string]interface{})
+	if !ok {
+		return cfg, false
+	}
+	tmp, ok := e[validationKey].(map[string]interface{})
+	if !ok {
+		return cfg, false
+	}
+	for _, k := range []string{"body", "query", "params"} {
+		if v, ok := tmp[k]; ok {
+			cfg.Schemas[k] = v
+		}
+	}
+	if cfg.MaxBodySize = sizeValue(tmp["max_body_size"]); cfg.MaxBodySize <= 0 {
+		cfg.MaxBodySize = defaultValidationMaxBodySize
+	}
+	return cfg, len(cfg.Schemas) > 0
+}
@@ -0,0 +1,144 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package proxy
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/logging"
+)
+
+func TestNewRequestValidationMiddleware(t *testing.T) {
+	endpoint := &config.EndpointConfig{
+		Endpoint: "/users/{id}",
+		ExtraConfig: config.ExtraConfig{
+			Namespace: map[string]interface{}{
+				validationKey: map[string]interface{}{
+					"body": map[string]interface{}{
+						"type":     "object",
+						"required": []interface{}{"name"},
+						"properties": map[string]interface{}{
+							"name": map[string]interface{}{"type": "string"},
+						},
+					},
+					"query": map[string]interface{}{
+						"properties": map[string]interface{}{
+							"page": map[string]interface{}{"type": "string", "pattern": "^[0-9]+$"},
+						},
+					},
+					"params": map[string]interface{}{
+						"properties": map[string]interface{}{
+							"Id": map[string]interface{}{"type": "string", "minLength": 3.0},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	calls := 0
+	p := NewRequestValidationMiddleware(logging.NoOp, endpoint)(func(_ context.Context, r *Request) (*Response, error) {
+		calls++
+		b, _ := io.ReadAll(r.Body)
+		if string(b) != `{"name":"foo"}` {
+			t.Errorf("unexpected body: %s", string(b))
+		}
+		return &Response{Data: map[string]interface{}{"ok": true}, IsComplete: true}, nil
+	})
+
+	resp, err := p(context.Background(), &Request{
+		Body:   io.NopCloser(strings.NewReader(`{"name":"foo"}`)),
+		Query:  map[string][]string{"page": {"2"}},
+		Params: map[string]string{"Id": "123"},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if resp == nil || !resp.IsComplete || calls != 1 {
+		t.Errorf("unexpected response: %v", resp)
+	}
+
+	_, err = p(context.Background(), &Request{
+		Body:   io.NopCloser(strings.NewReader(`{"name":42}`)),
+		Query:  map[string][]string{"page": {"a", "b"}},
+		Params: map[string]string{"Id": "1"},
+	})
+	if calls != 1 {
+		t.Error("the backend should not be called")
+	}
+	vErr, ok := err.(RequestValidationError)
+	if !ok {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if vErr.StatusCode() != 400 || vErr.Encoding() != "application/json" {
+		t.Errorf("unexpected error details: %d %s", vErr.StatusCode(), vErr.Encoding())
+	}
+	expected := `{"errors":[{"path":"/params/Id","message":"expected at least 3 characters"},` +
+		`{"path":"/query/page","message":"expected string, got array"},` +
+		`{"path":"/body/name","message":"expected string, got number"}]}`
+	if vErr.Error() != expected {
+		t.Errorf("unexpected error message: %s", vErr.Error())
+	}
+
+	_, err = p(context.Background(), &Request{Body: io.NopCloser(strings.NewReader(`{"name":`))})
+	if err == nil || !strings.Contains(err.Error(), "invalid JSON") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	_, err = p(context.Background(), &Request{})
+	if err == nil || !strings.Contains(err.Error(), "expected object, got null") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestNewRequestValidationMiddleware_invalidSchema(t *testing.T) {
+	endpoint := &config.EndpointConfig{
+		ExtraConfig: config.ExtraConfig{
+			Namespace: map[string]interface{}{
+				validationKey: map[string]interface{}{
+					"body": map[string]interface{}{"pattern": "("},
+				},
+			},
+		},
+	}
+	expected := &Response{}
+	p := NewRequestValidationMiddleware(logging.NoOp, endpoint)(dummyProxy(expected))
+	if resp, err := p(context.Background(), &Request{}); err != nil || resp != expected {
+		t.Errorf("unexpected result: %v %v", resp, err)
+	}
+}
+
+func TestNewRequestValidationMiddleware_maxBodySize(t *testing.T) {
+	endpoint := &config.EndpointConfig{
+		ExtraConfig: config.ExtraConfig{
+			Namespace: map[string]interface{}{
+				validationKey: map[string]interface{}{
+					"body":          map[string]interface{}{"type": "object"},
+					"max_body_size": 10.0,
+				},
+			},
+		},
+	}
+	expected := &Response{}
+	p := NewRequestValidationMiddleware(logging.NoOp, endpoint)(dummyProxy(expected))
+
+	if resp, err := p(context.Background(), &Request{Body: io.NopCloser(strings.NewReader(`{"a":"b"}`))}); err != nil || resp != expected {
+		t.Errorf("unexpected result: %v %v", resp, err)
+	}
+
+	_, err := p(context.Background(), &Request{Body: io.NopCloser(strings.NewReader(`{"a":"bcd"}`))})
+	sizeErr, ok := err.(RequestBodyTooLargeError)
+	if !ok {
+		t.Errorf("unexpected error: %v", err)
+		return
+	}
+	if sizeErr.StatusCode() != 413 || sizeErr.Max != 10 {
+		t.Errorf("unexpected error details: %d %d", sizeErr.StatusCode(), sizeErr.Max)
+	}
+}
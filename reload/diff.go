@@ -0,0 +1,204 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package reload
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/luraproject/lura/v2/config"
+)
+
+// Diff contains the keys ('METHOD /path') of the endpoints grouped by the kind of change
+type Diff struct {
+	Added     []string
+	Removed   []string
+	Changed   []string
+	Unchanged []string
+	// ServiceChanged flags changes outside the endpoints, like the global extra config
+	ServiceChanged bool
+}
+
+// IsEmpty returns true if there is nothing to reload
+func (d Diff) IsEmpty() bool {
+	return !d.ServiceChanged && len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// String implements the fmt.Stringer interface
+func (d Diff) String() string {
+	return fmt.Sprintf("added: %d, removed: %d, changed: %d, unchanged: %d, service changed: %v",
+		len(d.Added), len(d.Removed), len(d.Changed), len(d.Unchanged), d.ServiceChanged)
+}
+
+// RestartRequiredError is the error returned when the new configuration changes settings that
+// can not be applied without restarting the service, like the listener or the http transport
+type RestartRequiredError struct {
+	Fields []string
+}
+
+// Error implements the error interface
+func (e *RestartRequiredError) Error() string {
+	return "reload: restart required to apply the changes in " + strings.Join(e.Fields, ", ")
+}
+
+// NewDiff compares the endpoint hashes and secret digests of both configurations, so the rotated
+// secrets are reloaded too. It returns a RestartRequiredError if the changes can not be applied
+// by swapping the routing table.
+func NewDiff(current, next config.ServiceConfig) (Diff, error) {
+	if fields := changedStaticFields(current, next); len(fields) > 0 {
+		return Diff{}, &RestartRequiredError{Fields: fields}
+	}
+
+	currentHashes, err := endpointHashes(current)
+	if err != nil {
+		return Diff{}, err
+	}
+	nextHashes, err := endpointHashes(next)
+	if err != nil {
+		return Diff{}, err
+	}
+
+	diff := Diff{}
+	for k, h := range nextHashes {
+		old, ok := currentHashes[k]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, k)
+		case old != h:
+			diff.Changed = append(diff.Changed, k)
+		default:
+			diff.Unchanged = append(diff.Unchanged, k)
+		}
+	}
+	for k := range currentHashes {
+		if _, ok := nextHashes[k]; !ok {
+			diff.Removed = append(diff.Removed, k)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	sort.Strings(diff.Unchanged)
+
+	current.Endpoints, next.Endpoints = nil, nil
+	currentHash, err := current.Hash()
+	if err != nil {
+		return Diff{}, err
+	}
+	nextHash, err := next.Hash()
+	if err != nil {
+		return Diff{}, err
+	}
+	diff.ServiceChanged = currentHash != nextHash || current.SecretsDigest() != next.SecretsDigest()
+
+	return diff, nil
+}
+
+// EndpointKey returns the key identifying the endpoint in the routing table
+func EndpointKey(e *config.EndpointConfig) string {
+	return strings.ToUpper(e.Method) + " " + e.Endpoint
+}
+
+func endpointHashes(cfg config.ServiceConfig) (map[string]string, error) {
+	res := make(map[string]string, len(cfg.Endpoints))
+	for _, e := range cfg.Endpoints {
+		h, err := endpointHash(e)
+		if err != nil {
+			return nil, err
+		}
+		res[EndpointKey(e)] = h
+	}
+	return res, nil
+}
+
+// endpointHash returns the hash of the endpoint config combined with the digest of its secrets
+func endpointHash(e *config.EndpointConfig) (string, error) {
+	h, err := e.Hash()
+	if err != nil {
+		return "", err
+	}
+	return h + ":" + e.SecretsDigest(), nil
+}
+
+// staticFields are the settings consumed once at startup by the listener, the http transport,
+// the plugin loader and the async agents
+type staticFields struct {
+	Port                     int
+	Address                  string
+	TLS                      *config.TLS
+	UseH2C                   bool
+	ReadTimeout              time.Duration
+	WriteTimeout             time.Duration
+	IdleTimeout              time.Duration
+	ReadHeaderTimeout        time.Duration
+	MaxHeaderBytes           int
+	MaxShutdownDuration      time.Duration
+	DisableKeepAlives        bool
+	DisableCompression       bool
+	MaxIdleConns             int
+	MaxIdleConnsPerHost      int
+	IdleConnTimeout          time.Duration
+	ResponseHeaderTimeout    time.Duration
+	ExpectContinueTimeout    time.Duration
+	DialerTimeout            time.Duration
+	DialerFallbackDelay      time.Duration
+	DialerKeepAlive          time.Duration
+	AllowInsecureConnections bool
+	ClientTLS                *config.ClientTLS
+	DNSCacheTTL              time.Duration
+	Plugin                   *config.Plugin
+	AsyncAgents              []*config.AsyncAgent
+}
+
+func newStaticFields(cfg config.ServiceConfig) staticFields {
+	return staticFields{
+		Port:                     cfg.Port,
+		Address:                  cfg.Address,
+		TLS:                      cfg.TLS,
+		UseH2C:                   cfg.UseH2C,
+		ReadTimeout:              cfg.ReadTimeout,
+		WriteTimeout:             cfg.WriteTimeout,
+		IdleTimeout:              cfg.IdleTimeout,
+		ReadHeaderTimeout:        cfg.ReadHeaderTimeout,
+		MaxHeaderBytes:           cfg.MaxHeaderBytes,
+		MaxShutdownDuration:      cfg.MaxShutdownDuration,
+		DisableKeepAlives:        cfg.DisableKeepAlives,
+		DisableCompression:       cfg.DisableCompression,
+		MaxIdleConns:             cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:      cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:          cfg.IdleConnTimeout,
+		ResponseHeaderTimeout:    cfg.ResponseHeaderTimeout,
+		ExpectContinueTimeout:    cfg.ExpectContinueTimeout,
+		DialerTimeout:            cfg.DialerTimeout,
+		DialerFallbackDelay:      cfg.DialerFallbackDelay,
+		DialerKeepAlive:          cfg.DialerKeepAlive,
+		AllowInsecureConnections: cfg.AllowInsecureConnections,
+		ClientTLS:                cfg.ClientTLS,
+		DNSCacheTTL:              cfg.DNSCacheTTL,
+		Plugin:                   cfg.Plugin,
+		AsyncAgents:              cfg.AsyncAgents,
+	}
+}
+
+func changedStaticFields(current, next config.ServiceConfig) []string {
+	a := reflect.ValueOf(newStaticFields(current))
+	b := reflect.ValueOf(newStaticFields(next))
+	t := a.Type()
+
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		// the values are compared by their json representation, as the hashes do, because
+		// the backends of the async agents contain functions
+		x, errX := json.Marshal(a.Field(i).Interface())
+		y, errY := json.Marshal(b.Field(i).Interface())
+		if errX != nil || errY != nil || !bytes.Equal(x, y) {
+			fields = append(fields, t.Field(i).Name)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,145 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package reload
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/luraproject/lura/v2/config"
+)
+
+func TestNewDiff(t *testing.T) {
+	current := config.ServiceConfig{
+		Port: 8080,
+		Endpoints: []*config.EndpointConfig{
+			{Endpoint: "/a", Method: "GET", Backend: []*config.Backend{{URLPattern: "/a"}}},
+			{Endpoint: "/b", Method: "GET", Backend: []*config.Backend{{URLPattern: "/b"}}},
+			{Endpoint: "/c", Method: "post", Backend: []*config.Backend{{URLPattern: "/c"}}},
+		},
+	}
+	next := config.ServiceConfig{
+		Port: 8080,
+		Endpoints: []*config.EndpointConfig{
+			{Endpoint: "/a", Method: "GET", Backend: []*config.Backend{{URLPattern: "/a"}}},
+			{Endpoint: "/b", Method: "GET", Backend: []*config.Backend{{URLPattern: "/b2"}}},
+			{Endpoint: "/d", Method: "GET", Backend: []*config.Backend{{URLPattern: "/d"}}},
+		},
+	}
+
+	diff, err := NewDiff(current, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Diff{
+		Added:     []string{"GET /d"},
+		Removed:   []string{"POST /c"},
+		Changed:   []string{"GET /b"},
+		Unchanged: []string{"GET /a"},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("unexpected diff: %+v", diff)
+	}
+	if diff.IsEmpty() {
+		t.Error("the diff should not be empty")
+	}
+	if s := diff.String(); s != "added: 1, removed: 1, changed: 1, unchanged: 1, service changed: false" {
+		t.Errorf("unexpected description: %s", s)
+	}
+	if len(next.Endpoints) != 3 {
+		t.Error("the endpoints of the received config have been modified")
+	}
+}
+
+func TestNewDiff_serviceChanged(t *testing.T) {
+	current := config.ServiceConfig{Port: 8080}
+	next := config.ServiceConfig{
+		Port:        8080,
+		Name:        "ignored",
+		ExtraConfig: config.ExtraConfig{"some": "value"},
+	}
+
+	diff, err := NewDiff(current, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !diff.ServiceChanged {
+		t.Error("the service change has not been detected")
+	}
+
+	current.ExtraConfig = next.ExtraConfig
+	diff, err = NewDiff(current, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !diff.IsEmpty() {
+		t.Errorf("unexpected diff: %+v", diff)
+	}
+}
+
+func TestNewDiff_secretRotated(t *testing.T) {
+	secret := "first"
+	config.RegisterSecretResolver("reloadtest", config.SecretResolverFunc(func(_ string) (string, error) {
+		return secret, nil
+	}))
+	defer config.UnregisterSecretResolver("reloadtest")
+
+	newConfig := func() config.ServiceConfig {
+		cfg := config.ServiceConfig{
+			Port:        8080,
+			ExtraConfig: config.ExtraConfig{"auth": "${reloadtest:service}"},
+			Endpoints: []*config.EndpointConfig{
+				{Endpoint: "/a", Method: "GET", Backend: []*config.Backend{{URLPattern: "/a"}}},
+				{Endpoint: "/b", Method: "GET", Backend: []*config.Backend{{
+					URLPattern:  "/b",
+					ExtraConfig: config.ExtraConfig{"auth": "${reloadtest:backend}"},
+				}}},
+			},
+		}
+		if err := cfg.ResolveSecrets(); err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	}
+
+	current := newConfig()
+	diff, err := NewDiff(current, newConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !diff.IsEmpty() {
+		t.Errorf("unexpected diff: %+v", diff)
+	}
+
+	secret = "rotated"
+	diff, err = NewDiff(current, newConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Diff{
+		Changed:        []string{"GET /b"},
+		Unchanged:      []string{"GET /a"},
+		ServiceChanged: true,
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf("unexpected diff: %+v", diff)
+	}
+}
+
+func TestNewDiff_restartRequired(t *testing.T) {
+	current := config.ServiceConfig{Port: 8080}
+	next := config.ServiceConfig{Port: 8081, TLS: &config.TLS{PublicKey: "cert.pem"}}
+
+	_, err := NewDiff(current, next)
+	var restartErr *RestartRequiredError
+	if !errors.As(err, &restartErr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(restartErr.Fields, []string{"Port", "TLS"}) {
+		t.Errorf("unexpected fields: %v", restartErr.Fields)
+	}
+	if err.Error() != "reload: restart required to apply the changes in Port, TLS" {
+		t.Errorf("unexpected error message: %s", err.Error())
+	}
+}
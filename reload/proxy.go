@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package reload
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/proxy"
+)
+
+// ProxyFactory is a proxy.Factory reusing the pipes of the endpoints with the same configuration
+// (hash and secrets) as in the last committed generation, so just the new and changed ones are
+// rebuilt
+type ProxyFactory struct {
+	next    proxy.Factory
+	mu      *sync.Mutex
+	current map[string]proxy.Proxy
+	pending map[string]proxy.Proxy
+	built   int
+	err     error
+}
+
+// NewProxyFactory returns a ProxyFactory wrapping the received one
+func NewProxyFactory(pf proxy.Factory) *ProxyFactory {
+	return &ProxyFactory{
+		next:    pf,
+		mu:      new(sync.Mutex),
+		current: map[string]proxy.Proxy{},
+		pending: map[string]proxy.Proxy{},
+	}
+}
+
+// New implements the proxy.Factory interface
+func (p *ProxyFactory) New(cfg *config.EndpointConfig) (proxy.Proxy, error) {
+	h, err := endpointHash(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if prx, ok := p.pending[h]; ok {
+		return prx, nil
+	}
+	if prx, ok := p.current[h]; ok {
+		p.pending[h] = prx
+		return prx, nil
+	}
+
+	prx, err := p.next.New(cfg)
+	if err != nil {
+		if p.err == nil {
+			p.err = fmt.Errorf("building the pipe of %s: %w", EndpointKey(cfg), err)
+		}
+		return prx, err
+	}
+	p.pending[h] = prx
+	p.built++
+	return prx, nil
+}
+
+// Err returns the first error returned by the wrapped factory since the last commit or rollback
+func (p *ProxyFactory) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Commit replaces the pipes of the previous generation with the ones requested since the last
+// commit or rollback and returns the number of pipes built
+func (p *ProxyFactory) Commit() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	built := p.built
+	p.current, p.pending = p.pending, map[string]proxy.Proxy{}
+	p.built, p.err = 0, nil
+	return built
+}
+
+// Rollback discards the pipes requested since the last commit or rollback
+func (p *ProxyFactory) Rollback() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending = map[string]proxy.Proxy{}
+	p.built, p.err = 0, nil
+}
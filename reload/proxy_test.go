@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package reload
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/proxy"
+)
+
+func TestProxyFactory(t *testing.T) {
+	errExpected := errors.New("expect me")
+	calls := 0
+	pf := NewProxyFactory(proxy.FactoryFunc(func(cfg *config.EndpointConfig) (proxy.Proxy, error) {
+		calls++
+		if cfg.Endpoint == "/ko" {
+			return proxy.NoopProxy, errExpected
+		}
+		return func(_ context.Context, _ *proxy.Request) (*proxy.Response, error) {
+			return &proxy.Response{Data: map[string]interface{}{"endpoint": cfg.Endpoint}}, nil
+		}, nil
+	}))
+
+	a := &config.EndpointConfig{Endpoint: "/a", Method: "GET"}
+	b := &config.EndpointConfig{Endpoint: "/b", Method: "GET"}
+
+	for _, e := range []*config.EndpointConfig{a, b, a} {
+		if _, err := pf.New(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if built := pf.Commit(); built != 2 || calls != 2 {
+		t.Errorf("unexpected number of pipes built: %d (%d calls)", built, calls)
+	}
+
+	// unchanged endpoints reuse the pipes of the previous generation
+	changedB := &config.EndpointConfig{Endpoint: "/b", Method: "GET", QueryString: []string{"q"}}
+	for _, e := range []*config.EndpointConfig{{Endpoint: "/a", Method: "GET"}, changedB} {
+		if _, err := pf.New(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if built := pf.Commit(); built != 1 || calls != 3 {
+		t.Errorf("unexpected number of pipes built: %d (%d calls)", built, calls)
+	}
+
+	// failed generations are discarded
+	if _, err := pf.New(&config.EndpointConfig{Endpoint: "/ko", Method: "GET"}); !errors.Is(err, errExpected) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := pf.Err(); !errors.Is(err, errExpected) || err.Error() != "building the pipe of GET /ko: expect me" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	pf.Rollback()
+	if err := pf.Err(); err != nil {
+		t.Errorf("unexpected error after the rollback: %v", err)
+	}
+
+	// the pipes of the last committed generation are still available
+	prx, err := pf.New(changedB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := prx(context.Background(), &proxy.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["endpoint"] != "/b" {
+		t.Errorf("unexpected response: %v", resp.Data)
+	}
+	if calls != 4 {
+		t.Errorf("unexpected number of calls: %d", calls)
+	}
+}
+
+func TestProxyFactory_secretRotated(t *testing.T) {
+	secret := "first"
+	config.RegisterSecretResolver("proxytest", config.SecretResolverFunc(func(_ string) (string, error) {
+		return secret, nil
+	}))
+	defer config.UnregisterSecretResolver("proxytest")
+
+	calls := 0
+	pf := NewProxyFactory(proxy.FactoryFunc(func(_ *config.EndpointConfig) (proxy.Proxy, error) {
+		calls++
+		return proxy.NoopProxy, nil
+	}))
+
+	newEndpoint := func() *config.EndpointConfig {
+		cfg := config.ServiceConfig{Endpoints: []*config.EndpointConfig{{
+			Endpoint:    "/a",
+			Method:      "GET",
+			ExtraConfig: config.ExtraConfig{"auth": "${proxytest:token}"},
+		}}}
+		if err := cfg.ResolveSecrets(); err != nil {
+			t.Fatal(err)
+		}
+		return cfg.Endpoints[0]
+	}
+
+	for _, s := range []string{"first", "first", "rotated"} {
+		secret = s
+		if _, err := pf.New(newEndpoint()); err != nil {
+			t.Fatal(err)
+		}
+		pf.Commit()
+	}
+	if calls != 2 {
+		t.Errorf("unexpected number of pipes built: %d", calls)
+	}
+}
@@ -0,0 +1,289 @@
+// SPDX-License-Identifier: Apache-2.0
+
+/*
+Package reload applies the changes of the configuration file to a running service without
+closing the listener.
+
+Every reload parses and initializes the file, compares the endpoint hashes with the running
+configuration and builds a new router generation. The pipes of the unchanged endpoints are
+reused and the routing table is swapped atomically once the new generation is ready, so the
+in-flight requests are completed by the previous one. Failed reloads keep the previous
+configuration running.
+*/
+package reload
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/logging"
+	"github.com/luraproject/lura/v2/proxy"
+	"github.com/luraproject/lura/v2/router"
+	"github.com/luraproject/lura/v2/transport/http/server"
+)
+
+const logPrefix = "[SERVICE: Reload]"
+
+var (
+	// ErrNotStarted is the error returned when a reload is requested before the service is running
+	ErrNotStarted = errors.New("reload: the service is not running")
+	// ErrRouterExited is the error returned when the router of the new generation ends without
+	// starting its server
+	ErrRouterExited = errors.New("reload: the router exited before serving")
+)
+
+// RunServerFunc is a func that will run the http Server with the given params
+type RunServerFunc func(context.Context, config.ServiceConfig, http.Handler) error
+
+// RouterFactory returns a router factory with a fresh engine, using the received proxy factory
+// and RunServerFunc
+type RouterFactory func(proxy.Factory, RunServerFunc) router.Factory
+
+// Config defines the parts required to run and reload the service
+type Config struct {
+	// Path is the path of the configuration file
+	Path string
+	// Parser parses and initializes the configuration file. If nil, config.NewParser is used.
+	Parser config.Parser
+	// ProxyFactory builds the pipes of the endpoints
+	ProxyFactory proxy.Factory
+	// RouterFactory builds the routers of every generation
+	RouterFactory RouterFactory
+	// RunServer runs the http server. If nil, server.RunServer is used.
+	RunServer RunServerFunc
+	// Logger reports the reloads
+	Logger logging.Logger
+	// Interval is the period between the checks of the modification time of the configuration
+	// file. Zero disables the file watcher, so just the SIGHUP signals trigger a reload.
+	Interval time.Duration
+	// OnReload, if not nil, is called after every reload attempt
+	OnReload func(Diff, error)
+}
+
+// Reloader runs the service and applies the changes of the configuration file
+type Reloader struct {
+	cfg     Config
+	proxies *ProxyFactory
+	handler *switchHandler
+	started chan struct{}
+
+	mu         *sync.Mutex
+	ctx        context.Context
+	current    config.ServiceConfig
+	cancelPrev context.CancelFunc
+	// parsed is the version of the file read by Parse
+	parsed *fileVersion
+}
+
+// New returns a Reloader with the received config
+func New(cfg Config) *Reloader {
+	if cfg.Parser == nil {
+		cfg.Parser = config.NewParser()
+	}
+	if cfg.RunServer == nil {
+		cfg.RunServer = server.RunServer
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = logging.NoOp
+	}
+	return &Reloader{
+		cfg:     cfg,
+		proxies: NewProxyFactory(cfg.ProxyFactory),
+		handler: &switchHandler{},
+		started: make(chan struct{}),
+		mu:      new(sync.Mutex),
+	}
+}
+
+// Parse parses the configuration file with the parser of the reloader and records the version of
+// the file, so the changes made between the parsing and the Run call are detected by the watcher
+func (r *Reloader) Parse() (config.ServiceConfig, error) {
+	v := r.fileVersion()
+	cfg, err := r.cfg.Parser.Parse(r.cfg.Path)
+	if err != nil {
+		return cfg, err
+	}
+	r.mu.Lock()
+	r.parsed = &v
+	r.mu.Unlock()
+	return cfg, nil
+}
+
+// Run starts the service with the received configuration and watches the configuration file
+// until the context is canceled. The watcher compares the file with the version read by Parse or,
+// if the configuration was parsed by the caller, with the version found when Run is called.
+func (r *Reloader) Run(ctx context.Context, cfg config.ServiceConfig) {
+	r.mu.Lock()
+	r.ctx = ctx
+	r.current = cfg
+	baseline := r.fileVersion()
+	if r.parsed != nil {
+		baseline = *r.parsed
+	}
+	r.mu.Unlock()
+
+	runServer := func(ctx context.Context, cfg config.ServiceConfig, h http.Handler) error {
+		r.handler.store(h)
+		r.proxies.Commit()
+		close(r.started)
+		go r.watch(ctx, baseline)
+		return r.cfg.RunServer(ctx, cfg, r.handler)
+	}
+	r.cfg.RouterFactory(r.proxies, runServer).NewWithContext(ctx).Run(cfg)
+}
+
+// Reload parses the configuration file and applies the changes. It returns the error and keeps
+// the previous configuration if the file is not valid, the changes require a restart or the new
+// generation can not be built.
+func (r *Reloader) Reload() (Diff, error) {
+	diff, err := r.reload()
+	switch {
+	case err != nil:
+		r.cfg.Logger.Error(logPrefix, "Keeping the previous configuration:", err.Error())
+	case diff.IsEmpty():
+		r.cfg.Logger.Debug(logPrefix, "No changes to apply")
+	default:
+		r.cfg.Logger.Info(logPrefix, "Configuration reloaded.", diff.String())
+	}
+	if r.cfg.OnReload != nil {
+		r.cfg.OnReload(diff, err)
+	}
+	return diff, err
+}
+
+func (r *Reloader) reload() (Diff, error) {
+	select {
+	case <-r.started:
+	default:
+		return Diff{}, ErrNotStarted
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := r.cfg.Parser.Parse(r.cfg.Path)
+	if err != nil {
+		return Diff{}, err
+	}
+	diff, err := NewDiff(r.current, next)
+	if err != nil || diff.IsEmpty() {
+		return diff, err
+	}
+	if err := r.apply(next); err != nil {
+		return diff, err
+	}
+	r.current = next
+	return diff, nil
+}
+
+// apply builds a new router generation and swaps the routing table once it is ready
+func (r *Reloader) apply(next config.ServiceConfig) error {
+	ctx, cancel := context.WithCancel(r.ctx)
+	result := make(chan error, 2)
+
+	runServer := func(ctx context.Context, _ config.ServiceConfig, h http.Handler) error {
+		if err := r.proxies.Err(); err != nil {
+			result <- err
+			return err
+		}
+		r.handler.store(h)
+		result <- nil
+		<-ctx.Done()
+		return nil
+	}
+
+	go func() {
+		r.cfg.RouterFactory(r.proxies, runServer).NewWithContext(ctx).Run(next)
+		result <- ErrRouterExited
+	}()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-r.ctx.Done():
+		err = r.ctx.Err()
+	}
+	if err != nil {
+		cancel()
+		r.proxies.Rollback()
+		return err
+	}
+
+	built := r.proxies.Commit()
+	r.cfg.Logger.Debug(logPrefix, "Pipes built:", built)
+	if r.cancelPrev != nil {
+		r.cancelPrev()
+	}
+	r.cancelPrev = cancel
+	return nil
+}
+
+// watch triggers a reload on every SIGHUP and on every change of the modification time or the
+// size of the configuration file
+func (r *Reloader) watch(ctx context.Context, last fileVersion) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	defer signal.Stop(sig)
+
+	var tick <-chan time.Time
+	if r.cfg.Interval > 0 {
+		ticker := time.NewTicker(r.cfg.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sig:
+			r.Reload()
+		case <-tick:
+			v := r.fileVersion()
+			if v == last {
+				continue
+			}
+			last = v
+			r.Reload()
+		}
+	}
+}
+
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+func (r *Reloader) fileVersion() fileVersion {
+	info, err := os.Stat(r.cfg.Path)
+	if err != nil {
+		return fileVersion{}
+	}
+	return fileVersion{modTime: info.ModTime(), size: info.Size()}
+}
+
+// switchHandler dispatches the requests to the handler of the current generation
+type switchHandler struct {
+	h atomic.Value
+}
+
+type handlerBox struct {
+	http.Handler
+}
+
+func (s *switchHandler) store(h http.Handler) {
+	s.h.Store(handlerBox{h})
+}
+
+// ServeHTTP implements the http.Handler interface
+func (s *switchHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.h.Load().(handlerBox).ServeHTTP(w, req)
+}
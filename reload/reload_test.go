@@ -0,0 +1,283 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package reload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/logging"
+	"github.com/luraproject/lura/v2/proxy"
+	"github.com/luraproject/lura/v2/router"
+	"github.com/luraproject/lura/v2/router/mux"
+)
+
+const reloadTestConfig = `{
+	"version": 3,
+	"port": %d,
+	"host": ["http://127.0.0.1:8080"],
+	"endpoints": [%s]
+}`
+
+func reloadTestEndpoint(path, backend string) string {
+	return fmt.Sprintf(`{"endpoint": %q, "backend": [{"url_pattern": %q}]}`, path, backend)
+}
+
+func TestReloader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lura.json")
+	writeConfig := func(port int, endpoints ...string) {
+		body := ""
+		for i, e := range endpoints {
+			if i > 0 {
+				body += ","
+			}
+			body += e
+		}
+		if err := os.WriteFile(path, []byte(fmt.Sprintf(reloadTestConfig, port, body)), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mu := new(sync.Mutex)
+	built := map[string]int{}
+	pf := proxy.FactoryFunc(func(cfg *config.EndpointConfig) (proxy.Proxy, error) {
+		backend := cfg.Backend[0].URLPattern
+		mu.Lock()
+		built[backend]++
+		mu.Unlock()
+		if backend == "/ko" {
+			return proxy.NoopProxy, errors.New("unable to build the pipe")
+		}
+		return func(_ context.Context, _ *proxy.Request) (*proxy.Response, error) {
+			return &proxy.Response{Data: map[string]interface{}{"backend": backend}, IsComplete: true}, nil
+		}, nil
+	})
+
+	handler := make(chan http.Handler, 1)
+	runServer := func(ctx context.Context, _ config.ServiceConfig, h http.Handler) error {
+		handler <- h
+		<-ctx.Done()
+		return nil
+	}
+
+	reports := make(chan error, 10)
+	r := New(Config{
+		Path:         path,
+		ProxyFactory: pf,
+		RouterFactory: func(pf proxy.Factory, rs RunServerFunc) router.Factory {
+			return mux.NewFactory(mux.Config{
+				Engine:         mux.DefaultEngine(),
+				HandlerFactory: mux.EndpointHandler,
+				ProxyFactory:   pf,
+				Logger:         logging.NoOp,
+				RunServer:      mux.RunServerFunc(rs),
+			})
+		},
+		RunServer: runServer,
+		OnReload:  func(_ Diff, err error) { reports <- err },
+	})
+
+	if _, err := r.Reload(); !errors.Is(err, ErrNotStarted) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	<-reports
+
+	writeConfig(8080, reloadTestEndpoint("/a", "/a1"), reloadTestEndpoint("/b", "/b1"))
+	cfg, err := config.NewParser().Parse(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx, cfg)
+
+	var h http.Handler
+	select {
+	case h = <-handler:
+	case <-time.After(time.Second):
+		t.Fatal("the server has not been started")
+	}
+
+	assertResponse := func(path string, status int, body string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, http.NoBody))
+		b, _ := io.ReadAll(w.Result().Body)
+		if w.Code != status {
+			t.Errorf("%s: unexpected status code: %d", path, w.Code)
+			return
+		}
+		if body != "" && string(b) != body {
+			t.Errorf("%s: unexpected body: %s", path, string(b))
+		}
+	}
+
+	assertResponse("/a", http.StatusOK, `{"backend":"/a1"}`)
+	assertResponse("/b", http.StatusOK, `{"backend":"/b1"}`)
+	assertResponse("/c", http.StatusNotFound, "")
+
+	// a changed, b unchanged, c added
+	writeConfig(8080, reloadTestEndpoint("/a", "/a2"), reloadTestEndpoint("/b", "/b1"), reloadTestEndpoint("/c", "/c1"))
+	diff, err := r.Reload()
+	<-reports
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Added) != 1 || len(diff.Changed) != 1 || len(diff.Unchanged) != 1 {
+		t.Errorf("unexpected diff: %+v", diff)
+	}
+	assertResponse("/a", http.StatusOK, `{"backend":"/a2"}`)
+	assertResponse("/b", http.StatusOK, `{"backend":"/b1"}`)
+	assertResponse("/c", http.StatusOK, `{"backend":"/c1"}`)
+	mu.Lock()
+	if built["/b1"] != 1 {
+		t.Errorf("the unchanged pipe has been rebuilt %d times", built["/b1"])
+	}
+	mu.Unlock()
+
+	// failed reloads keep the previous configuration
+	for _, tc := range []struct {
+		name  string
+		write func()
+	}{
+		{"invalid file", func() { os.WriteFile(path, []byte("{"), 0o600) }},
+		{"restart required", func() { writeConfig(8081, reloadTestEndpoint("/a", "/a3")) }},
+		{"pipe error", func() { writeConfig(8080, reloadTestEndpoint("/a", "/a3"), reloadTestEndpoint("/d", "/ko")) }},
+	} {
+		tc.write()
+		if _, err := r.Reload(); err == nil {
+			t.Errorf("%s: error expected", tc.name)
+		}
+		<-reports
+		assertResponse("/a", http.StatusOK, `{"backend":"/a2"}`)
+		assertResponse("/c", http.StatusOK, `{"backend":"/c1"}`)
+		assertResponse("/d", http.StatusNotFound, "")
+	}
+
+	var restartErr *RestartRequiredError
+	writeConfig(8081, reloadTestEndpoint("/a", "/a3"))
+	if _, err := r.Reload(); !errors.As(err, &restartErr) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	<-reports
+}
+
+func TestReloader_watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lura.json")
+	writeConfig := func(endpoint string) {
+		if err := os.WriteFile(path, []byte(fmt.Sprintf(reloadTestConfig, 8080, endpoint)), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig(reloadTestEndpoint("/a", "/a"))
+	cfg, err := config.NewParser().Parse(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := make(chan http.Handler, 1)
+	reports := make(chan Diff, 10)
+	r := New(watchTestConfig(t, path, handler, reports))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx, cfg)
+
+	var h http.Handler
+	select {
+	case h = <-handler:
+	case <-time.After(time.Second):
+		t.Fatal("the server has not been started")
+	}
+
+	writeConfig(reloadTestEndpoint("/a", "/a") + "," + reloadTestEndpoint("/watched", "/watched"))
+	select {
+	case d := <-reports:
+		if len(d.Added) != 1 || d.Added[0] != "GET /watched" {
+			t.Errorf("unexpected diff: %+v", d)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the change of the file has not been detected")
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/watched", http.NoBody))
+	if w.Code != http.StatusOK {
+		t.Errorf("unexpected status code: %d", w.Code)
+	}
+}
+
+func TestReloader_changedBeforeRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lura.json")
+	writeConfig := func(endpoint string) {
+		if err := os.WriteFile(path, []byte(fmt.Sprintf(reloadTestConfig, 8080, endpoint)), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig(reloadTestEndpoint("/a", "/a"))
+
+	handler := make(chan http.Handler, 1)
+	reports := make(chan Diff, 10)
+	r := New(watchTestConfig(t, path, handler, reports))
+	cfg, err := r.Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the file changes before the service is started
+	writeConfig(reloadTestEndpoint("/a", "/a") + "," + reloadTestEndpoint("/late", "/late"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Run(ctx, cfg)
+
+	select {
+	case d := <-reports:
+		if len(d.Added) != 1 || d.Added[0] != "GET /late" {
+			t.Errorf("unexpected diff: %+v", d)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the change of the file has not been detected")
+	}
+}
+
+func watchTestConfig(t *testing.T, path string, handler chan http.Handler, reports chan Diff) Config {
+	return Config{
+		Path: path,
+		ProxyFactory: proxy.FactoryFunc(func(_ *config.EndpointConfig) (proxy.Proxy, error) {
+			return proxy.NoopProxy, nil
+		}),
+		RouterFactory: func(pf proxy.Factory, rs RunServerFunc) router.Factory {
+			return mux.NewFactory(mux.Config{
+				Engine:         mux.DefaultEngine(),
+				HandlerFactory: mux.EndpointHandler,
+				ProxyFactory:   pf,
+				Logger:         logging.NoOp,
+				RunServer:      mux.RunServerFunc(rs),
+			})
+		},
+		RunServer: func(ctx context.Context, _ config.ServiceConfig, h http.Handler) error {
+			handler <- h
+			<-ctx.Done()
+			return nil
+		},
+		Interval: 10 * time.Millisecond,
+		OnReload: func(d Diff, err error) {
+			if err != nil {
+				t.Error(err)
+			}
+			reports <- d
+		},
+	}
+}
@@ -7,8 +7,10 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/logging"
 	"github.com/luraproject/lura/v2/proxy"
 	"github.com/luraproject/lura/v2/router/mux"
+	"github.com/luraproject/lura/v2/transport/http/server"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
@@ -24,6 +26,17 @@ func NewEndpointHandler(cfg *config.EndpointConfig, prxy proxy.Proxy) http.Handl
 	return hf(cfg, prxy)
 }
 
+// NewEndpointHandlerWithLogger returns a HandlerFactory using the injected logger and the default
+// ToHTTPError function
+func NewEndpointHandlerWithLogger(logger logging.Logger) HandlerFactory {
+	hf := mux.CustomEndpointHandlerWithLogger(
+		logger,
+		mux.NewRequestBuilder(extractParamsFromEndpoint),
+		server.DefaultToHTTPError,
+	)
+	return HandlerFactory(hf)
+}
+
 func extractParamsFromEndpoint(r *http.Request) map[string]string {
 	ctx := r.Context()
 	rctx := chi.RouteContext(ctx)
@@ -46,7 +46,7 @@ func DefaultFactory(proxyFactory proxy.Factory, logger logging.Logger) router.Fa
 		Config{
 			Engine:         chi.NewRouter(),
 			Middlewares:    chi.Middlewares{middleware.Logger},
-			HandlerFactory: NewEndpointHandler,
+			HandlerFactory: NewEndpointHandlerWithLogger(logger),
 			ProxyFactory:   proxyFactory,
 			Logger:         logger,
 			DebugPattern:   ChiDefaultDebugPattern,
@@ -94,7 +94,7 @@ func (r chiRouter) Run(cfg config.ServiceConfig) {
 
 	server.InitHTTPDefaultTransport(cfg)
 
-	r.registerKrakendEndpoints(cfg.Endpoints)
+	r.registerKrakendEndpoints(cfg)
 
 	r.cfg.Engine.NotFound(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set(server.CompleteResponseHeaderName, server.HeaderIncompleteResponseValue)
@@ -117,15 +117,19 @@ func (r chiRouter) registerDebugEndpoints() {
 	r.cfg.Engine.Delete(r.cfg.DebugPattern, debugHandler)
 }
 
-func (r chiRouter) registerKrakendEndpoints(endpoints []*config.EndpointConfig) {
-	for _, c := range endpoints {
+func (r chiRouter) registerKrakendEndpoints(cfg config.ServiceConfig) {
+	for _, c := range cfg.Endpoints {
 		proxyStack, err := r.cfg.ProxyFactory.New(c)
 		if err != nil {
 			r.cfg.Logger.Error(logPrefix, "calling the ProxyFactory", err.Error())
 			continue
 		}
 
-		r.registerKrakendEndpoint(c.Method, c, r.cfg.HandlerFactory(c, proxyStack), len(c.Backend))
+		h := r.cfg.HandlerFactory(c, proxyStack)
+		if compression, ok := server.NewCompressionConfig(cfg.ExtraConfig, c.ExtraConfig); ok {
+			h = server.NewCompressionHandler(compression, h).ServeHTTP
+		}
+		r.registerKrakendEndpoint(c.Method, c, h, len(c.Backend))
 	}
 }
 
@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/luraproject/lura/v2/transport/http/server"
+)
+
+// compressionHandler wraps the handler so the responses are compressed when the client accepts
+// any of the configured encodings
+func compressionHandler(cfg *server.CompressionConfig, h gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cw, ok := server.NewCompressionWriter(c.Writer, c.Request, cfg)
+		if !ok {
+			h(c)
+			return
+		}
+		w := c.Writer
+		c.Writer = &compressionResponseWriter{ResponseWriter: w, cw: cw}
+		defer func() {
+			cw.Close()
+			c.Writer = w
+		}()
+		h(c)
+	}
+}
+
+// compressionResponseWriter is a gin.ResponseWriter sending the body through a
+// server.CompressionWriter
+type compressionResponseWriter struct {
+	gin.ResponseWriter
+	cw *server.CompressionWriter
+}
+
+func (w *compressionResponseWriter) WriteHeader(code int) {
+	w.cw.WriteHeader(code)
+}
+
+// WriteHeaderNow is a no-op because the status is sent once the compression is decided
+func (*compressionResponseWriter) WriteHeaderNow() {}
+
+func (w *compressionResponseWriter) Status() int {
+	return w.cw.Status()
+}
+
+func (w *compressionResponseWriter) Write(data []byte) (int, error) {
+	return w.cw.Write(data)
+}
+
+func (w *compressionResponseWriter) WriteString(s string) (int, error) {
+	return w.cw.Write([]byte(s))
+}
+
+func (w *compressionResponseWriter) Flush() {
+	w.cw.Flush()
+}
+
+func (w *compressionResponseWriter) Unwrap() http.ResponseWriter {
+	return w.cw
+}
@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package gin
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/encoding"
+	"github.com/luraproject/lura/v2/proxy"
+	"github.com/luraproject/lura/v2/transport/http/server"
+)
+
+func TestCompressionHandler(t *testing.T) {
+	content := strings.Repeat("supu ", 300)
+	jsonProxy := func(_ context.Context, _ *proxy.Request) (*proxy.Response, error) {
+		return &proxy.Response{IsComplete: true, Data: map[string]interface{}{"content": content}}, nil
+	}
+	noopProxy := func(_ context.Context, _ *proxy.Request) (*proxy.Response, error) {
+		return &proxy.Response{
+			IsComplete: true,
+			Io:         strings.NewReader("already encoded"),
+			Metadata: proxy.Metadata{
+				StatusCode: http.StatusOK,
+				Headers:    map[string][]string{"Content-Encoding": {"br"}, "Content-Type": {"application/json"}},
+			},
+		}, nil
+	}
+	cfg := &server.CompressionConfig{Encodings: []string{"gzip"}, MinSize: 100, MIMETypes: server.DefaultCompressionMIMETypes}
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/json", compressionHandler(cfg, EndpointHandler(&config.EndpointConfig{Timeout: time.Second, OutputEncoding: encoding.JSON}, jsonProxy)))
+	engine.GET("/noop", compressionHandler(cfg, EndpointHandler(&config.EndpointConfig{Timeout: time.Second, OutputEncoding: encoding.NOOP}, noopProxy)))
+
+	req, _ := http.NewRequest("GET", "/json", http.NoBody)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("unexpected status code: %d", w.Code)
+	}
+	if ce := w.Header().Get("Content-Encoding"); ce != "gzip" {
+		t.Errorf("unexpected content encoding: %s", ce)
+	}
+	gr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(gr)
+	if string(body) != `{"content":"`+content+`"}` {
+		t.Errorf("unexpected body: %s", string(body))
+	}
+
+	req, _ = http.NewRequest("GET", "/noop", http.NoBody)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if ce := w.Header().Get("Content-Encoding"); ce != "br" {
+		t.Errorf("unexpected content encoding: %s", ce)
+	}
+	if w.Body.String() != "already encoded" {
+		t.Errorf("unexpected body: %s", w.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/json", http.NoBody)
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if ce := w.Header().Get("Content-Encoding"); ce != "" {
+		t.Errorf("unexpected content encoding: %s", ce)
+	}
+	if w.Body.String() != `{"content":"`+content+`"}` {
+		t.Errorf("unexpected body: %s", w.Body.String())
+	}
+}
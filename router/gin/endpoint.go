@@ -4,6 +4,7 @@ package gin
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/textproto"
 
@@ -11,9 +12,11 @@ import (
 
 	"github.com/luraproject/lura/v2/config"
 	"github.com/luraproject/lura/v2/core"
+	"github.com/luraproject/lura/v2/encoding"
 	"github.com/luraproject/lura/v2/logging"
 	"github.com/luraproject/lura/v2/proxy"
 	"github.com/luraproject/lura/v2/transport/http/server"
+	"github.com/luraproject/lura/v2/transport/http/server/websocket"
 )
 
 const requestParamsAsterisk string = "*"
@@ -42,12 +45,36 @@ func CustomErrorEndpointHandler(logger logging.Logger, errF server.ToHTTPError)
 		render := getRender(configuration)
 		logPrefix := "[ENDPOINT: " + configuration.Endpoint + "]"
 
+		if tunnel, ok := websocket.New(logger, configuration); ok {
+			return func(c *gin.Context) {
+				tunnel(c.Writer, c.Request, requestGenerator(c, configuration.QueryString))
+			}
+		}
+
+		newRequestContext := func(c *gin.Context) (context.Context, func() bool, context.CancelFunc) {
+			ctx, cancel := context.WithTimeout(c, configuration.Timeout)
+			return ctx, func() bool { return true }, cancel
+		}
+		if configuration.OutputEncoding == encoding.NOOP && server.IsStreamingEndpoint(configuration) {
+			// streaming endpoints are not affected by the service write timeout and their
+			// timeout only covers the reception of the backend response headers. The context
+			// of the request is used so a client disconnection cancels the backend request.
+			render = streamingRender
+			newRequestContext = func(c *gin.Context) (context.Context, func() bool, context.CancelFunc) {
+				if err := server.DisableWriteDeadline(c.Writer); err != nil {
+					logger.Debug(logPrefix, "Unable to disable the write deadline:", err.Error())
+				}
+				return server.NewStreamingContext(c.Request.Context(), configuration.Timeout)
+			}
+		}
+
 		return func(c *gin.Context) {
-			requestCtx, cancel := context.WithTimeout(c, configuration.Timeout)
+			requestCtx, stopTimeout, cancel := newRequestContext(c)
 
 			c.Header(core.KrakendHeaderName, core.KrakendHeaderValue)
 
 			response, err := prxy(requestCtx, requestGenerator(c, configuration.QueryString))
+			stopTimeout()
 
 			select {
 			case <-requestCtx.Done():
@@ -59,7 +86,7 @@ func CustomErrorEndpointHandler(logger logging.Logger, errF server.ToHTTPError)
 
 			complete := server.HeaderIncompleteResponseValue
 
-			if response != nil && len(response.Data) > 0 {
+			if response != nil && (len(response.Data) > 0 || response.Metadata.Mocked) {
 				if response.IsComplete {
 					complete = server.HeaderCompleteResponseValue
 					if isCacheEnabled {
@@ -99,6 +126,15 @@ func CustomErrorEndpointHandler(logger logging.Logger, errF server.ToHTTPError)
 							}
 						}
 					}
+					var validationErr proxy.RequestValidationError
+					if errors.As(err, &validationErr) {
+						// the violations are always returned, so the clients can fix their requests
+						c.Status(validationErr.StatusCode())
+						ErrorResponseWriter(c, validationErr)
+						cancel()
+						return
+					}
+
 					if t, ok := err.(responseError); ok {
 						c.Status(t.StatusCode())
 					} else {
@@ -113,7 +149,11 @@ func CustomErrorEndpointHandler(logger logging.Logger, errF server.ToHTTPError)
 				}
 			}
 
-			render(c, response)
+			if response != nil && response.Metadata.Mocked {
+				mockedRender(c, response, render)
+			} else {
+				render(c, response)
+			}
 			cancel()
 		}
 	}
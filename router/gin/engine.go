@@ -23,6 +23,14 @@ import (
 
 const Namespace = "github_com/luraproject/lura/router/gin"
 
+func init() {
+	config.MustRegisterNamespace(config.Namespace{
+		Name:   Namespace,
+		Scope:  config.ServiceScope,
+		Schema: config.StructSchema(engineConfiguration{}),
+	})
+}
+
 type EngineOptions struct {
 	Logger    logging.Logger
 	Writer    io.Writer
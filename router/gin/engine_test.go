@@ -87,3 +87,24 @@ func TestNewEngine_paramsAreChecked(t *testing.T) {
 	assertResponse("/user/123%3f/public", http.StatusBadRequest, "error: encoded url params")
 	assertResponse("/user/123%23/public", http.StatusBadRequest, "error: encoded url params")
 }
+
+func TestNamespace_strict(t *testing.T) {
+	cfg := config.ServiceConfig{
+		Version: config.ConfigVersion,
+		ExtraConfig: config.ExtraConfig{
+			Namespace: map[string]interface{}{
+				"auto_options":      true,
+				"health_path":       "/health",
+				"logger_skip_paths": []interface{}{"/health"},
+			},
+		},
+	}
+	if err := cfg.ValidateStrict().Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	cfg.ExtraConfig[Namespace].(map[string]interface{})["auto_option"] = true
+	if err := cfg.ValidateStrict().Err(); err == nil {
+		t.Error("expecting an error")
+	}
+}
@@ -3,6 +3,7 @@
 package gin
 
 import (
+	"bytes"
 	"io"
 	"net/http"
 	"sync"
@@ -11,6 +12,7 @@ import (
 	"github.com/luraproject/lura/v2/config"
 	"github.com/luraproject/lura/v2/encoding"
 	"github.com/luraproject/lura/v2/proxy"
+	"github.com/luraproject/lura/v2/transport/http/server"
 )
 
 // Render defines the signature of the functions to be use for the final response
@@ -31,6 +33,9 @@ var (
 		"json-collection": jsonCollectionRender,
 		XML:               xmlRender,
 		YAML:              yamlRender,
+		encoding.CSV:      csvRender,
+		encoding.FORM:     formRender,
+		encoding.MSGPACK:  msgpackRender,
 	}
 )
 
@@ -147,10 +152,74 @@ func yamlRender(c *gin.Context, response *proxy.Response) {
 	c.YAML(status, response.Data)
 }
 
+func csvRender(c *gin.Context, response *proxy.Response) {
+	buf := &bytes.Buffer{}
+	if err := encoding.EncodeCSV(buf, csvRows(response)); err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	c.Data(c.Writer.Status(), "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// csvRows returns the items of the collection or the response data as a single row
+func csvRows(response *proxy.Response) []interface{} {
+	if response == nil {
+		return []interface{}{}
+	}
+	if col, ok := response.Data["collection"].([]interface{}); ok {
+		return col
+	}
+	return []interface{}{response.Data}
+}
+
+func formRender(c *gin.Context, response *proxy.Response) {
+	var data map[string]interface{}
+	if response != nil {
+		data = response.Data
+	}
+	form, err := encoding.EncodeForm(data)
+	if err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	c.Data(c.Writer.Status(), "application/x-www-form-urlencoded", []byte(form))
+}
+
+func msgpackRender(c *gin.Context, response *proxy.Response) {
+	data := map[string]interface{}{}
+	if response != nil {
+		data = response.Data
+	}
+	buf := &bytes.Buffer{}
+	if err := encoding.EncodeMsgpack(buf, data); err != nil {
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+	c.Data(c.Writer.Status(), "application/msgpack", buf.Bytes())
+}
+
 func noopRender(c *gin.Context, response *proxy.Response) {
+	if !writeNoopHeaders(c, response) {
+		return
+	}
+	io.Copy(c.Writer, response.Io)
+}
+
+// streamingRender is the render used by the no-op endpoints with the streaming mode enabled.
+// It flushes the response as the data arrives from the backend.
+func streamingRender(c *gin.Context, response *proxy.Response) {
+	if !writeNoopHeaders(c, response) {
+		return
+	}
+	server.StreamResponse(c.Writer, response.Io)
+}
+
+// writeNoopHeaders writes the status code and the headers of the response and returns true
+// if there is a body to copy
+func writeNoopHeaders(c *gin.Context, response *proxy.Response) bool {
 	if response == nil {
 		c.Status(http.StatusInternalServerError)
-		return
+		return false
 	}
 	for k, vs := range response.Metadata.Headers {
 		for _, v := range vs {
@@ -158,10 +227,21 @@ func noopRender(c *gin.Context, response *proxy.Response) {
 		}
 	}
 	c.Status(response.Metadata.StatusCode)
-	if response.Io == nil {
+	return response.Io != nil
+}
+
+// mockedRender renders the mocked responses with their status code. The mocked bodies are copied
+// as they are, while the mocked data is rendered with the render of the endpoint.
+func mockedRender(c *gin.Context, response *proxy.Response, render Render) {
+	if response.Metadata.StatusCode != 0 {
+		c.Status(response.Metadata.StatusCode)
+	}
+	if response.Io != nil {
+		c.Writer.WriteHeaderNow()
+		io.Copy(c.Writer, response.Io)
 		return
 	}
-	io.Copy(c.Writer, response.Io)
+	render(c, response)
 }
 
 var emptyResponse = gin.H{}
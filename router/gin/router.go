@@ -145,7 +145,11 @@ func (r ginRouter) registerKrakendEndpoints(rg *gin.RouterGroup, cfg config.Serv
 				proxyBuildFailedHandler, 1)
 			continue
 		}
-		r.registerKrakendEndpoint(rg, c.Method, c, r.cfg.HandlerFactory(c, proxyStack), len(c.Backend))
+		h := r.cfg.HandlerFactory(c, proxyStack)
+		if compression, ok := server.NewCompressionConfig(cfg.ExtraConfig, c.ExtraConfig); ok {
+			h = compressionHandler(compression, h)
+		}
+		r.registerKrakendEndpoint(rg, c.Method, c, h, len(c.Backend))
 	}
 }
 
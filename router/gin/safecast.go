@@ -40,6 +40,11 @@ func (s *safeCast) WriteHeader(statusCode int) {
 	s.w.WriteHeader(statusCode)
 }
 
+// Unwrap returns the wrapped writer, so it can be managed with an http.ResponseController
+func (s *safeCast) Unwrap() http.ResponseWriter {
+	return s.w
+}
+
 func (s *safeCast) Flush() {
 	if f, ok := s.w.(http.Flusher); ok {
 		f.Flush()
@@ -29,7 +29,7 @@ func DefaultConfig(pf proxy.Factory, logger logging.Logger) mux.Config {
 	return mux.Config{
 		Engine:         gorillaEngine{gorilla.NewRouter()},
 		Middlewares:    []mux.HandlerMiddleware{},
-		HandlerFactory: mux.CustomEndpointHandler(mux.NewRequestBuilder(gorillaParamsExtractor)),
+		HandlerFactory: mux.CustomEndpointHandlerWithLogger(logger, mux.NewRequestBuilder(gorillaParamsExtractor), server.DefaultToHTTPError),
 		ProxyFactory:   pf,
 		Logger:         logger,
 		DebugPattern:   "/__debug/{params}",
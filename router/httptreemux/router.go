@@ -28,7 +28,7 @@ func DefaultConfig(pf proxy.Factory, logger logging.Logger) mux.Config {
 	return mux.Config{
 		Engine:         NewEngine(httptreemux.NewContextMux()),
 		Middlewares:    []mux.HandlerMiddleware{},
-		HandlerFactory: mux.CustomEndpointHandler(mux.NewRequestBuilder(ParamsExtractor)),
+		HandlerFactory: mux.CustomEndpointHandlerWithLogger(logger, mux.NewRequestBuilder(ParamsExtractor), server.DefaultToHTTPError),
 		ProxyFactory:   pf,
 		Logger:         logger,
 		DebugPattern:   "/__debug/{params}",
@@ -4,7 +4,9 @@ package mux
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/textproto"
@@ -12,8 +14,11 @@ import (
 
 	"github.com/luraproject/lura/v2/config"
 	"github.com/luraproject/lura/v2/core"
+	"github.com/luraproject/lura/v2/encoding"
+	"github.com/luraproject/lura/v2/logging"
 	"github.com/luraproject/lura/v2/proxy"
 	"github.com/luraproject/lura/v2/transport/http/server"
+	"github.com/luraproject/lura/v2/transport/http/server/websocket"
 )
 
 const requestParamsAsterisk string = "*"
@@ -32,6 +37,12 @@ func CustomEndpointHandler(rb RequestBuilder) HandlerFactory {
 
 // CustomEndpointHandlerWithHTTPError returns a HandlerFactory with the received RequestBuilder
 func CustomEndpointHandlerWithHTTPError(rb RequestBuilder, errF server.ToHTTPError) HandlerFactory {
+	return CustomEndpointHandlerWithLogger(logging.NoOp, rb, errF)
+}
+
+// CustomEndpointHandlerWithLogger returns a HandlerFactory with the received logger, RequestBuilder
+// and ToHTTPError function
+func CustomEndpointHandlerWithLogger(logger logging.Logger, rb RequestBuilder, errF server.ToHTTPError) HandlerFactory {
 	return func(configuration *config.EndpointConfig, prxy proxy.Proxy) http.HandlerFunc {
 		cacheControlHeaderValue := fmt.Sprintf("public, max-age=%d", int(configuration.CacheTTL.Seconds()))
 		isCacheEnabled := configuration.CacheTTL.Seconds() != 0
@@ -43,6 +54,31 @@ func CustomEndpointHandlerWithHTTPError(rb RequestBuilder, errF server.ToHTTPErr
 		}
 		method := strings.ToTitle(configuration.Method)
 
+		if tunnel, ok := websocket.New(logger, configuration); ok {
+			return func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set(core.KrakendHeaderName, core.KrakendHeaderValue)
+				if r.Method != method {
+					http.Error(w, "", http.StatusMethodNotAllowed)
+					return
+				}
+				tunnel(w, r, rb(r, configuration.QueryString, headersToSend))
+			}
+		}
+
+		newRequestContext := func(w http.ResponseWriter, r *http.Request) (context.Context, func() bool, context.CancelFunc) {
+			ctx, cancel := context.WithTimeout(r.Context(), configuration.Timeout)
+			return ctx, func() bool { return true }, cancel
+		}
+		if configuration.OutputEncoding == encoding.NOOP && server.IsStreamingEndpoint(configuration) {
+			// streaming endpoints are not affected by the service write timeout and their
+			// timeout only covers the reception of the backend response headers
+			render = streamingRender
+			newRequestContext = func(w http.ResponseWriter, r *http.Request) (context.Context, func() bool, context.CancelFunc) {
+				server.DisableWriteDeadline(w)
+				return server.NewStreamingContext(r.Context(), configuration.Timeout)
+			}
+		}
+
 		return func(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set(core.KrakendHeaderName, core.KrakendHeaderValue)
 			if r.Method != method {
@@ -51,9 +87,10 @@ func CustomEndpointHandlerWithHTTPError(rb RequestBuilder, errF server.ToHTTPErr
 				return
 			}
 
-			requestCtx, cancel := context.WithTimeout(r.Context(), configuration.Timeout)
+			requestCtx, stopTimeout, cancel := newRequestContext(w, r)
 
 			response, err := prxy(requestCtx, rb(r, configuration.QueryString, headersToSend))
+			stopTimeout()
 
 			select {
 			case <-requestCtx.Done():
@@ -63,7 +100,7 @@ func CustomEndpointHandlerWithHTTPError(rb RequestBuilder, errF server.ToHTTPErr
 			default:
 			}
 
-			if response != nil && len(response.Data) > 0 {
+			if response != nil && (len(response.Data) > 0 || response.Metadata.Mocked) {
 				if response.IsComplete {
 					w.Header().Set(server.CompleteResponseHeaderName, server.HeaderCompleteResponseValue)
 					if isCacheEnabled {
@@ -81,6 +118,15 @@ func CustomEndpointHandlerWithHTTPError(rb RequestBuilder, errF server.ToHTTPErr
 			} else {
 				w.Header().Set(server.CompleteResponseHeaderName, server.HeaderIncompleteResponseValue)
 				if err != nil {
+					var validationErr proxy.RequestValidationError
+					if errors.As(err, &validationErr) {
+						// the violations are always returned, so the clients can fix their requests
+						w.Header().Set("Content-Type", validationErr.Encoding())
+						w.WriteHeader(validationErr.StatusCode())
+						io.WriteString(w, validationErr.Error())
+						cancel()
+						return
+					}
 					if t, ok := err.(responseError); ok {
 						http.Error(w, err.Error(), t.StatusCode())
 					} else {
@@ -91,7 +137,11 @@ func CustomEndpointHandlerWithHTTPError(rb RequestBuilder, errF server.ToHTTPErr
 				}
 			}
 
-			render(w, response)
+			if response != nil && response.Metadata.Mocked {
+				mockedRender(w, response, render)
+			} else {
+				render(w, response)
+			}
 			cancel()
 		}
 	}
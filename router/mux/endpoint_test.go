@@ -3,20 +3,27 @@
 package mux
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/encoding"
+	"github.com/luraproject/lura/v2/jsonschema"
+	"github.com/luraproject/lura/v2/logging"
 	"github.com/luraproject/lura/v2/proxy"
 	"github.com/luraproject/lura/v2/transport/http/server"
+	"github.com/luraproject/lura/v2/transport/http/server/websocket"
 )
 
 func TestEndpointHandler_ok(t *testing.T) {
@@ -107,6 +114,25 @@ func TestEndpointHandler_ko(t *testing.T) {
 	time.Sleep(5 * time.Millisecond)
 }
 
+func TestEndpointHandler_requestValidationError(t *testing.T) {
+	p := func(_ context.Context, _ *proxy.Request) (*proxy.Response, error) {
+		return nil, proxy.RequestValidationError{Violations: []jsonschema.Violation{
+			{Path: "/query/page", Message: "expected string, got array"},
+		}}
+	}
+	endpointHandlerTestCase{
+		timeout:            10,
+		proxy:              p,
+		method:             "GET",
+		expectedBody:       `{"errors":[{"path":"/query/page","message":"expected string, got array"}]}`,
+		expectedCache:      "",
+		expectedContent:    "application/json",
+		expectedStatusCode: http.StatusBadRequest,
+		completed:          false,
+	}.test(t)
+	time.Sleep(5 * time.Millisecond)
+}
+
 func TestEndpointHandler_incompleteAndErrored(t *testing.T) {
 	p := func(_ context.Context, _ *proxy.Request) (*proxy.Response, error) {
 		return &proxy.Response{
@@ -180,6 +206,57 @@ func TestEndpointHandler_noop(t *testing.T) {
 	time.Sleep(5 * time.Millisecond)
 }
 
+func TestEndpointHandler_mocked(t *testing.T) {
+	p := func(_ context.Context, _ *proxy.Request) (*proxy.Response, error) {
+		return &proxy.Response{
+			IsComplete: true,
+			Data:       map[string]interface{}{"supu": "tupu"},
+			Metadata: proxy.Metadata{
+				Headers:    map[string][]string{"X-Mock": {"yes"}},
+				StatusCode: http.StatusCreated,
+				Mocked:     true,
+			},
+		}, nil
+	}
+	endpointHandlerTestCase{
+		timeout:            10,
+		proxy:              p,
+		method:             "GET",
+		expectedBody:       "{\"supu\":\"tupu\"}",
+		expectedCache:      "public, max-age=21600",
+		expectedContent:    "application/json",
+		expectedStatusCode: http.StatusCreated,
+		completed:          true,
+		expectedHeaders:    map[string][]string{"X-Mock": {"yes"}},
+	}.test(t)
+	time.Sleep(5 * time.Millisecond)
+}
+
+func TestEndpointHandler_mockedBody(t *testing.T) {
+	p := func(_ context.Context, _ *proxy.Request) (*proxy.Response, error) {
+		return &proxy.Response{
+			IsComplete: true,
+			Io:         strings.NewReader("mocked body"),
+			Metadata: proxy.Metadata{
+				Headers:    map[string][]string{"Content-Type": {"text/plain"}},
+				StatusCode: http.StatusAccepted,
+				Mocked:     true,
+			},
+		}, nil
+	}
+	endpointHandlerTestCase{
+		timeout:            10,
+		proxy:              p,
+		method:             "GET",
+		expectedBody:       "mocked body",
+		expectedCache:      "public, max-age=21600",
+		expectedContent:    "text/plain",
+		expectedStatusCode: http.StatusAccepted,
+		completed:          true,
+	}.test(t)
+	time.Sleep(5 * time.Millisecond)
+}
+
 func TestEndpointHandler_badMethod(t *testing.T) {
 	endpointHandlerTestCase{
 		timeout:            10,
@@ -301,3 +378,163 @@ func startMuxServer(handlerFunc http.HandlerFunc) *http.ServeMux {
 	router.Handle("/_mux_endpoint", handlerFunc)
 	return router
 }
+
+func TestEndpointHandler_streaming(t *testing.T) {
+	events := make(chan string)
+	canceled := make(chan struct{})
+
+	p := func(ctx context.Context, _ *proxy.Request) (*proxy.Response, error) {
+		pr, pw := io.Pipe()
+		go func() {
+			for {
+				select {
+				case e := <-events:
+					pw.Write([]byte(e))
+				case <-ctx.Done():
+					close(canceled)
+					pw.CloseWithError(ctx.Err())
+					return
+				}
+			}
+		}()
+		return &proxy.Response{
+			Metadata: proxy.Metadata{
+				StatusCode: http.StatusOK,
+				Headers:    map[string][]string{"Content-Type": {"text/event-stream"}},
+			},
+			Io: pr,
+		}, nil
+	}
+	endpoint := &config.EndpointConfig{
+		Method:         "GET",
+		Timeout:        50 * time.Millisecond,
+		OutputEncoding: encoding.NOOP,
+		ExtraConfig: config.ExtraConfig{
+			server.Namespace: map[string]interface{}{"streaming": true},
+		},
+	}
+
+	router := http.NewServeMux()
+	router.Handle("/_endpoint", EndpointHandler(endpoint, p))
+
+	s := httptest.NewUnstartedServer(router)
+	s.Config.WriteTimeout = 50 * time.Millisecond
+	s.Start()
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", s.URL+"/_endpoint", http.NoBody)
+	resp, err := (&http.Client{Timeout: 5 * time.Second}).Do(req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("unexpected content type: %s", ct)
+	}
+
+	r := bufio.NewReader(resp.Body)
+	for _, e := range []string{"data: first\n", "data: second\n"} {
+		// wait longer than the endpoint timeout and the server write timeout
+		<-time.After(100 * time.Millisecond)
+		events <- e
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Errorf("reading the stream: %v", err)
+			return
+		}
+		if line != e {
+			t.Errorf("unexpected event: %q", line)
+		}
+	}
+
+	cancel()
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("the client disconnection was not propagated to the backend")
+	}
+}
+
+func TestEndpointHandler_websocket(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rooms/lobby" || r.Header.Get("Sec-Websocket-Key") != "key" {
+			http.Error(w, "", http.StatusBadRequest)
+			return
+		}
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		rw.Flush()
+		io.Copy(conn, rw)
+	}))
+	defer backend.Close()
+
+	endpoint := &config.EndpointConfig{
+		Method:   "GET",
+		Endpoint: "/ws/lobby",
+		Timeout:  time.Second,
+		Backend: []*config.Backend{
+			{Host: []string{backend.URL}, URLPattern: "/rooms/{{.Room}}"},
+		},
+		ExtraConfig: config.ExtraConfig{websocket.Namespace: map[string]interface{}{}},
+	}
+
+	rb := NewRequestBuilder(func(_ *http.Request) map[string]string {
+		return map[string]string{"Room": "lobby"}
+	})
+	buf := new(bytes.Buffer)
+	logger, err := logging.NewLogger("DEBUG", buf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := httptest.NewServer(CustomEndpointHandlerWithLogger(logger, rb, server.DefaultToHTTPError)(endpoint, proxy.NoopProxy))
+	defer s.Close()
+	if !strings.Contains(buf.String(), "Tunneling the connections to /rooms/{{.Room}}") {
+		t.Errorf("the injected logger has not been used: %s", buf.String())
+	}
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(s.URL, "http://"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Second))
+
+	req, _ := http.NewRequest("GET", s.URL+"/ws/lobby", http.NoBody)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Key", "key")
+	req.Write(conn)
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("unexpected status code: %d", resp.StatusCode)
+		return
+	}
+
+	conn.Write([]byte("hello"))
+	echo := make([]byte, 5)
+	if _, err := io.ReadFull(br, echo); err != nil {
+		t.Error(err)
+		return
+	}
+	if string(echo) != "hello" {
+		t.Errorf("unexpected echo: %s", echo)
+	}
+}
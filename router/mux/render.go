@@ -3,14 +3,17 @@
 package mux
 
 import (
+	"bytes"
 	"encoding/json"
 	"io"
 	"net/http"
 	"sync"
 
+	"github.com/goccy/go-yaml"
 	"github.com/luraproject/lura/v2/config"
 	"github.com/luraproject/lura/v2/encoding"
 	"github.com/luraproject/lura/v2/proxy"
+	"github.com/luraproject/lura/v2/transport/http/server"
 )
 
 // Render defines the signature of the functions to be use for the final response
@@ -27,6 +30,10 @@ var (
 		encoding.JSON:     jsonRender,
 		encoding.NOOP:     noopRender,
 		"json-collection": jsonCollectionRender,
+		encoding.YAML:     yamlRender,
+		encoding.CSV:      csvRender,
+		encoding.FORM:     formRender,
+		encoding.MSGPACK:  msgpackRender,
 	}
 )
 
@@ -119,10 +126,91 @@ func stringRender(w http.ResponseWriter, response *proxy.Response) {
 	w.Write([]byte(msg))
 }
 
+func yamlRender(w http.ResponseWriter, response *proxy.Response) {
+	data := map[string]interface{}{}
+	if response != nil {
+		data = response.Data
+	}
+	b, err := yaml.Marshal(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(b)
+}
+
+func csvRender(w http.ResponseWriter, response *proxy.Response) {
+	buf := &bytes.Buffer{}
+	if err := encoding.EncodeCSV(buf, csvRows(response)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// csvRows returns the items of the collection or the response data as a single row
+func csvRows(response *proxy.Response) []interface{} {
+	if response == nil {
+		return []interface{}{}
+	}
+	if col, ok := response.Data["collection"].([]interface{}); ok {
+		return col
+	}
+	return []interface{}{response.Data}
+}
+
+func formRender(w http.ResponseWriter, response *proxy.Response) {
+	var data map[string]interface{}
+	if response != nil {
+		data = response.Data
+	}
+	form, err := encoding.EncodeForm(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-www-form-urlencoded")
+	w.Write([]byte(form))
+}
+
+func msgpackRender(w http.ResponseWriter, response *proxy.Response) {
+	data := map[string]interface{}{}
+	if response != nil {
+		data = response.Data
+	}
+	buf := &bytes.Buffer{}
+	if err := encoding.EncodeMsgpack(buf, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/msgpack")
+	w.Write(buf.Bytes())
+}
+
 func noopRender(w http.ResponseWriter, response *proxy.Response) {
+	if !writeNoopHeaders(w, response) {
+		return
+	}
+	io.Copy(w, response.Io)
+}
+
+// streamingRender is the render used by the no-op endpoints with the streaming mode enabled.
+// It flushes the response as the data arrives from the backend.
+func streamingRender(w http.ResponseWriter, response *proxy.Response) {
+	if !writeNoopHeaders(w, response) {
+		return
+	}
+	server.StreamResponse(w, response.Io)
+}
+
+// writeNoopHeaders writes the status code and the headers of the response and returns true
+// if there is a body to copy
+func writeNoopHeaders(w http.ResponseWriter, response *proxy.Response) bool {
 	if response == nil {
 		http.Error(w, "", http.StatusInternalServerError)
-		return
+		return false
 	}
 
 	for k, vs := range response.Metadata.Headers {
@@ -134,8 +222,43 @@ func noopRender(w http.ResponseWriter, response *proxy.Response) {
 		w.WriteHeader(response.Metadata.StatusCode)
 	}
 
-	if response.Io == nil {
+	return response.Io != nil
+}
+
+// mockedRender renders the mocked responses with their status code. The mocked bodies are copied
+// as they are, while the mocked data is rendered with the render of the endpoint.
+func mockedRender(w http.ResponseWriter, response *proxy.Response, render Render) {
+	if response.Io != nil {
+		if response.Metadata.StatusCode != 0 {
+			w.WriteHeader(response.Metadata.StatusCode)
+		}
+		io.Copy(w, response.Io)
 		return
 	}
-	io.Copy(w, response.Io)
+	if response.Metadata.StatusCode == 0 {
+		render(w, response)
+		return
+	}
+	render(&statusResponseWriter{ResponseWriter: w, status: response.Metadata.StatusCode}, response)
+}
+
+// statusResponseWriter writes the status code just before the first write, so the renders can
+// still set their headers
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusResponseWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(w.status)
+	return w.ResponseWriter.Write(b)
 }
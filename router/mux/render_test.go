@@ -365,3 +365,60 @@ func TestRender_noop_nilResponse(t *testing.T) {
 		t.Error("Unexpected status code:", w.Result().StatusCode)
 	}
 }
+
+func TestRender_wireFormats(t *testing.T) {
+	p := func(_ context.Context, _ *proxy.Request) (*proxy.Response, error) {
+		return &proxy.Response{
+			IsComplete: true,
+			Data: map[string]interface{}{"collection": []interface{}{
+				map[string]interface{}{"id": "1", "name": "a"},
+				map[string]interface{}{"id": "2", "name": "b"},
+			}},
+		}, nil
+	}
+
+	for _, tc := range []struct {
+		encoding string
+		header   string
+		decoder  func(bool) func(io.Reader, *map[string]interface{}) error
+		body     string
+	}{
+		{encoding: encoding.CSV, header: "text/csv; charset=utf-8", body: "id,name\n1,a\n2,b\n"},
+		{encoding: encoding.FORM, header: "application/x-www-form-urlencoded", body: "collection=%7B%22id%22%3A%221%22%2C%22name%22%3A%22a%22%7D&collection=%7B%22id%22%3A%222%22%2C%22name%22%3A%22b%22%7D"},
+		{encoding: encoding.MSGPACK, header: "application/msgpack", decoder: encoding.NewMsgpackDecoder},
+		{encoding: encoding.YAML, header: "application/yaml", decoder: encoding.NewYAMLDecoder},
+	} {
+		endpoint := &config.EndpointConfig{
+			Timeout:        time.Second,
+			OutputEncoding: tc.encoding,
+			Method:         "GET",
+		}
+		router := http.NewServeMux()
+		router.Handle("/_mux_endpoint", EndpointHandler(endpoint, p))
+
+		req, _ := http.NewRequest("GET", "http://127.0.0.1:8080/_mux_endpoint", http.NoBody)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: unexpected status code: %d", tc.encoding, w.Code)
+		}
+		if h := w.Header().Get("Content-Type"); h != tc.header {
+			t.Errorf("%s: unexpected content type: %s", tc.encoding, h)
+		}
+		if tc.decoder == nil {
+			if w.Body.String() != tc.body {
+				t.Errorf("%s: unexpected body: %q", tc.encoding, w.Body.String())
+			}
+			continue
+		}
+		var data map[string]interface{}
+		if err := tc.decoder(false)(w.Body, &data); err != nil {
+			t.Errorf("%s: %v", tc.encoding, err)
+			continue
+		}
+		if col, ok := data["collection"].([]interface{}); !ok || len(col) != 2 {
+			t.Errorf("%s: unexpected data: %v", tc.encoding, data)
+		}
+	}
+}
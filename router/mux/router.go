@@ -50,7 +50,7 @@ func DefaultFactory(pf proxy.Factory, logger logging.Logger) router.Factory {
 		Config{
 			Engine:         DefaultEngine(),
 			Middlewares:    []HandlerMiddleware{},
-			HandlerFactory: EndpointHandler,
+			HandlerFactory: CustomEndpointHandlerWithLogger(logger, NewRequest, server.DefaultToHTTPError),
 			ProxyFactory:   pf,
 			Logger:         logger,
 			DebugPattern:   DefaultDebugPattern,
@@ -134,7 +134,7 @@ func (r httpRouter) Run(cfg config.ServiceConfig) {
 
 	server.InitHTTPDefaultTransport(cfg)
 
-	r.registerKrakendEndpoints(cfg.Endpoints)
+	r.registerKrakendEndpoints(cfg)
 
 	if err := r.RunServer(r.ctx, cfg, r.handler()); err != nil {
 		r.cfg.Logger.Error(logPrefix, err.Error())
@@ -143,15 +143,19 @@ func (r httpRouter) Run(cfg config.ServiceConfig) {
 	r.cfg.Logger.Info(logPrefix, "Router execution ended")
 }
 
-func (r httpRouter) registerKrakendEndpoints(endpoints []*config.EndpointConfig) {
-	for _, c := range endpoints {
+func (r httpRouter) registerKrakendEndpoints(cfg config.ServiceConfig) {
+	for _, c := range cfg.Endpoints {
 		proxyStack, err := r.cfg.ProxyFactory.New(c)
 		if err != nil {
 			r.cfg.Logger.Error(logPrefix, "Calling the ProxyFactory", err.Error())
 			continue
 		}
 
-		r.registerKrakendEndpoint(c.Method, c, r.cfg.HandlerFactory(c, proxyStack), len(c.Backend))
+		h := r.cfg.HandlerFactory(c, proxyStack)
+		if compression, ok := server.NewCompressionConfig(cfg.ExtraConfig, c.ExtraConfig); ok {
+			h = server.NewCompressionHandler(compression, h).ServeHTTP
+		}
+		r.registerKrakendEndpoint(c.Method, c, h, len(c.Backend))
 	}
 }
 
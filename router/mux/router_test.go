@@ -17,6 +17,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/andybalholm/brotli"
+
 	"github.com/luraproject/lura/v2/config"
 	"github.com/luraproject/lura/v2/logging"
 	"github.com/luraproject/lura/v2/proxy"
@@ -135,6 +137,79 @@ func TestDefaultFactory_ok(t *testing.T) {
 	}
 }
 
+func TestDefaultFactory_compression(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		time.Sleep(5 * time.Millisecond)
+	}()
+
+	content := strings.Repeat("tupu ", 300)
+	r := DefaultFactory(noopProxyFactory(map[string]interface{}{"supu": content}), logging.NoOp).NewWithContext(ctx)
+	expectedBody := `{"supu":"` + content + `"}`
+
+	serviceCfg := config.ServiceConfig{
+		Port: 8065,
+		ExtraConfig: config.ExtraConfig{
+			server.CompressionNamespace: map[string]interface{}{"encodings": []interface{}{"br"}},
+		},
+		Endpoints: []*config.EndpointConfig{
+			{
+				Endpoint: "/compressed",
+				Method:   "GET",
+				Timeout:  10,
+				Backend:  []*config.Backend{{}},
+			},
+			{
+				Endpoint: "/plain",
+				Method:   "GET",
+				Timeout:  10,
+				Backend:  []*config.Backend{{}},
+				ExtraConfig: config.ExtraConfig{
+					server.CompressionNamespace: map[string]interface{}{"disabled": true},
+				},
+			},
+		},
+	}
+
+	go func() { r.Run(serviceCfg) }()
+
+	time.Sleep(5 * time.Millisecond)
+
+	for _, tc := range []struct {
+		path     string
+		encoding string
+	}{
+		{path: "/compressed", encoding: "br"},
+		{path: "/plain"},
+	} {
+		req, _ := http.NewRequest("GET", "http://127.0.0.1:8065"+tc.path, http.NoBody)
+		req.Header.Set("Accept-Encoding", "gzip, br")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Error("Making the request:", err.Error())
+			return
+		}
+		defer resp.Body.Close()
+
+		if ce := resp.Header.Get("Content-Encoding"); ce != tc.encoding {
+			t.Error(tc.path, "Unexpected content encoding:", ce)
+		}
+		var body io.Reader = resp.Body
+		if tc.encoding == "br" {
+			body = brotli.NewReader(resp.Body)
+		}
+		content, ioerr := io.ReadAll(body)
+		if ioerr != nil {
+			t.Error("Reading the response:", ioerr.Error())
+			return
+		}
+		if string(content) != expectedBody {
+			t.Error(tc.path, "Unexpected body:", string(content))
+		}
+	}
+}
+
 func TestDefaultFactory_ko(t *testing.T) {
 	buff := bytes.NewBuffer(make([]byte, 1024))
 	logger, err := logging.NewLogger("ERROR", buff, "pref")
@@ -3,6 +3,7 @@
 package sd
 
 import (
+	"github.com/luraproject/lura/v2/config"
 	"github.com/luraproject/lura/v2/register"
 )
 
@@ -27,9 +28,10 @@ func initRegister() *Register {
 }
 
 // Register adds the SubscriberFactory to the internal register under the given
-// name
+// name and declares the name as a known driver for the config validation
 func (r *Register) Register(name string, sf SubscriberFactory) error {
 	r.data.Register(name, sf)
+	config.RegisterSDDriver(name)
 	return nil
 }
 
@@ -0,0 +1,53 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package test
+
+import (
+	"testing"
+
+	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/encoding"
+	"github.com/luraproject/lura/v2/proxy"
+	proxyplugin "github.com/luraproject/lura/v2/proxy/plugin"
+	"github.com/luraproject/lura/v2/router/gin"
+	"github.com/luraproject/lura/v2/transport/grpc"
+	"github.com/luraproject/lura/v2/transport/http/client"
+	"github.com/luraproject/lura/v2/transport/http/client/graphql"
+	clientplugin "github.com/luraproject/lura/v2/transport/http/client/plugin"
+	"github.com/luraproject/lura/v2/transport/http/server"
+	serverplugin "github.com/luraproject/lura/v2/transport/http/server/plugin"
+	"github.com/luraproject/lura/v2/transport/http/server/websocket"
+)
+
+// TestNamespaces checks that the extra config namespaces read by the lura components are
+// registered, so the strict parsers accept them
+func TestNamespaces(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		scope config.ExtraConfigScope
+	}{
+		{name: proxy.Namespace, scope: config.EndpointScope | config.BackendScope},
+		{name: proxyplugin.Namespace, scope: config.EndpointScope | config.BackendScope},
+		{name: encoding.Namespace, scope: config.BackendScope},
+		// the options of the http client used by the proxy package
+		{name: "backend/http/client", scope: config.BackendScope},
+		{name: gin.Namespace, scope: config.ServiceScope},
+		{name: grpc.Namespace, scope: config.BackendScope},
+		{name: client.Namespace, scope: config.BackendScope},
+		{name: graphql.Namespace, scope: config.BackendScope},
+		{name: clientplugin.Namespace, scope: config.BackendScope},
+		{name: server.Namespace, scope: config.EndpointScope},
+		{name: server.CompressionNamespace, scope: config.ServiceScope | config.EndpointScope},
+		{name: serverplugin.Namespace, scope: config.ServiceScope},
+		{name: websocket.Namespace, scope: config.EndpointScope},
+	} {
+		ns, ok := config.LookupNamespace(tc.name)
+		if !ok {
+			t.Errorf("the namespace %s is not registered", tc.name)
+			continue
+		}
+		if ns.Scope&tc.scope != tc.scope {
+			t.Errorf("the namespace %s is not supported at the levels %s (supported at: %s)", tc.name, tc.scope, ns.Scope)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package grpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http2"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/encoding"
+)
+
+const (
+	contentType       = "application/grpc"
+	messageHeaderSize = 5
+)
+
+// reservedHeaders are the headers managed by the protocol, so they are not forwarded as metadata
+// to the gRPC backends nor returned as metadata of the replies
+var reservedHeaders = map[string]struct{}{
+	"Accept-Encoding":   {},
+	"Connection":        {},
+	"Content-Length":    {},
+	"Content-Type":      {},
+	"Host":              {},
+	"Keep-Alive":        {},
+	"Te":                {},
+	"Trailer":           {},
+	"Transfer-Encoding": {},
+	"Upgrade":           {},
+}
+
+// Client calls unary gRPC methods over HTTP/2. The backends with the http or grpc schemes are
+// reached in plain text (h2c) and the ones with the https or grpcs schemes over TLS.
+type Client struct {
+	plain  http.RoundTripper
+	secure http.RoundTripper
+}
+
+// NewClient returns a Client using the received TLS config for the secure connections. If the
+// config is nil, the TLS config of the http.DefaultTransport is used.
+func NewClient(tlsConfig *tls.Config) *Client {
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+		if t, ok := http.DefaultTransport.(*http.Transport); ok && t.TLSClientConfig != nil {
+			tlsConfig = t.TLSClientConfig.Clone()
+		}
+	}
+	return &Client{
+		plain: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, network, addr)
+			},
+		},
+		secure: &http2.Transport{TLSClientConfig: tlsConfig},
+	}
+}
+
+// Invoke calls the method on the host of the target URL and returns the reply and the metadata
+// of the response. The headers are forwarded to the backend as metadata. The headers managed by
+// the protocol (Content-Type, Grpc-*, Te...) are not forwarded nor returned. When the call does
+// not end with an OK status, the returned error is a ResponseError.
+func (c *Client) Invoke(ctx context.Context, target *url.URL, md protoreflect.MethodDescriptor, in proto.Message, headers map[string][]string) (*dynamicpb.Message, http.Header, error) {
+	return c.InvokeWithLimits(ctx, target, md, in, headers, nil)
+}
+
+// InvokeWithLimits is like Invoke but bounds the size of the response body and the size of the
+// decompressed reply with the received limits. The replies exceeding them fail with an
+// *encoding.LimitError.
+func (c *Client) InvokeWithLimits(ctx context.Context, target *url.URL, md protoreflect.MethodDescriptor, in proto.Message, headers map[string][]string, limits *config.ResponseLimits) (*dynamicpb.Message, http.Header, error) {
+	var l config.ResponseLimits
+	if limits != nil {
+		l = *limits
+	}
+	if target == nil {
+		return nil, nil, errors.New("grpc: no target defined")
+	}
+	var transport http.RoundTripper
+	scheme := "http"
+	switch target.Scheme {
+	case "http", "grpc", "h2c":
+		transport = c.plain
+	case "https", "grpcs":
+		transport = c.secure
+		scheme = "https"
+	default:
+		return nil, nil, fmt.Errorf("grpc: unsupported scheme %q", target.Scheme)
+	}
+
+	payload, err := proto.Marshal(in)
+	if err != nil {
+		return nil, nil, err
+	}
+	body := make([]byte, messageHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(body[1:messageHeaderSize], uint32(len(payload)))
+	copy(body[messageHeaderSize:], payload)
+
+	u := &url.URL{Scheme: scheme, Host: target.Host, Path: methodPath(md)}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, vs := range headers {
+		k = textproto.CanonicalMIMEHeaderKey(k)
+		if _, ok := reservedHeaders[k]; ok || strings.HasPrefix(k, "Grpc-") {
+			continue
+		}
+		req.Header[k] = vs
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Te", "trailers")
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Header.Set("Grpc-Timeout", encodeTimeout(time.Until(deadline)))
+	}
+
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return nil, nil, NewResponseError(DeadlineExceeded, err.Error())
+		case context.Canceled:
+			return nil, nil, NewResponseError(Canceled, err.Error())
+		}
+		return nil, nil, NewResponseError(Unavailable, err.Error())
+	}
+	defer resp.Body.Close()
+
+	metadata := responseMetadata(resp.Header)
+	if resp.StatusCode != http.StatusOK {
+		return nil, metadata, NewResponseError(codeFromHTTPStatus(resp.StatusCode), resp.Status)
+	}
+
+	b, err := readBody(resp, l.MaxBodySize)
+	if err != nil {
+		var limitErr *encoding.LimitError
+		if errors.As(err, &limitErr) {
+			return nil, metadata, err
+		}
+		return nil, metadata, NewResponseError(Internal, err.Error())
+	}
+
+	// trailers-only responses send the status in the headers
+	status := resp.Trailer.Get("Grpc-Status")
+	msg := resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status = resp.Header.Get("Grpc-Status")
+		msg = resp.Header.Get("Grpc-Message")
+	}
+	code, err := strconv.ParseUint(status, 10, 32)
+	if err != nil {
+		return nil, metadata, NewResponseError(Internal, "missing or invalid grpc-status")
+	}
+	if Code(code) != OK {
+		if m, err := url.PathUnescape(msg); err == nil {
+			msg = m
+		}
+		return nil, metadata, NewResponseError(Code(code), msg)
+	}
+
+	payload, err = decodeMessage(b, resp.Header.Get("Grpc-Encoding"), l.MaxDecompressedSize)
+	if err != nil {
+		var limitErr *encoding.LimitError
+		if errors.As(err, &limitErr) {
+			return nil, metadata, err
+		}
+		return nil, metadata, NewResponseError(Internal, err.Error())
+	}
+	out := dynamicpb.NewMessage(md.Output())
+	if err := proto.Unmarshal(payload, out); err != nil {
+		return nil, metadata, NewResponseError(Internal, err.Error())
+	}
+	return out, metadata, nil
+}
+
+// responseMetadata returns the headers of the response without the ones managed by the protocol
+func responseMetadata(h http.Header) http.Header {
+	res := make(http.Header, len(h))
+	for k, vs := range h {
+		k = textproto.CanonicalMIMEHeaderKey(k)
+		if _, ok := reservedHeaders[k]; ok || strings.HasPrefix(k, "Grpc-") {
+			continue
+		}
+		res[k] = vs
+	}
+	return res
+}
+
+// readBody reads the body of the response, failing with an *encoding.LimitError if it has more
+// than max bytes. Zero means no limit.
+func readBody(resp *http.Response, max int64) ([]byte, error) {
+	if max <= 0 {
+		return io.ReadAll(resp.Body)
+	}
+	if resp.ContentLength > max {
+		return nil, &encoding.LimitError{Limit: encoding.LimitBodySize, Max: max}
+	}
+	r := encoding.NewLimitedReader(resp.Body, encoding.LimitBodySize, max)
+	b, err := io.ReadAll(r)
+	return b, r.Err(err)
+}
+
+// decodeMessage extracts the payload of the first length-prefixed message of the body. The
+// compressed payloads are bounded by maxDecompressed bytes, if it is not zero.
+func decodeMessage(b []byte, messageEncoding string, maxDecompressed int64) ([]byte, error) {
+	if len(b) < messageHeaderSize {
+		return nil, errors.New("grpc: empty response")
+	}
+	size := binary.BigEndian.Uint32(b[1:messageHeaderSize])
+	if uint64(len(b)-messageHeaderSize) < uint64(size) {
+		return nil, errors.New("grpc: truncated response")
+	}
+	payload := b[messageHeaderSize : messageHeaderSize+int(size)]
+	if b[0] == 0 {
+		return payload, nil
+	}
+	if messageEncoding != "gzip" {
+		return nil, fmt.Errorf("grpc: unsupported message encoding %q", messageEncoding)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	if maxDecompressed <= 0 {
+		return io.ReadAll(r)
+	}
+	l := encoding.NewLimitedReader(r, encoding.LimitDecompressedSize, maxDecompressed)
+	payload, err = io.ReadAll(l)
+	return payload, l.Err(err)
+}
+
+// encodeTimeout formats the timeout as defined by the gRPC over HTTP/2 spec (at most 8 digits)
+func encodeTimeout(d time.Duration) string {
+	if d <= 0 {
+		return "1n"
+	}
+	for _, unit := range []struct {
+		d      time.Duration
+		suffix string
+	}{
+		{time.Nanosecond, "n"},
+		{time.Microsecond, "u"},
+		{time.Millisecond, "m"},
+		{time.Second, "S"},
+		{time.Minute, "M"},
+		{time.Hour, "H"},
+	} {
+		if v := d / unit.d; v < 99999999 {
+			if d%unit.d != 0 {
+				// round up so the backend never gets a shorter deadline than the gateway
+				v++
+			}
+			return strconv.FormatInt(int64(v), 10) + unit.suffix
+		}
+	}
+	return "99999999H"
+}
@@ -0,0 +1,297 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package grpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/encoding"
+)
+
+func TestClient_Invoke(t *testing.T) {
+	md, err := LoadMethod(writeDescriptorSet(t), "test.Greeter/SayHello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newGreeterServer(md, false)
+	defer s.Close()
+
+	in, _ := NewRequestMessage(md, nil, map[string]string{"Name": "lura"}, url.Values{"age": {"3"}, "tags": {"a", "b"}})
+	target, _ := url.Parse(s.URL + "/ignored")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	out, headers, err := NewClient(nil).Invoke(ctx, target, md, in, map[string][]string{
+		"X-Custom":       {"custom"},
+		"Content-Length": {"1000"},
+		"Grpc-Status":    {"5"},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if h := headers.Get("X-Backend"); h != "greeter" {
+		t.Errorf("unexpected header: %s", h)
+	}
+	for k := range headers {
+		if k == "Content-Type" || k == "Trailer" || strings.HasPrefix(k, "Grpc-") {
+			t.Errorf("unexpected protocol header: %s", k)
+		}
+	}
+
+	data, err := MessageToData(out, Options{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if data["message"] != "hello lura" || data["count"] != "3" || len(data["tags"].([]interface{})) != 2 || data["userId"] != "custom" {
+		t.Errorf("unexpected data: %v", data)
+	}
+}
+
+func TestClient_Invoke_gzip(t *testing.T) {
+	md, err := LoadMethod(writeDescriptorSet(t), "test.Greeter/SayHello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newGreeterServer(md, true)
+	defer s.Close()
+
+	in, _ := NewRequestMessage(md, nil, map[string]string{"Name": "lura"}, nil)
+	target, _ := url.Parse("grpc://" + s.Listener.Addr().String())
+
+	out, _, err := NewClient(nil).Invoke(context.Background(), target, md, in, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if m := out.Get(md.Output().Fields().ByName("message")).String(); m != "hello lura" {
+		t.Errorf("unexpected message: %s", m)
+	}
+}
+
+func TestClient_InvokeWithLimits(t *testing.T) {
+	md, err := LoadMethod(writeDescriptorSet(t), "test.Greeter/SayHello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newGreeterServer(md, true)
+	defer s.Close()
+
+	in, _ := NewRequestMessage(md, nil, map[string]string{"Name": "lura"}, nil)
+	target, _ := url.Parse("grpc://" + s.Listener.Addr().String())
+
+	for _, tc := range []struct {
+		limits config.ResponseLimits
+		limit  string
+	}{
+		{limits: config.ResponseLimits{MaxBodySize: 4}, limit: encoding.LimitBodySize},
+		{limits: config.ResponseLimits{MaxDecompressedSize: 4}, limit: encoding.LimitDecompressedSize},
+	} {
+		_, _, err := NewClient(nil).InvokeWithLimits(context.Background(), target, md, in, nil, &tc.limits)
+		var limitErr *encoding.LimitError
+		if !errors.As(err, &limitErr) || limitErr.Limit != tc.limit {
+			t.Errorf("%s: unexpected error: %v", tc.limit, err)
+		}
+	}
+
+	limits := &config.ResponseLimits{MaxBodySize: 1024, MaxDecompressedSize: 1024}
+	out, _, err := NewClient(nil).InvokeWithLimits(context.Background(), target, md, in, nil, limits)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if m := out.Get(md.Output().Fields().ByName("message")).String(); m != "hello lura" {
+		t.Errorf("unexpected message: %s", m)
+	}
+}
+
+func TestClient_Invoke_statusError(t *testing.T) {
+	md, err := LoadMethod(writeDescriptorSet(t), "test.Greeter/SayHello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newGreeterServer(md, false)
+	defer s.Close()
+
+	for _, tc := range []struct {
+		name    string
+		code    Code
+		status  int
+		message string
+	}{
+		{name: "missing", code: NotFound, status: http.StatusNotFound, message: "user not found"},
+		{name: "denied", code: PermissionDenied, status: http.StatusForbidden, message: "nope"},
+		{name: "http", code: Unimplemented, status: http.StatusNotImplemented, message: "404 Not Found"},
+	} {
+		in, _ := NewRequestMessage(md, nil, map[string]string{"Name": tc.name}, nil)
+		target, _ := url.Parse(s.URL)
+		_, _, err := NewClient(nil).Invoke(context.Background(), target, md, in, nil)
+		re, ok := err.(ResponseError)
+		if !ok {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if re.Code != tc.code || re.StatusCode() != tc.status || re.Message != tc.message {
+			t.Errorf("%s: unexpected error: %+v", tc.name, re)
+		}
+		if re.Encoding() != "application/json" {
+			t.Errorf("%s: unexpected encoding: %s", tc.name, re.Encoding())
+		}
+	}
+}
+
+func TestClient_Invoke_unavailable(t *testing.T) {
+	md, err := LoadMethod(writeDescriptorSet(t), "test.Greeter/SayHello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	in, _ := NewRequestMessage(md, nil, nil, nil)
+
+	target, _ := url.Parse("http://127.0.0.1:1")
+	_, _, err = NewClient(nil).Invoke(context.Background(), target, md, in, nil)
+	if re, ok := err.(ResponseError); !ok || re.Code != Unavailable {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	target, _ = url.Parse("ftp://127.0.0.1:1")
+	if _, _, err = NewClient(nil).Invoke(context.Background(), target, md, in, nil); err == nil {
+		t.Error("error expected")
+	}
+}
+
+func TestHTTPStatusFromCode(t *testing.T) {
+	for code, status := range map[Code]int{
+		OK:                 http.StatusOK,
+		Canceled:           499,
+		Unknown:            http.StatusInternalServerError,
+		InvalidArgument:    http.StatusBadRequest,
+		DeadlineExceeded:   http.StatusGatewayTimeout,
+		NotFound:           http.StatusNotFound,
+		AlreadyExists:      http.StatusConflict,
+		PermissionDenied:   http.StatusForbidden,
+		ResourceExhausted:  http.StatusTooManyRequests,
+		FailedPrecondition: http.StatusBadRequest,
+		Aborted:            http.StatusConflict,
+		OutOfRange:         http.StatusBadRequest,
+		Unimplemented:      http.StatusNotImplemented,
+		Internal:           http.StatusInternalServerError,
+		Unavailable:        http.StatusServiceUnavailable,
+		DataLoss:           http.StatusInternalServerError,
+		Unauthenticated:    http.StatusUnauthorized,
+	} {
+		if s := HTTPStatusFromCode(code); s != status {
+			t.Errorf("%d: unexpected status %d", code, s)
+		}
+	}
+}
+
+func TestEncodeTimeout(t *testing.T) {
+	for d, expected := range map[time.Duration]string{
+		-time.Second:                  "1n",
+		500 * time.Nanosecond:         "500n",
+		2 * time.Second:               "2000000u",
+		200 * time.Second:             "200000m",
+		200000 * time.Second:          "200000S",
+		time.Second + time.Nanosecond: "1000001u",
+	} {
+		if res := encodeTimeout(d); res != expected {
+			t.Errorf("%s: unexpected timeout %s", d, res)
+		}
+	}
+}
+
+// newGreeterServer returns a h2c server implementing the test.Greeter/SayHello method. The
+// reply depends on the name of the request:
+//   - missing: NotFound status in a trailers-only response
+//   - denied: PermissionDenied status in the trailers
+//   - http: 404 HTTP status
+func newGreeterServer(md protoreflect.MethodDescriptor, compress bool) *httptest.Server {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 || r.Method != http.MethodPost || r.URL.Path != "/test.Greeter/SayHello" ||
+			r.Header.Get("Content-Type") != "application/grpc" || r.Header.Get("Te") != "trailers" ||
+			r.Header.Get("Grpc-Status") != "" || r.Header.Get("Content-Length") == "1000" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		b, _ := io.ReadAll(r.Body)
+		in := dynamicpb.NewMessage(md.Input())
+		if len(b) < 5 || proto.Unmarshal(b[5:], in) != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		fields := md.Input().Fields()
+		name := in.Get(fields.ByName("name")).String()
+
+		switch name {
+		case "missing":
+			w.Header().Set("Content-Type", "application/grpc")
+			w.Header().Set("Grpc-Status", "5")
+			w.Header().Set("Grpc-Message", "user%20not%20found")
+			return
+		case "http":
+			http.Error(w, "", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("X-Backend", "greeter")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+
+		if name == "denied" {
+			w.WriteHeader(http.StatusOK)
+			w.Header().Set("Grpc-Status", "7")
+			w.Header().Set("Grpc-Message", "nope")
+			return
+		}
+
+		outFields := md.Output().Fields()
+		out := dynamicpb.NewMessage(md.Output())
+		out.Set(outFields.ByName("message"), protoreflect.ValueOfString("hello "+name))
+		out.Set(outFields.ByName("count"), protoreflect.ValueOfInt64(in.Get(fields.ByName("age")).Int()))
+		out.Set(outFields.ByName("user_id"), protoreflect.ValueOfString(r.Header.Get("X-Custom")))
+		tags := out.Mutable(outFields.ByName("tags")).List()
+		inTags := in.Get(fields.ByName("tags")).List()
+		for i := 0; i < inTags.Len(); i++ {
+			tags.Append(inTags.Get(i))
+		}
+		payload, _ := proto.Marshal(out)
+
+		flag := byte(0)
+		if compress {
+			buf := &bytes.Buffer{}
+			zw := gzip.NewWriter(buf)
+			zw.Write(payload)
+			zw.Close()
+			payload = buf.Bytes()
+			flag = 1
+			w.Header().Set("Grpc-Encoding", "gzip")
+		}
+		frame := make([]byte, 5, 5+len(payload))
+		frame[0] = flag
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(payload)))
+		w.Write(append(frame, payload...))
+
+		w.Header().Set("Grpc-Status", "0")
+	})
+	return httptest.NewServer(h2c.NewHandler(h, &http2.Server{}))
+}
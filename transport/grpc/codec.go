@@ -0,0 +1,184 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package grpc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// NewRequestMessage builds the input message of the method. The JSON body (if any) is decoded
+// first, then the query strings and finally the params are set, so the params have the highest
+// priority. The query strings and the params are matched with the fields by their proto or JSON
+// name (case insensitive) and they can reach nested messages using dots (inner.id). Unknown
+// names are ignored and repeated fields accept several query string values.
+func NewRequestMessage(md protoreflect.MethodDescriptor, body io.Reader, params map[string]string, query url.Values) (*dynamicpb.Message, error) {
+	m := dynamicpb.NewMessage(md.Input())
+
+	if body != nil {
+		b, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(b)) > 0 {
+			if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(b, m); err != nil {
+				return nil, fmt.Errorf("decoding the body: %w", err)
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := setField(m, k, query[k]); err != nil {
+			return nil, err
+		}
+	}
+
+	keys = keys[:0]
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := setField(m, k, []string{params[k]}); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// MessageToData returns the JSON representation of the message as a map
+func MessageToData(m protoreflect.ProtoMessage, opt Options) (map[string]interface{}, error) {
+	b, err := protojson.MarshalOptions{
+		UseProtoNames:   opt.UseProtoNames,
+		EmitUnpopulated: opt.EmitUnpopulated,
+	}.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]interface{}{}
+	if err := json.Unmarshal(b, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func setField(m protoreflect.Message, path string, values []string) error {
+	if len(values) == 0 {
+		return nil
+	}
+	parts := strings.Split(path, ".")
+	for _, part := range parts[:len(parts)-1] {
+		fd := findField(m.Descriptor(), part)
+		if fd == nil || fd.Kind() != protoreflect.MessageKind || fd.IsList() || fd.IsMap() {
+			return nil
+		}
+		m = m.Mutable(fd).Message()
+	}
+
+	fd := findField(m.Descriptor(), parts[len(parts)-1])
+	if fd == nil || fd.IsMap() {
+		return nil
+	}
+
+	if fd.IsList() {
+		list := m.Mutable(fd).List()
+		for _, v := range values {
+			pv, err := parseValue(fd, v)
+			if err != nil {
+				return fmt.Errorf("invalid value for %s: %w", path, err)
+			}
+			list.Append(pv)
+		}
+		return nil
+	}
+
+	pv, err := parseValue(fd, values[0])
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %w", path, err)
+	}
+	m.Set(fd, pv)
+	return nil
+}
+
+func findField(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	fields := md.Fields()
+	if fd := fields.ByName(protoreflect.Name(name)); fd != nil {
+		return fd
+	}
+	if fd := fields.ByJSONName(name); fd != nil {
+		return fd
+	}
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if strings.EqualFold(string(fd.Name()), name) || strings.EqualFold(fd.JSONName(), name) {
+			return fd
+		}
+	}
+	return nil
+}
+
+func parseValue(fd protoreflect.FieldDescriptor, s string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(s), nil
+	case protoreflect.BoolKind:
+		v, err := strconv.ParseBool(s)
+		return protoreflect.ValueOfBool(v), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		v, err := strconv.ParseInt(s, 10, 32)
+		return protoreflect.ValueOfInt32(int32(v)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		v, err := strconv.ParseInt(s, 10, 64)
+		return protoreflect.ValueOfInt64(v), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		v, err := strconv.ParseUint(s, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(v)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		v, err := strconv.ParseUint(s, 10, 64)
+		return protoreflect.ValueOfUint64(v), err
+	case protoreflect.FloatKind:
+		v, err := strconv.ParseFloat(s, 32)
+		if err == nil && math.IsInf(v, 0) {
+			err = fmt.Errorf("float out of range: %s", s)
+		}
+		return protoreflect.ValueOfFloat32(float32(v)), err
+	case protoreflect.DoubleKind:
+		v, err := strconv.ParseFloat(s, 64)
+		return protoreflect.ValueOfFloat64(v), err
+	case protoreflect.BytesKind:
+		v, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			v, err = base64.URLEncoding.DecodeString(s)
+		}
+		return protoreflect.ValueOfBytes(v), err
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(s)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		v, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			return protoreflect.Value{}, fmt.Errorf("unknown enum value %s", s)
+		}
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(v)), nil
+	default:
+		return protoreflect.Value{}, fmt.Errorf("unsupported kind %s", fd.Kind())
+	}
+}
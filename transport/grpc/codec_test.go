@@ -0,0 +1,102 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package grpc
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewRequestMessage(t *testing.T) {
+	md, err := LoadMethod(writeDescriptorSet(t), "test.Greeter/SayHello")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := strings.NewReader(`{"name":"body","age":10,"unknown":true,"inner":{"id":"a"}}`)
+	params := map[string]string{"Name": "param", "User_id": "42"}
+	query := url.Values{
+		"age":      {"33"},
+		"tags":     {"a", "b"},
+		"inner.id": {"b"},
+		"kind":     {"KIND_A"},
+		"active":   {"true"},
+		"score":    {"1.5"},
+		"data":     {"aGVsbG8="},
+		"other":    {"ignored"},
+	}
+
+	m, err := NewRequestMessage(md, body, params, query)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	data, err := MessageToData(m, Options{UseProtoNames: true})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expected := map[string]interface{}{
+		"name":    "param",
+		"age":     33.0,
+		"tags":    []interface{}{"a", "b"},
+		"inner":   map[string]interface{}{"id": "b"},
+		"kind":    "KIND_A",
+		"active":  true,
+		"score":   1.5,
+		"data":    "aGVsbG8=",
+		"user_id": "42",
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("unexpected data: %v", data)
+	}
+}
+
+func TestNewRequestMessage_emptyBody(t *testing.T) {
+	md, err := LoadMethod(writeDescriptorSet(t), "test.Greeter/SayHello")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := NewRequestMessage(md, strings.NewReader(" "), nil, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	data, err := MessageToData(m, Options{EmitUnpopulated: true})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if data["name"] != "" || data["userId"] != "0" || data["kind"] != "KIND_UNKNOWN" {
+		t.Errorf("unexpected data: %v", data)
+	}
+}
+
+func TestNewRequestMessage_ko(t *testing.T) {
+	md, err := LoadMethod(writeDescriptorSet(t), "test.Greeter/SayHello")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tc := range []struct {
+		body   string
+		params map[string]string
+		query  url.Values
+	}{
+		{body: `{"name":`},
+		{body: `{"age":"nope"}`},
+		{params: map[string]string{"Age": "nope"}},
+		{query: url.Values{"kind": {"KIND_B"}}},
+		{query: url.Values{"active": {"maybe"}}},
+		{query: url.Values{"data": {"!!"}}},
+		{query: url.Values{"inner": {"a"}}},
+	} {
+		if _, err := NewRequestMessage(md, strings.NewReader(tc.body), tc.params, tc.query); err == nil {
+			t.Errorf("#%d: error expected", i)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+// SPDX-License-Identifier: Apache-2.0
+
+/*
+Package grpc offers the basic pieces for calling unary gRPC methods described by a descriptor
+set file and transcoding their messages from and to JSON, without requiring any generated code
+*/
+package grpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/luraproject/lura/v2/config"
+)
+
+// Namespace is the key for the backend's extra config
+const Namespace = "github.com/devopsfaith/krakend/transport/grpc"
+
+func init() {
+	config.MustRegisterNamespace(config.Namespace{
+		Name:  Namespace,
+		Scope: config.BackendScope,
+		Schema: []byte(`{
+			"type": "object",
+			"additionalProperties": false,
+			"required": ["descriptor_set", "method"],
+			"properties": {
+				"descriptor_set": {"type": "string"},
+				"method": {"type": "string"},
+				"use_proto_names": {"type": "boolean"},
+				"emit_unpopulated": {"type": "boolean"}
+			}
+		}`),
+	})
+}
+
+var (
+	// ErrNoConfigFound is the error returned when the backend has no gRPC config
+	ErrNoConfigFound = errors.New("grpc: no configuration found")
+	// ErrStreamingMethod is the error returned when the configured method is not unary
+	ErrStreamingMethod = errors.New("grpc: streaming methods are not supported")
+)
+
+// Options contains the gRPC config of a backend
+type Options struct {
+	// DescriptorSet is the path of the FileDescriptorSet describing the service (as generated by
+	// protoc --include_imports --descriptor_set_out)
+	DescriptorSet string `json:"descriptor_set"`
+	// Method is the full name of the method to call (package.Service/Method)
+	Method string `json:"method"`
+	// UseProtoNames makes the response use the field names of the proto file instead of the
+	// lowerCamelCase JSON names
+	UseProtoNames bool `json:"use_proto_names"`
+	// EmitUnpopulated makes the response include the fields with zero values
+	EmitUnpopulated bool `json:"emit_unpopulated"`
+}
+
+// GetOptions extracts the Options from the backend's extra config
+func GetOptions(cfg config.ExtraConfig) (*Options, error) {
+	tmp, ok := cfg[Namespace]
+	if !ok {
+		return nil, ErrNoConfigFound
+	}
+
+	b, err := json.Marshal(tmp)
+	if err != nil {
+		return nil, err
+	}
+
+	var opt Options
+	if err := json.Unmarshal(b, &opt); err != nil {
+		return nil, err
+	}
+	if opt.DescriptorSet == "" {
+		return nil, errors.New("grpc: the descriptor set is required")
+	}
+	if opt.Method == "" {
+		return nil, errors.New("grpc: the method is required")
+	}
+	return &opt, nil
+}
+
+var (
+	filesMu    = &sync.Mutex{}
+	filesCache = map[string]*protoregistry.Files{}
+)
+
+// LoadMethod returns the descriptor of the unary method defined in the descriptor set file. The
+// method can be expressed as "package.Service/Method" or "package.Service.Method". The parsed
+// descriptor sets are cached, so several backends can share the same file.
+func LoadMethod(descriptorSet, method string) (protoreflect.MethodDescriptor, error) {
+	files, err := loadFiles(descriptorSet)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.ReplaceAll(strings.TrimPrefix(method, "/"), "/", ".")
+	d, err := files.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, fmt.Errorf("grpc: method %s not found: %w", method, err)
+	}
+	md, ok := d.(protoreflect.MethodDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("grpc: %s is not a method", method)
+	}
+	if md.IsStreamingClient() || md.IsStreamingServer() {
+		return nil, ErrStreamingMethod
+	}
+	return md, nil
+}
+
+func loadFiles(path string) (*protoregistry.Files, error) {
+	filesMu.Lock()
+	defer filesMu.Unlock()
+
+	if f, ok := filesCache[path]; ok {
+		return f, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(b, set); err != nil {
+		return nil, fmt.Errorf("grpc: parsing the descriptor set %s: %w", path, err)
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("grpc: parsing the descriptor set %s: %w", path, err)
+	}
+
+	filesCache[path] = files
+	return files, nil
+}
+
+// methodPath returns the path of the HTTP/2 request for the method
+func methodPath(md protoreflect.MethodDescriptor) string {
+	return "/" + string(md.Parent().FullName()) + "/" + string(md.Name())
+}
@@ -0,0 +1,199 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package grpc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/luraproject/lura/v2/config"
+)
+
+func TestGetOptions(t *testing.T) {
+	if _, err := GetOptions(config.ExtraConfig{}); err != ErrNoConfigFound {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	for i, cfg := range []map[string]interface{}{
+		{"method": "test.Greeter/SayHello"},
+		{"descriptor_set": "greeter.pb"},
+		{"descriptor_set": 42},
+	} {
+		if _, err := GetOptions(config.ExtraConfig{Namespace: cfg}); err == nil {
+			t.Errorf("#%d: error expected", i)
+		}
+	}
+
+	opt, err := GetOptions(config.ExtraConfig{Namespace: map[string]interface{}{
+		"descriptor_set":  "greeter.pb",
+		"method":          "test.Greeter/SayHello",
+		"use_proto_names": true,
+	}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if opt.DescriptorSet != "greeter.pb" || opt.Method != "test.Greeter/SayHello" || !opt.UseProtoNames || opt.EmitUnpopulated {
+		t.Errorf("unexpected options: %+v", opt)
+	}
+}
+
+func TestLoadMethod(t *testing.T) {
+	path := writeDescriptorSet(t)
+
+	for _, name := range []string{"test.Greeter/SayHello", "/test.Greeter/SayHello", "test.Greeter.SayHello"} {
+		md, err := LoadMethod(path, name)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if p := methodPath(md); p != "/test.Greeter/SayHello" {
+			t.Errorf("%s: unexpected path %s", name, p)
+		}
+		if md.Input().FullName() != "test.HelloRequest" {
+			t.Errorf("%s: unexpected input %s", name, md.Input().FullName())
+		}
+	}
+
+	if _, err := LoadMethod(path, "test.Greeter/Stream"); err != ErrStreamingMethod {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, name := range []string{"test.Greeter/Unknown", "test.HelloRequest"} {
+		if _, err := LoadMethod(path, name); err == nil {
+			t.Errorf("%s: error expected", name)
+		}
+	}
+	if _, err := LoadMethod(filepath.Join(t.TempDir(), "unknown.pb"), "test.Greeter/SayHello"); err == nil {
+		t.Error("error expected")
+	}
+}
+
+// writeDescriptorSet stores the descriptor set of the following proto file in a temp file:
+//
+//	syntax = "proto3";
+//	package test;
+//
+//	enum Kind {
+//		KIND_UNKNOWN = 0;
+//		KIND_A = 1;
+//	}
+//	message Inner {
+//		string id = 1;
+//	}
+//	message HelloRequest {
+//		string name = 1;
+//		int32 age = 2;
+//		repeated string tags = 3;
+//		Inner inner = 4;
+//		Kind kind = 5;
+//		bool active = 6;
+//		double score = 7;
+//		bytes data = 8;
+//		uint64 user_id = 9;
+//	}
+//	message HelloReply {
+//		string message = 1;
+//		int64 count = 2;
+//		repeated string tags = 3;
+//		string user_id = 4;
+//	}
+//	service Greeter {
+//		rpc SayHello(HelloRequest) returns (HelloReply);
+//		rpc Stream(HelloRequest) returns (stream HelloReply);
+//	}
+func writeDescriptorSet(t *testing.T) string {
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string, repeated bool) *descriptorpb.FieldDescriptorProto {
+		label := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+		if repeated {
+			label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+		}
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(number),
+			Type:   typ.Enum(),
+			Label:  label.Enum(),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Kind"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("KIND_UNKNOWN"), Number: proto.Int32(0)},
+				{Name: proto.String("KIND_A"), Number: proto.Int32(1)},
+			},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name:  proto.String("Inner"),
+				Field: []*descriptorpb.FieldDescriptorProto{field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", false)},
+			},
+			{
+				Name: proto.String("HelloRequest"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", false),
+					field("age", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32, "", false),
+					field("tags", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", true),
+					field("inner", 4, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".test.Inner", false),
+					field("kind", 5, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".test.Kind", false),
+					field("active", 6, descriptorpb.FieldDescriptorProto_TYPE_BOOL, "", false),
+					field("score", 7, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, "", false),
+					field("data", 8, descriptorpb.FieldDescriptorProto_TYPE_BYTES, "", false),
+					field("user_id", 9, descriptorpb.FieldDescriptorProto_TYPE_UINT64, "", false),
+				},
+			},
+			{
+				Name: proto.String("HelloReply"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("message", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", false),
+					field("count", 2, descriptorpb.FieldDescriptorProto_TYPE_INT64, "", false),
+					field("tags", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", true),
+					field("user_id", 4, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", false),
+				},
+			},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Greeter"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{
+					Name:       proto.String("SayHello"),
+					InputType:  proto.String(".test.HelloRequest"),
+					OutputType: proto.String(".test.HelloReply"),
+				},
+				{
+					Name:            proto.String("Stream"),
+					InputType:       proto.String(".test.HelloRequest"),
+					OutputType:      proto.String(".test.HelloReply"),
+					ServerStreaming: proto.Bool(true),
+				},
+			},
+		}},
+	}
+	// fill the json names, as protoc does
+	fd, err := protodesc.NewFile(file, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := proto.Marshal(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{protodesc.ToFileDescriptorProto(fd)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "greeter.pb")
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package grpc
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/luraproject/lura/v2/transport/http/client"
+)
+
+// Code is a gRPC status code
+type Code uint32
+
+// The gRPC status codes, as defined in https://grpc.github.io/grpc/core/md_doc_statuscodes.html
+const (
+	OK Code = iota
+	Canceled
+	Unknown
+	InvalidArgument
+	DeadlineExceeded
+	NotFound
+	AlreadyExists
+	PermissionDenied
+	ResourceExhausted
+	FailedPrecondition
+	Aborted
+	OutOfRange
+	Unimplemented
+	Internal
+	Unavailable
+	DataLoss
+	Unauthenticated
+)
+
+// HTTPStatusFromCode returns the HTTP status code equivalent to the gRPC status code
+func HTTPStatusFromCode(code Code) int {
+	switch code {
+	case OK:
+		return http.StatusOK
+	case Canceled:
+		return 499
+	case InvalidArgument, FailedPrecondition, OutOfRange:
+		return http.StatusBadRequest
+	case DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case NotFound:
+		return http.StatusNotFound
+	case AlreadyExists, Aborted:
+		return http.StatusConflict
+	case PermissionDenied:
+		return http.StatusForbidden
+	case ResourceExhausted:
+		return http.StatusTooManyRequests
+	case Unimplemented:
+		return http.StatusNotImplemented
+	case Unavailable:
+		return http.StatusServiceUnavailable
+	case Unauthenticated:
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// codeFromHTTPStatus returns the gRPC status code for the HTTP status of a response without
+// gRPC status, following the gRPC spec
+func codeFromHTTPStatus(status int) Code {
+	switch status {
+	case http.StatusBadRequest:
+		return Internal
+	case http.StatusUnauthorized:
+		return Unauthenticated
+	case http.StatusForbidden:
+		return PermissionDenied
+	case http.StatusNotFound:
+		return Unimplemented
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return Unavailable
+	default:
+		return Unknown
+	}
+}
+
+// ResponseError is the error returned when the gRPC call does not end with an OK status. It
+// behaves as a client.HTTPResponseError with the HTTP status equivalent to the gRPC one and
+// a JSON body containing the gRPC code and message.
+type ResponseError struct {
+	client.HTTPResponseError
+	Code    Code   `json:"grpc_code"`
+	Message string `json:"grpc_message,omitempty"`
+}
+
+// NewResponseError returns a ResponseError for the gRPC status code and message
+func NewResponseError(code Code, msg string) ResponseError {
+	body, _ := json.Marshal(map[string]interface{}{"code": code, "message": msg})
+	return ResponseError{
+		HTTPResponseError: client.HTTPResponseError{
+			Code: HTTPStatusFromCode(code),
+			Msg:  string(body),
+			Enc:  "application/json",
+		},
+		Code:    code,
+		Message: msg,
+	}
+}
@@ -0,0 +1,198 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// ErrUnsupportedContentEncoding is the error returned when there is no decoder registered for
+// the content coding of a response
+var ErrUnsupportedContentEncoding = errors.New("unsupported content encoding")
+
+// ContentDecoderFactory returns a reader decoding the content read from the received reader
+type ContentDecoderFactory func(io.Reader) (io.ReadCloser, error)
+
+var (
+	contentDecodersMutex = &sync.RWMutex{}
+	contentDecoders      = map[string]ContentDecoderFactory{}
+	acceptedEncodings    = []string{"gzip"}
+)
+
+func init() {
+	RegisterContentDecoder("gzip", newGzipDecoder)
+	RegisterContentDecoder("br", newBrotliDecoder)
+	RegisterContentDecoder("zstd", newZstdDecoder)
+	RegisterContentDecoder("deflate", newDeflateDecoder)
+}
+
+// RegisterContentDecoder registers the decoder factory for the content coding, so the responses
+// using it can be decoded. The coding is advertised to the backends only if it is enabled with
+// SetAcceptEncoding.
+func RegisterContentDecoder(name string, f ContentDecoderFactory) {
+	contentDecodersMutex.Lock()
+	contentDecoders[strings.ToLower(name)] = f
+	contentDecodersMutex.Unlock()
+}
+
+// UnregisterContentDecoder removes the decoder factory of the content coding, so it is not
+// decoded nor advertised anymore
+func UnregisterContentDecoder(name string) {
+	contentDecodersMutex.Lock()
+	delete(contentDecoders, strings.ToLower(name))
+	contentDecodersMutex.Unlock()
+}
+
+// SetAcceptEncoding enables the content codings to advertise in the Accept-Encoding header of
+// the requests to the backends, in order of preference. By default, only gzip is advertised.
+func SetAcceptEncoding(names ...string) {
+	enabled := make([]string, len(names))
+	for i, name := range names {
+		enabled[i] = strings.ToLower(strings.TrimSpace(name))
+	}
+	contentDecodersMutex.Lock()
+	acceptedEncodings = enabled
+	contentDecodersMutex.Unlock()
+}
+
+// AcceptEncoding returns the value of the Accept-Encoding header listing the enabled content
+// codings with a registered decoder
+func AcceptEncoding() string {
+	contentDecodersMutex.RLock()
+	defer contentDecodersMutex.RUnlock()
+	names := make([]string, 0, len(acceptedEncodings))
+	for _, name := range acceptedEncodings {
+		if _, ok := contentDecoders[name]; ok {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// NewContentDecoder returns a reader decoding the body with the codings listed in the
+// Content-Encoding header, in the reverse order they were applied. The identity coding
+// and the empty header return the body as it is.
+func NewContentDecoder(body io.Reader, contentEncoding string) (io.ReadCloser, error) {
+	codings := strings.Split(contentEncoding, ",")
+	res := io.NopCloser(body)
+	closers := []io.Closer{}
+
+	for i := len(codings) - 1; i >= 0; i-- {
+		name := strings.ToLower(strings.TrimSpace(codings[i]))
+		if name == "" || name == "identity" {
+			continue
+		}
+		contentDecodersMutex.RLock()
+		f, ok := contentDecoders[name]
+		contentDecodersMutex.RUnlock()
+		if !ok {
+			closeAll(closers)
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedContentEncoding, name)
+		}
+		r, err := f(res)
+		if err != nil {
+			closeAll(closers)
+			return nil, err
+		}
+		closers = append(closers, r)
+		res = r
+	}
+
+	if len(closers) < 2 {
+		return res, nil
+	}
+	return multiCloser{Reader: res, closers: closers}, nil
+}
+
+// NewContentEncodingHTTPRequestExecutor creates a HTTPRequestExecutor with the received
+// HTTPClientFactory advertising the enabled content codings in the requests without an
+// Accept-Encoding header. The clients with a transport disabling the compression are not
+// affected. Since the responses are not decoded by the http client, they must be decoded
+// with NewContentDecoder.
+func NewContentEncodingHTTPRequestExecutor(clientFactory HTTPClientFactory) HTTPRequestExecutor {
+	return func(ctx context.Context, req *http.Request) (*http.Response, error) {
+		c := clientFactory(ctx)
+		if req.Header.Get("Accept-Encoding") == "" && compressionEnabled(c) {
+			if ae := AcceptEncoding(); ae != "" {
+				if req.Header == nil {
+					req.Header = http.Header{}
+				}
+				req.Header.Set("Accept-Encoding", ae)
+			}
+		}
+		return c.Do(req.WithContext(ctx))
+	}
+}
+
+func compressionEnabled(c *http.Client) bool {
+	t := c.Transport
+	if t == nil {
+		t = http.DefaultTransport
+	}
+	if tr, ok := t.(*http.Transport); ok {
+		return !tr.DisableCompression
+	}
+	return true
+}
+
+type multiCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (m multiCloser) Close() error {
+	return closeAll(m.closers)
+}
+
+func closeAll(closers []io.Closer) error {
+	var err error
+	for i := len(closers) - 1; i >= 0; i-- {
+		if e := closers[i].Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func newGzipDecoder(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+func newBrotliDecoder(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(brotli.NewReader(r)), nil
+}
+
+func newZstdDecoder(r io.Reader) (io.ReadCloser, error) {
+	d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return d.IOReadCloser(), nil
+}
+
+// newDeflateDecoder accepts both the zlib format defined by the HTTP spec and the raw deflate
+// streams sent by some servers
+func newDeflateDecoder(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if len(header) == 2 && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
@@ -0,0 +1,182 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package client
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+const contentEncodingPayload = `{"msg":"some nice, interesting and long content"}`
+
+func encodeContent(t *testing.T, name string, in []byte) []byte {
+	buf := &bytes.Buffer{}
+	var w io.WriteCloser
+	switch name {
+	case "gzip":
+		w = gzip.NewWriter(buf)
+	case "br":
+		w = brotli.NewWriter(buf)
+	case "zstd":
+		zw, err := zstd.NewWriter(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w = zw
+	case "deflate":
+		w = zlib.NewWriter(buf)
+	case "raw-deflate":
+		fw, err := flate.NewWriter(buf, flate.BestSpeed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w = fw
+	default:
+		t.Fatalf("unknown encoding %s", name)
+	}
+	if _, err := w.Write(in); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestNewContentDecoder(t *testing.T) {
+	for _, tc := range []struct {
+		header string
+		body   []byte
+	}{
+		{header: "", body: []byte(contentEncodingPayload)},
+		{header: "identity", body: []byte(contentEncodingPayload)},
+		{header: "gzip", body: encodeContent(t, "gzip", []byte(contentEncodingPayload))},
+		{header: "GZIP", body: encodeContent(t, "gzip", []byte(contentEncodingPayload))},
+		{header: "br", body: encodeContent(t, "br", []byte(contentEncodingPayload))},
+		{header: "zstd", body: encodeContent(t, "zstd", []byte(contentEncodingPayload))},
+		{header: "deflate", body: encodeContent(t, "deflate", []byte(contentEncodingPayload))},
+		{header: "deflate", body: encodeContent(t, "raw-deflate", []byte(contentEncodingPayload))},
+		{header: "gzip, br", body: encodeContent(t, "br", encodeContent(t, "gzip", []byte(contentEncodingPayload)))},
+	} {
+		r, err := NewContentDecoder(bytes.NewReader(tc.body), tc.header)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.header, err)
+			continue
+		}
+		b, err := io.ReadAll(r)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.header, err)
+		}
+		if err := r.Close(); err != nil {
+			t.Errorf("%s: unexpected error closing the reader: %v", tc.header, err)
+		}
+		if string(b) != contentEncodingPayload {
+			t.Errorf("%s: unexpected content: %s", tc.header, string(b))
+		}
+	}
+}
+
+func TestNewContentDecoder_ko(t *testing.T) {
+	if _, err := NewContentDecoder(strings.NewReader(contentEncodingPayload), "compress"); !errors.Is(err, ErrUnsupportedContentEncoding) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := NewContentDecoder(strings.NewReader(contentEncodingPayload), "gzip"); err == nil {
+		t.Error("error expected")
+	}
+}
+
+func TestRegisterContentDecoder(t *testing.T) {
+	if ae := AcceptEncoding(); ae != "gzip" {
+		t.Errorf("unexpected Accept-Encoding: %s", ae)
+	}
+
+	RegisterContentDecoder("Upper", func(r io.Reader) (io.ReadCloser, error) {
+		b, err := io.ReadAll(r)
+		return io.NopCloser(bytes.NewReader(bytes.ToUpper(b))), err
+	})
+	defer UnregisterContentDecoder("upper")
+
+	if ae := AcceptEncoding(); ae != "gzip" {
+		t.Errorf("unexpected Accept-Encoding: %s", ae)
+	}
+	r, err := NewContentDecoder(strings.NewReader("abc"), "upper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := io.ReadAll(r); string(b) != "ABC" {
+		t.Errorf("unexpected content: %s", string(b))
+	}
+
+	SetAcceptEncoding("gzip", "BR", "zstd", "deflate", "upper", "unknown")
+	defer SetAcceptEncoding("gzip")
+
+	if ae := AcceptEncoding(); ae != "gzip, br, zstd, deflate, upper" {
+		t.Errorf("unexpected Accept-Encoding: %s", ae)
+	}
+
+	UnregisterContentDecoder("br")
+	defer RegisterContentDecoder("br", newBrotliDecoder)
+
+	if ae := AcceptEncoding(); ae != "gzip, zstd, deflate, upper" {
+		t.Errorf("unexpected Accept-Encoding: %s", ae)
+	}
+	if _, err := NewContentDecoder(strings.NewReader("abc"), "br"); !errors.Is(err, ErrUnsupportedContentEncoding) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestNewContentEncodingHTTPRequestExecutor(t *testing.T) {
+	var received string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Encoding", "br")
+		w.Write(encodeContent(t, "br", []byte(contentEncodingPayload)))
+	}))
+	defer ts.Close()
+
+	for _, tc := range []struct {
+		name     string
+		client   *http.Client
+		header   string
+		expected string
+	}{
+		{name: "default", client: ts.Client(), expected: AcceptEncoding()},
+		{name: "explicit header", client: ts.Client(), header: "identity", expected: "identity"},
+		{name: "compression disabled", client: &http.Client{Transport: &http.Transport{DisableCompression: true}}},
+	} {
+		re := NewContentEncodingHTTPRequestExecutor(func(_ context.Context) *http.Client { return tc.client })
+		req, _ := http.NewRequest("GET", ts.URL, http.NoBody)
+		if tc.header != "" {
+			req.Header.Set("Accept-Encoding", tc.header)
+		}
+		resp, err := re(context.Background(), req)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if received != tc.expected {
+			t.Errorf("%s: unexpected Accept-Encoding: '%s'", tc.name, received)
+		}
+		r, err := NewContentDecoder(resp.Body, resp.Header.Get("Content-Encoding"))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.name, err)
+			continue
+		}
+		if b, _ := io.ReadAll(r); string(b) != contentEncodingPayload {
+			t.Errorf("%s: unexpected content: %s", tc.name, string(b))
+		}
+		resp.Body.Close()
+	}
+}
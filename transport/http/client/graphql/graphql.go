@@ -22,6 +22,25 @@ import (
 // Namespace is the key for the backend's extra config
 const Namespace = "github.com/devopsfaith/krakend/transport/http/client/graphql"
 
+func init() {
+	config.MustRegisterNamespace(config.Namespace{
+		Name:  Namespace,
+		Scope: config.BackendScope,
+		Schema: []byte(`{
+			"type": "object",
+			"additionalProperties": false,
+			"properties": {
+				"type": {"enum": ["query", "mutation"]},
+				"method": {"type": "string"},
+				"query": {"type": "string"},
+				"query_path": {"type": "string"},
+				"operationName": {"type": "string"},
+				"variables": {"type": "object"}
+			}
+		}`),
+	})
+}
+
 // OperationType contains all the operations allowed by graphql
 type OperationType string
 
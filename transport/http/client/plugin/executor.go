@@ -15,6 +15,15 @@ import (
 
 const Namespace = "github.com/devopsfaith/krakend/transport/http/client/executor"
 
+func init() {
+	config.MustRegisterNamespace(config.Namespace{
+		Name:  Namespace,
+		Scope: config.BackendScope,
+		// the config of the plugin is stored under its name
+		Schema: []byte(`{"type": "object", "required": ["name"], "properties": {"name": {"type": "string"}}}`),
+	})
+}
+
 func HTTPRequestExecutor(
 	logger logging.Logger,
 	next func(*config.Backend) client.HTTPRequestExecutor,
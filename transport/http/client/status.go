@@ -16,6 +16,18 @@ import (
 // Namespace to be used in extra config
 const Namespace = "github.com/devopsfaith/krakend/http"
 
+func init() {
+	config.MustRegisterNamespace(config.Namespace{
+		Name:  Namespace,
+		Scope: config.BackendScope,
+		Schema: []byte(`{
+			"type": "object",
+			"additionalProperties": false,
+			"properties": {"return_error_details": {"type": "string"}, "return_error_code": {"type": "boolean"}}
+		}`),
+	})
+}
+
 // ErrInvalidStatusCode is the error returned by the http proxy when the received status code
 // is not a 200 nor a 201
 var ErrInvalidStatusCode = errors.New("invalid status code")
@@ -140,12 +152,22 @@ func DetailedHTTPStatusHandlerWithErrPrefix(name, errPrefix string) HTTPStatusHa
 	}
 }
 
+// newHTTPResponseError reads the body of the response, decoding it with the content decoders
+// if the backend compressed it, so the error details are returned as plain text
 func newHTTPResponseError(resp *http.Response) HTTPResponseError {
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		body = []byte{}
 	}
 	resp.Body.Close()
+	if ce := resp.Header.Get("Content-Encoding"); ce != "" && len(body) > 0 {
+		if decoded, err := decodeContent(body, ce); err == nil {
+			body = decoded
+			resp.Header.Del("Content-Encoding")
+			resp.Header.Del("Content-Length")
+			resp.ContentLength = int64(len(body))
+		}
+	}
 	resp.Body = io.NopCloser(bytes.NewBuffer(body))
 
 	return HTTPResponseError{
@@ -187,3 +209,12 @@ type NamedHTTPResponseError struct {
 func (r NamedHTTPResponseError) Name() string {
 	return r.name
 }
+
+func decodeContent(body []byte, contentEncoding string) ([]byte, error) {
+	r, err := NewContentDecoder(bytes.NewReader(body), contentEncoding)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -174,3 +174,33 @@ var statusCodes = []int{
 	http.StatusNotExtended,
 	http.StatusNetworkAuthenticationRequired,
 }
+
+func TestErrorHTTPStatusHandler_contentEncoding(t *testing.T) {
+	sh := ErrorHTTPStatusHandlerWithErrPrefix("[GET /foo]:")
+	for _, coding := range []string{"gzip", "br", "zstd", "deflate"} {
+		resp := &http.Response{
+			StatusCode: http.StatusBadRequest,
+			Body:       io.NopCloser(bytes.NewReader(encodeContent(t, coding, []byte(`{"msg":"wrong"}`)))),
+			Header: http.Header{
+				"Content-Type":     []string{"application/json"},
+				"Content-Encoding": []string{coding},
+			},
+		}
+
+		r, err := sh(context.Background(), resp)
+		e, ok := err.(HTTPResponseError)
+		if !ok {
+			t.Errorf("%s: unexpected error type %T: %v", coding, err, err)
+			continue
+		}
+		if e.Error() != `{"msg":"wrong"}` {
+			t.Errorf("%s: unexpected message: %s", coding, e.Msg)
+		}
+		if ce := r.Header.Get("Content-Encoding"); ce != "" {
+			t.Errorf("%s: unexpected Content-Encoding: %s", coding, ce)
+		}
+		if b, _ := io.ReadAll(r.Body); string(b) != `{"msg":"wrong"}` {
+			t.Errorf("%s: unexpected body: %s", coding, string(b))
+		}
+	}
+}
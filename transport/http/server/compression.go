@@ -0,0 +1,425 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/luraproject/lura/v2/config"
+)
+
+// CompressionNamespace is the key used to store the response compression options in the service
+// and endpoint extra configs
+const CompressionNamespace = "github.com/devopsfaith/krakend/transport/http/server/compression"
+
+func init() {
+	config.MustRegisterNamespace(config.Namespace{
+		Name:  CompressionNamespace,
+		Scope: config.ServiceScope | config.EndpointScope,
+		Schema: []byte(`{
+			"type": "object",
+			"additionalProperties": false,
+			"properties": {
+				"disabled": {"type": "boolean"},
+				"encodings": {"type": "array", "items": {"type": "string"}},
+				"min_size": {"type": "integer", "minimum": 0},
+				"mime_types": {"type": "array", "items": {"type": "string"}}
+			}
+		}`),
+	})
+}
+
+// DefaultCompressionMinSize is the min size of the responses to compress when it is not configured
+const DefaultCompressionMinSize = 1024
+
+var (
+	// DefaultCompressionEncodings are the content codings offered to the clients when they are not
+	// configured, in order of preference
+	DefaultCompressionEncodings = []string{"br", "zstd", "gzip"}
+	// DefaultCompressionMIMETypes are the media types compressed when they are not configured
+	DefaultCompressionMIMETypes = []string{
+		"application/json",
+		"application/xml",
+		"application/yaml",
+		"application/x-yaml",
+		"application/x-www-form-urlencoded",
+		"application/msgpack",
+		"application/javascript",
+		"text/*",
+	}
+)
+
+// CompressionConfig defines how the responses are compressed when the clients accept it
+type CompressionConfig struct {
+	// Encodings are the content codings to offer, in order of preference
+	Encodings []string
+	// MinSize is the min number of bytes of the responses to compress
+	MinSize int
+	// MIMETypes are the media types to compress. They accept wildcard subtypes, like 'text/*'.
+	MIMETypes []string
+}
+
+type compressionOptions struct {
+	Disabled  *bool    `json:"disabled"`
+	Encodings []string `json:"encodings"`
+	MinSize   *int     `json:"min_size"`
+	MIMETypes []string `json:"mime_types"`
+}
+
+// NewCompressionConfig returns the compression config of an endpoint. The options defined in the
+// endpoint extra config override the ones defined at the service level. It returns false if the
+// compression is not enabled at any level, it is disabled or none of the encodings is supported.
+//
+//	"extra_config": {
+//		"github.com/devopsfaith/krakend/transport/http/server/compression": {
+//			"encodings": ["br", "gzip"],
+//			"min_size": 512,
+//			"mime_types": ["application/json"]
+//		}
+//	}
+func NewCompressionConfig(service, endpoint config.ExtraConfig) (*CompressionConfig, bool) {
+	cfg := CompressionConfig{
+		Encodings: DefaultCompressionEncodings,
+		MinSize:   DefaultCompressionMinSize,
+		MIMETypes: DefaultCompressionMIMETypes,
+	}
+	enabled := false
+
+	for _, e := range []config.ExtraConfig{service, endpoint} {
+		v, ok := e[CompressionNamespace]
+		if !ok {
+			continue
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			continue
+		}
+		opts := compressionOptions{}
+		if err := json.Unmarshal(b, &opts); err != nil {
+			continue
+		}
+		enabled = opts.Disabled == nil || !*opts.Disabled
+		if len(opts.Encodings) > 0 {
+			cfg.Encodings = opts.Encodings
+		}
+		if opts.MinSize != nil {
+			cfg.MinSize = *opts.MinSize
+		}
+		if len(opts.MIMETypes) > 0 {
+			cfg.MIMETypes = opts.MIMETypes
+		}
+	}
+
+	encodings := make([]string, 0, len(cfg.Encodings))
+	for _, name := range cfg.Encodings {
+		name = strings.ToLower(name)
+		if _, ok := compressors[name]; ok {
+			encodings = append(encodings, name)
+		}
+	}
+	cfg.Encodings = encodings
+
+	if !enabled || len(cfg.Encodings) == 0 {
+		return nil, false
+	}
+	return &cfg, true
+}
+
+// NewCompressionHandler returns a http.Handler compressing the responses of the received one when
+// the client accepts any of the configured encodings
+func NewCompressionHandler(cfg *CompressionConfig, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cw, ok := NewCompressionWriter(w, r, cfg)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// NewCompressionWriter returns a CompressionWriter wrapping the received one if the request
+// accepts any of the configured encodings. HEAD requests and protocol upgrades are ignored.
+func NewCompressionWriter(w http.ResponseWriter, r *http.Request, cfg *CompressionConfig) (*CompressionWriter, bool) {
+	if r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" {
+		return nil, false
+	}
+	enc := negotiateEncoding(r.Header.Get("Accept-Encoding"), cfg.Encodings)
+	if enc == "" {
+		return nil, false
+	}
+	return &CompressionWriter{w: w, cfg: cfg, encoding: enc, status: http.StatusOK}, true
+}
+
+// CompressionWriter is a http.ResponseWriter compressing the body with the negotiated encoding.
+// The body is buffered until it reaches the min size, so the smaller responses are sent as
+// they are. The responses with a Content-Encoding (like the ones passed through from no-op
+// backends), a media type not in the list or a status without body are not compressed.
+// Close must be called once the response is complete.
+type CompressionWriter struct {
+	w        http.ResponseWriter
+	cfg      *CompressionConfig
+	encoding string
+	status   int
+	buf      []byte
+	enc      compressor
+	started  bool
+}
+
+// Header implements the http.ResponseWriter interface
+func (c *CompressionWriter) Header() http.Header {
+	return c.w.Header()
+}
+
+// WriteHeader implements the http.ResponseWriter interface. The status is sent with the first
+// chunk of the body, once the compression is decided.
+func (c *CompressionWriter) WriteHeader(status int) {
+	if c.started {
+		return
+	}
+	c.status = status
+}
+
+// Status returns the status of the response
+func (c *CompressionWriter) Status() int {
+	return c.status
+}
+
+// Write implements the http.ResponseWriter interface
+func (c *CompressionWriter) Write(p []byte) (int, error) {
+	if c.started {
+		if c.enc != nil {
+			return c.enc.Write(p)
+		}
+		return c.w.Write(p)
+	}
+	if !c.compressible() {
+		if err := c.start(false); err != nil {
+			return 0, err
+		}
+		return c.w.Write(p)
+	}
+	c.buf = append(c.buf, p...)
+	if len(c.buf) < c.cfg.MinSize {
+		return len(p), nil
+	}
+	if err := c.start(true); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// FlushError sends the buffered data to the client, compressing it if possible regardless of
+// its size, so the streams are not delayed
+func (c *CompressionWriter) FlushError() error {
+	if !c.started {
+		if err := c.start(c.compressible()); err != nil {
+			return err
+		}
+	}
+	if c.enc != nil {
+		if err := c.enc.Flush(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(c.w).Flush()
+}
+
+// Flush implements the http.Flusher interface
+func (c *CompressionWriter) Flush() {
+	c.FlushError()
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, so the http.ResponseController can access it
+func (c *CompressionWriter) Unwrap() http.ResponseWriter {
+	return c.w
+}
+
+// Close sends the pending data and finishes the compressed stream, returning the encoder to
+// its pool
+func (c *CompressionWriter) Close() error {
+	if !c.started {
+		if err := c.start(false); err != nil {
+			return err
+		}
+	}
+	if c.enc == nil {
+		return nil
+	}
+	err := c.enc.Close()
+	compressors[c.encoding].put(c.enc)
+	c.enc = nil
+	return err
+}
+
+func (c *CompressionWriter) compressible() bool {
+	if !bodyAllowedForStatus(c.status) || c.status == http.StatusPartialContent {
+		return false
+	}
+	h := c.w.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	return matchMIMEType(h.Get("Content-Type"), c.cfg.MIMETypes)
+}
+
+// start sends the headers and the buffered data
+func (c *CompressionWriter) start(compress bool) error {
+	c.started = true
+	h := c.w.Header()
+	if c.compressible() {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	if compress {
+		enc, err := compressors[c.encoding].get(c.w)
+		if err != nil {
+			return err
+		}
+		c.enc = enc
+		h.Set("Content-Encoding", c.encoding)
+		h.Del("Content-Length")
+	}
+	c.w.WriteHeader(c.status)
+	if len(c.buf) == 0 {
+		return nil
+	}
+	var err error
+	if c.enc != nil {
+		_, err = c.enc.Write(c.buf)
+	} else {
+		_, err = c.w.Write(c.buf)
+	}
+	c.buf = nil
+	return err
+}
+
+func bodyAllowedForStatus(status int) bool {
+	switch {
+	case status >= 100 && status <= 199:
+		return false
+	case status == http.StatusNoContent, status == http.StatusNotModified:
+		return false
+	}
+	return true
+}
+
+func matchMIMEType(contentType string, allowed []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range allowed {
+		t = strings.ToLower(t)
+		if t == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(t, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiateEncoding returns the supported encoding with the highest quality value in the
+// Accept-Encoding header. The ties are solved with the order of the supported encodings.
+func negotiateEncoding(header string, supported []string) string {
+	accepted := map[string]float64{}
+	wildcard := -1.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			k, v, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(k) != "q" {
+				continue
+			}
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				f = 0
+			}
+			q = f
+		}
+		if name == "*" {
+			wildcard = q
+			continue
+		}
+		accepted[name] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range supported {
+		q, ok := accepted[enc]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+// compressorPool reuses the encoders of an algorithm with a given level, since allocating
+// their internal state on every response is expensive
+type compressorPool struct {
+	level int
+	new   func(w io.Writer, level int) (compressor, error)
+	pool  sync.Pool
+}
+
+func (p *compressorPool) get(w io.Writer) (compressor, error) {
+	if enc, ok := p.pool.Get().(compressor); ok {
+		enc.Reset(w)
+		return enc, nil
+	}
+	return p.new(w, p.level)
+}
+
+// put returns a closed encoder to the pool, dropping its reference to the response writer
+func (p *compressorPool) put(enc compressor) {
+	enc.Reset(io.Discard)
+	p.pool.Put(enc)
+}
+
+var compressors = map[string]*compressorPool{
+	"gzip": {
+		level: gzip.DefaultCompression,
+		new: func(w io.Writer, level int) (compressor, error) {
+			return gzip.NewWriterLevel(w, level)
+		},
+	},
+	"br": {
+		// the default quality is too slow for dynamic content
+		level: 4,
+		new: func(w io.Writer, level int) (compressor, error) {
+			return brotli.NewWriterLevel(w, level), nil
+		},
+	},
+	"zstd": {
+		level: int(zstd.SpeedDefault),
+		new: func(w io.Writer, level int) (compressor, error) {
+			return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.EncoderLevel(level)), zstd.WithEncoderConcurrency(1))
+		},
+	},
+}
@@ -0,0 +1,289 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/luraproject/lura/v2/config"
+)
+
+func TestNewCompressionConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		service  config.ExtraConfig
+		endpoint config.ExtraConfig
+		expected *CompressionConfig
+	}{
+		{
+			name: "not configured",
+		},
+		{
+			name:    "service defaults",
+			service: config.ExtraConfig{CompressionNamespace: map[string]interface{}{}},
+			expected: &CompressionConfig{
+				Encodings: DefaultCompressionEncodings,
+				MinSize:   DefaultCompressionMinSize,
+				MIMETypes: DefaultCompressionMIMETypes,
+			},
+		},
+		{
+			name:     "endpoint",
+			endpoint: config.ExtraConfig{CompressionNamespace: map[string]interface{}{"encodings": []interface{}{"GZIP", "compress"}, "min_size": 0}},
+			expected: &CompressionConfig{
+				Encodings: []string{"gzip"},
+				MinSize:   0,
+				MIMETypes: DefaultCompressionMIMETypes,
+			},
+		},
+		{
+			name:     "endpoint overriding the service",
+			service:  config.ExtraConfig{CompressionNamespace: map[string]interface{}{"encodings": []interface{}{"br"}, "min_size": 10}},
+			endpoint: config.ExtraConfig{CompressionNamespace: map[string]interface{}{"mime_types": []interface{}{"application/json"}}},
+			expected: &CompressionConfig{
+				Encodings: []string{"br"},
+				MinSize:   10,
+				MIMETypes: []string{"application/json"},
+			},
+		},
+		{
+			name:     "disabled by the endpoint",
+			service:  config.ExtraConfig{CompressionNamespace: map[string]interface{}{}},
+			endpoint: config.ExtraConfig{CompressionNamespace: map[string]interface{}{"disabled": true}},
+		},
+		{
+			name:     "enabled by the endpoint",
+			service:  config.ExtraConfig{CompressionNamespace: map[string]interface{}{"disabled": true, "min_size": 1}},
+			endpoint: config.ExtraConfig{CompressionNamespace: map[string]interface{}{"disabled": false}},
+			expected: &CompressionConfig{
+				Encodings: DefaultCompressionEncodings,
+				MinSize:   1,
+				MIMETypes: DefaultCompressionMIMETypes,
+			},
+		},
+		{
+			name:    "unsupported encodings",
+			service: config.ExtraConfig{CompressionNamespace: map[string]interface{}{"encodings": []interface{}{"compress"}}},
+		},
+	} {
+		cfg, ok := NewCompressionConfig(tc.service, tc.endpoint)
+		if ok != (tc.expected != nil) {
+			t.Errorf("%s: unexpected result: %v", tc.name, ok)
+			continue
+		}
+		if !reflect.DeepEqual(cfg, tc.expected) {
+			t.Errorf("%s: unexpected config: %+v", tc.name, cfg)
+		}
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	supported := []string{"br", "zstd", "gzip"}
+	for header, expected := range map[string]string{
+		"":                             "",
+		"identity":                     "",
+		"gzip":                         "gzip",
+		"gzip, deflate, br, zstd":      "br",
+		"gzip;q=1.0, br;q=0.5":         "gzip",
+		"br;q=0, zstd":                 "zstd",
+		"*":                            "br",
+		"*;q=0.1, gzip;q=0.5":          "gzip",
+		"br;q=0, zstd;q=0, *":          "gzip",
+		"GZIP ; q=0.8, deflate":        "gzip",
+		"gzip;q=invalid, zstd;q=0.001": "zstd",
+	} {
+		if res := negotiateEncoding(header, supported); res != expected {
+			t.Errorf("%s: unexpected encoding %s", header, res)
+		}
+	}
+}
+
+func decompress(t *testing.T, encoding string, body []byte) string {
+	var r io.Reader
+	switch encoding {
+	case "gzip":
+		gr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = gr
+	case "br":
+		r = brotli.NewReader(bytes.NewReader(body))
+	case "zstd":
+		zr, err := zstd.NewReader(bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+		r = zr
+	default:
+		return string(body)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestNewCompressionHandler(t *testing.T) {
+	big := `{"msg":"` + strings.Repeat("lura ", 300) + `"}`
+	cfg := &CompressionConfig{Encodings: []string{"br", "zstd", "gzip"}, MinSize: 1024, MIMETypes: DefaultCompressionMIMETypes}
+
+	for _, tc := range []struct {
+		name           string
+		method         string
+		acceptEncoding string
+		contentType    string
+		encoded        bool
+		status         int
+		body           string
+		chunks         int
+		expected       string
+	}{
+		{name: "gzip", acceptEncoding: "gzip", contentType: "application/json", body: big, expected: "gzip"},
+		{name: "brotli", acceptEncoding: "gzip, br", contentType: "application/json; charset=utf-8", body: big, expected: "br"},
+		{name: "zstd", acceptEncoding: "zstd", contentType: "text/plain", body: big, chunks: 10, expected: "zstd"},
+		{name: "not accepted", acceptEncoding: "deflate", contentType: "application/json", body: big},
+		{name: "too small", acceptEncoding: "gzip", contentType: "application/json", body: `{"a":1}`},
+		{name: "too small in chunks", acceptEncoding: "gzip", contentType: "application/json", body: `{"a":1}`, chunks: 3},
+		{name: "mime type", acceptEncoding: "gzip", contentType: "image/png", body: big},
+		{name: "already encoded", acceptEncoding: "gzip", contentType: "application/json", encoded: true, body: big},
+		{name: "no content", acceptEncoding: "gzip", contentType: "application/json", status: http.StatusNoContent},
+		{name: "head", method: http.MethodHead, acceptEncoding: "gzip", contentType: "application/json", body: big},
+		{name: "error status", acceptEncoding: "gzip", contentType: "application/json", status: http.StatusInternalServerError, body: big, expected: "gzip"},
+	} {
+		h := NewCompressionHandler(cfg, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", tc.contentType)
+			if tc.encoded {
+				w.Header().Set("Content-Encoding", "identity")
+			}
+			w.Header().Set("Content-Length", strconv.Itoa(len(tc.body)))
+			if tc.status != 0 {
+				w.WriteHeader(tc.status)
+			}
+			if tc.chunks == 0 {
+				w.Write([]byte(tc.body))
+				return
+			}
+			size := len(tc.body)/tc.chunks + 1
+			for i := 0; i < len(tc.body); i += size {
+				io.WriteString(w, tc.body[i:min(i+size, len(tc.body))])
+			}
+		}))
+
+		method := tc.method
+		if method == "" {
+			method = http.MethodGet
+		}
+		req, _ := http.NewRequest(method, "/", http.NoBody)
+		req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		status := tc.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if w.Code != status {
+			t.Errorf("%s: unexpected status code %d", tc.name, w.Code)
+		}
+		if tc.encoded {
+			if ce := w.Header().Get("Content-Encoding"); ce != "identity" {
+				t.Errorf("%s: unexpected content encoding %s", tc.name, ce)
+			}
+		} else if ce := w.Header().Get("Content-Encoding"); ce != tc.expected {
+			t.Errorf("%s: unexpected content encoding %s", tc.name, ce)
+		}
+		if tc.expected != "" {
+			if w.Header().Get("Content-Length") != "" {
+				t.Errorf("%s: unexpected content length", tc.name)
+			}
+			if w.Header().Get("Vary") != "Accept-Encoding" {
+				t.Errorf("%s: unexpected vary header: %v", tc.name, w.Header()["Vary"])
+			}
+			if w.Body.Len() >= len(tc.body) {
+				t.Errorf("%s: the body was not compressed", tc.name)
+			}
+		}
+		if body := decompress(t, tc.expected, w.Body.Bytes()); body != tc.body {
+			t.Errorf("%s: unexpected body: %s", tc.name, body)
+		}
+	}
+}
+
+func TestCompressionWriter_flush(t *testing.T) {
+	cfg := &CompressionConfig{Encodings: []string{"gzip"}, MinSize: 1024, MIMETypes: DefaultCompressionMIMETypes}
+	req, _ := http.NewRequest(http.MethodGet, "/", http.NoBody)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+
+	cw, ok := NewCompressionWriter(w, req, cfg)
+	if !ok {
+		t.Fatal("the request accepts gzip")
+	}
+	cw.Header().Set("Content-Type", "text/event-stream")
+	io.WriteString(cw, "data: first\n\n")
+	if err := http.NewResponseController(cw).Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if !w.Flushed {
+		t.Error("the recorder was not flushed")
+	}
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Errorf("unexpected content encoding %s", w.Header().Get("Content-Encoding"))
+	}
+	if w.Body.Len() == 0 {
+		t.Error("the flushed chunk was not sent")
+	}
+	io.WriteString(cw, "data: second\n\n")
+	if err := cw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if body := decompress(t, "gzip", w.Body.Bytes()); body != "data: first\n\ndata: second\n\n" {
+		t.Errorf("unexpected body: %s", body)
+	}
+}
+
+func TestCompressionWriter_pooledEncoders(t *testing.T) {
+	for _, encoding := range []string{"gzip", "br", "zstd"} {
+		cfg := &CompressionConfig{Encodings: []string{encoding}, MIMETypes: DefaultCompressionMIMETypes}
+		for i := 0; i < 3; i++ {
+			req, _ := http.NewRequest(http.MethodGet, "/", http.NoBody)
+			req.Header.Set("Accept-Encoding", encoding)
+			w := httptest.NewRecorder()
+
+			cw, _ := NewCompressionWriter(w, req, cfg)
+			cw.Header().Set("Content-Type", "text/plain")
+			expected := strings.Repeat(strconv.Itoa(i), 100)
+			io.WriteString(cw, expected)
+			if err := cw.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if body := decompress(t, encoding, w.Body.Bytes()); body != expected {
+				t.Errorf("%s #%d: unexpected body: %s", encoding, i, body)
+			}
+		}
+	}
+}
+
+func TestNewCompressionWriter_upgrade(t *testing.T) {
+	cfg := &CompressionConfig{Encodings: []string{"gzip"}, MIMETypes: DefaultCompressionMIMETypes}
+	req, _ := http.NewRequest(http.MethodGet, "/", http.NoBody)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("Upgrade", "websocket")
+	if _, ok := NewCompressionWriter(httptest.NewRecorder(), req, cfg); ok {
+		t.Error("the protocol upgrades should not be compressed")
+	}
+}
@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+
+	"github.com/luraproject/lura/v2/config"
+)
+
+// NewHTTP3Server returns a http3.Server sharing the handler, the limits and the TLS configuration
+// (including the loaded certificates) of the received http.Server. QUIC requires TLS 1.3, so
+// RunServer rejects the HTTP/3 servers with a lower TLS max version with ErrHTTP3TLSVersion.
+func NewHTTP3Server(s *http.Server) *http3.Server {
+	return &http3.Server{
+		Addr:           s.Addr,
+		Handler:        s.Handler,
+		TLSConfig:      http3.ConfigureTLSConfig(s.TLSConfig),
+		MaxHeaderBytes: s.MaxHeaderBytes,
+		IdleTimeout:    s.IdleTimeout,
+	}
+}
+
+// ListenHTTP3 opens the UDP socket for the QUIC listener defined in the TLS configuration. The
+// port of the service is used if the HTTP3Port is not defined.
+func ListenHTTP3(cfg config.ServiceConfig) (net.PacketConn, error) {
+	port := cfg.Port
+	if cfg.TLS != nil && cfg.TLS.HTTP3Port != 0 {
+		port = cfg.TLS.HTTP3Port
+	}
+	return net.ListenPacket("udp", net.JoinHostPort(cfg.Address, fmt.Sprintf("%d", port)))
+}
+
+// NewAltSvcHandler returns a http.Handler advertising the HTTP/3 server in the Alt-Svc header of
+// the responses, so the clients can switch to it
+func NewAltSvcHandler(h3 *http3.Server, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h3.SetQUICHeaders(w.Header())
+		next.ServeHTTP(w, r)
+	})
+}
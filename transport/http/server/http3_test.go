@@ -0,0 +1,153 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+
+	"github.com/luraproject/lura/v2/config"
+)
+
+func TestRunServer_http3(t *testing.T) {
+	testKeysAreAvailable(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	port := newPort()
+
+	done := make(chan error)
+	go func() {
+		done <- RunServer(
+			ctx,
+			config.ServiceConfig{
+				Port:                port,
+				MaxShutdownDuration: time.Second,
+				TLS: &config.TLS{
+					PublicKey:   "cert.pem",
+					PrivateKey:  "key.pem",
+					EnableHTTP3: true,
+				},
+			},
+			http.HandlerFunc(dummyHandler),
+		)
+	}()
+
+	client, err := httpsClient("cert.pem")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	<-time.After(100 * time.Millisecond)
+
+	resp, err := client.Get(fmt.Sprintf("https://localhost:%d", port))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 {
+		t.Errorf("unexpected status code: %d", resp.StatusCode)
+		return
+	}
+	if altSvc := resp.Header.Get("Alt-Svc"); altSvc != fmt.Sprintf(`h3=":%d"; ma=2592000`, port) {
+		t.Errorf("unexpected Alt-Svc header: %s", altSvc)
+	}
+
+	h3Client, err := http3Client("cert.pem")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	resp, err = h3Client.Get(fmt.Sprintf("https://localhost:%d/h3", port))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.ProtoMajor != 3 {
+		t.Errorf("unexpected protocol: %s", resp.Proto)
+	}
+	if string(body) != `Hello, "/h3"` {
+		t.Errorf("unexpected body: %s", string(body))
+	}
+	h3Client.CloseIdleConnections()
+
+	cancel()
+
+	if err = <-done; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRunServer_http3PortInUse(t *testing.T) {
+	testKeysAreAvailable(t)
+
+	conn, err := net.ListenPacket("udp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	err = RunServer(
+		context.Background(),
+		config.ServiceConfig{
+			Port: newPort(),
+			TLS: &config.TLS{
+				PublicKey:   "cert.pem",
+				PrivateKey:  "key.pem",
+				EnableHTTP3: true,
+				HTTP3Port:   conn.LocalAddr().(*net.UDPAddr).Port,
+			},
+		},
+		http.HandlerFunc(dummyHandler),
+	)
+	if err == nil {
+		t.Error("error expected")
+	}
+}
+
+func TestRunServer_http3TLSVersion(t *testing.T) {
+	err := RunServer(
+		context.Background(),
+		config.ServiceConfig{
+			Port: newPort(),
+			TLS: &config.TLS{
+				PublicKey:   "cert.pem",
+				PrivateKey:  "key.pem",
+				MaxVersion:  "TLS12",
+				EnableHTTP3: true,
+			},
+		},
+		http.HandlerFunc(dummyHandler),
+	)
+	if err != ErrHTTP3TLSVersion {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func http3Client(cert string) (*http.Client, error) {
+	cer, err := os.ReadFile(cert)
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(cer) {
+		return nil, errors.New("failed to parse root certificate")
+	}
+	return &http.Client{Transport: &http3.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}, nil
+}
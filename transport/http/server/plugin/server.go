@@ -13,6 +13,19 @@ import (
 const Namespace = "github_com/devopsfaith/krakend/transport/http/server/handler"
 const logPrefix = "[PLUGIN: Server]"
 
+func init() {
+	config.MustRegisterNamespace(config.Namespace{
+		Name:  Namespace,
+		Scope: config.ServiceScope,
+		// the config of every plugin is stored under its name
+		Schema: []byte(`{
+			"type": "object",
+			"required": ["name"],
+			"properties": {"name": {"anyOf": [{"type": "string"}, {"type": "array", "items": {"type": "string"}}]}}
+		}`),
+	})
+}
+
 type RunServer func(context.Context, config.ServiceConfig, http.Handler) error
 
 func New(logger logging.Logger, next RunServer) RunServer {
@@ -20,6 +20,7 @@ import (
 	"github.com/luraproject/lura/v2/config"
 	"github.com/luraproject/lura/v2/core"
 	"github.com/luraproject/lura/v2/logging"
+	"github.com/quic-go/quic-go/http3"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 )
@@ -56,7 +57,10 @@ var (
 	ErrPrivateKey = errors.New("private key not defined")
 	// ErrPublicKey is the error returned by the router when the public key is not defined
 	ErrPublicKey = errors.New("public key not defined")
-	loggerPrefix = "[SERVICE: HTTP Server]"
+	// ErrHTTP3TLSVersion is the error returned by the router when the HTTP/3 server is enabled
+	// with a TLS max version lower than 1.3, required by QUIC
+	ErrHTTP3TLSVersion = errors.New("HTTP/3 requires a TLS max version of 1.3")
+	loggerPrefix       = "[SERVICE: HTTP Server]"
 )
 
 // InitHTTPDefaultTransport ensures the default HTTP transport is configured just once per execution
@@ -108,14 +112,18 @@ func RunServer(ctx context.Context, cfg config.ServiceConfig, handler http.Handl
 
 func RunServerWithLoggerFactory(l logging.Logger) func(context.Context, config.ServiceConfig, http.Handler) error {
 	return func(ctx context.Context, cfg config.ServiceConfig, handler http.Handler) error {
-		done := make(chan error)
+		done := make(chan error, 2)
 		s := NewServerWithLogger(cfg, handler, l)
+		var h3 *http3.Server
 
 		if s.TLSConfig == nil {
 			go func() {
 				done <- s.ListenAndServe()
 			}()
 		} else {
+			if cfg.TLS.EnableHTTP3 && s.TLSConfig.MaxVersion < tls.VersionTLS13 {
+				return ErrHTTP3TLSVersion
+			}
 			if cfg.TLS.PublicKey != "" || cfg.TLS.PrivateKey != "" {
 				cfg.TLS.Keys = append(cfg.TLS.Keys, config.TLSKeyPair{
 					PublicKey:  cfg.TLS.PublicKey,
@@ -139,6 +147,20 @@ func RunServerWithLoggerFactory(l logging.Logger) func(context.Context, config.S
 				s.TLSConfig.Certificates = append(s.TLSConfig.Certificates, cert)
 			}
 
+			if cfg.TLS.EnableHTTP3 {
+				conn, err := ListenHTTP3(cfg)
+				if err != nil {
+					return err
+				}
+				defer conn.Close()
+
+				h3 = NewHTTP3Server(s)
+				s.Handler = NewAltSvcHandler(h3, s.Handler)
+				go func() {
+					done <- h3.Serve(conn)
+				}()
+			}
+
 			go func() {
 				// since we already use the list of certificates in the config
 				// we do not need to specify the files for public and private key here
@@ -148,18 +170,38 @@ func RunServerWithLoggerFactory(l logging.Logger) func(context.Context, config.S
 
 		select {
 		case err := <-done:
+			if h3 != nil {
+				// the remaining listener is not left serving alone
+				s.Close()
+				h3.Close()
+			}
 			return err
 		case <-ctx.Done():
-			if cfg.MaxShutdownDuration <= 0 {
-				return s.Shutdown(context.Background())
+			shutdownCtx := context.Background()
+			if cfg.MaxShutdownDuration > 0 {
+				var cancel context.CancelFunc
+				shutdownCtx, cancel = context.WithTimeout(shutdownCtx, cfg.MaxShutdownDuration)
+				defer cancel()
+			}
+			if h3 == nil {
+				return s.Shutdown(shutdownCtx)
 			}
-			withTimeout, cancel := context.WithTimeout(context.Background(), cfg.MaxShutdownDuration)
-			defer cancel()
-			return s.Shutdown(withTimeout)
+			return shutdownAll(shutdownCtx, s, h3)
 		}
 	}
 }
 
+// shutdownAll gracefully shuts down the TCP and the QUIC servers in parallel, so both share the
+// same max shutdown duration
+func shutdownAll(ctx context.Context, s *http.Server, h3 *http3.Server) error {
+	h3Err := make(chan error, 1)
+	go func() {
+		h3Err <- h3.Shutdown(ctx)
+	}()
+	err := s.Shutdown(ctx)
+	return errors.Join(err, <-h3Err)
+}
+
 // NewServer returns a http.Server ready to serve the injected handler
 func NewServer(cfg config.ServiceConfig, handler http.Handler) *http.Server {
 	return NewServerWithLogger(cfg, handler, nil)
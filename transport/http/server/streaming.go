@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/luraproject/lura/v2/config"
+)
+
+// Namespace is the key used to store the http server options in the endpoint extra config
+const Namespace = "github.com/devopsfaith/krakend/transport/http/server"
+
+func init() {
+	config.MustRegisterNamespace(config.Namespace{
+		Name:   Namespace,
+		Scope:  config.EndpointScope,
+		Schema: []byte(`{"type": "object", "additionalProperties": false, "properties": {"streaming": {"type": "boolean"}}}`),
+	})
+}
+
+const (
+	streamingKey   = "streaming"
+	streamingChunk = 4 * 1024
+)
+
+// IsStreamingEndpoint returns true if the endpoint has the streaming mode enabled. The streaming
+// mode only applies to endpoints using the no-op encoding, so the body of the backend response
+// is passed through to the client as it arrives.
+//
+//	"extra_config": {
+//		"github.com/devopsfaith/krakend/transport/http/server": {
+//			"streaming": true
+//		}
+//	}
+func IsStreamingEndpoint(cfg *config.EndpointConfig) bool {
+	e, ok := cfg.ExtraConfig[Namespace].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	v, _ := e[streamingKey].(bool)
+	return v
+}
+
+// NewStreamingContext returns a context derived from the received one (usually the context of
+// the request, so a client disconnection cancels it) and a stop function. The context is canceled
+// if the timeout expires before stop is called, so the timeout just covers the time required by
+// the backend to return the response headers. Once stopped, the context lasts until the client
+// disconnects or the returned cancel function is called. Stop returns false if the timeout already
+// expired.
+func NewStreamingContext(ctx context.Context, timeout time.Duration) (context.Context, func() bool, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	if timeout <= 0 {
+		return ctx, func() bool { return ctx.Err() == nil }, cancel
+	}
+	timer := time.AfterFunc(timeout, cancel)
+	return ctx, timer.Stop, cancel
+}
+
+// DisableWriteDeadline removes the write deadline set by the server (WriteTimeout) for the
+// current response, so long lasting streams are not cut. It returns an error if the underlying
+// writer does not support it.
+func DisableWriteDeadline(w http.ResponseWriter) error {
+	return http.NewResponseController(w).SetWriteDeadline(time.Time{})
+}
+
+// StreamResponse copies the content of the reader into the writer, flushing it after every write
+// so the client receives the data as soon as it arrives. It stops when the reader is consumed or
+// when a write fails (usually because the client is gone).
+func StreamResponse(w http.ResponseWriter, r io.Reader) (int64, error) {
+	rc := http.NewResponseController(w)
+	if err := rc.Flush(); err != nil {
+		return io.Copy(w, r)
+	}
+
+	var written int64
+	buf := make([]byte, streamingChunk)
+	for {
+		n, rErr := r.Read(buf)
+		if n > 0 {
+			m, wErr := w.Write(buf[:n])
+			written += int64(m)
+			if wErr != nil {
+				return written, wErr
+			}
+			if wErr = rc.Flush(); wErr != nil {
+				return written, wErr
+			}
+		}
+		if rErr == io.EOF {
+			return written, nil
+		}
+		if rErr != nil {
+			return written, rErr
+		}
+	}
+}
@@ -0,0 +1,132 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package server
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/luraproject/lura/v2/config"
+)
+
+func TestIsStreamingEndpoint(t *testing.T) {
+	for i, tc := range []struct {
+		extra    config.ExtraConfig
+		expected bool
+	}{
+		{extra: config.ExtraConfig{}},
+		{extra: config.ExtraConfig{Namespace: map[string]interface{}{}}},
+		{extra: config.ExtraConfig{Namespace: map[string]interface{}{"streaming": "true"}}},
+		{extra: config.ExtraConfig{Namespace: map[string]interface{}{"streaming": false}}},
+		{extra: config.ExtraConfig{Namespace: map[string]interface{}{"streaming": true}}, expected: true},
+	} {
+		if res := IsStreamingEndpoint(&config.EndpointConfig{ExtraConfig: tc.extra}); res != tc.expected {
+			t.Errorf("#%d: unexpected result. have: %v, want: %v", i, res, tc.expected)
+		}
+	}
+}
+
+func TestNewStreamingContext_stopped(t *testing.T) {
+	ctx, stop, cancel := NewStreamingContext(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if !stop() {
+		t.Error("the timeout should not be expired")
+	}
+
+	<-time.After(50 * time.Millisecond)
+	if err := ctx.Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	cancel()
+	if err := ctx.Err(); err != context.Canceled {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestNewStreamingContext_timeout(t *testing.T) {
+	ctx, stop, cancel := NewStreamingContext(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Error("the context should be canceled")
+	}
+
+	if stop() {
+		t.Error("the timeout should be expired")
+	}
+}
+
+func TestNewStreamingContext_parentCanceled(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, stop, cancel := NewStreamingContext(parent, 0)
+	defer cancel()
+
+	if !stop() {
+		t.Error("the context should be alive")
+	}
+
+	cancelParent()
+	if err := ctx.Err(); err != context.Canceled {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if stop() {
+		t.Error("the context should be canceled")
+	}
+}
+
+func TestStreamResponse(t *testing.T) {
+	pr, pw := io.Pipe()
+	w := httptest.NewRecorder()
+	done := make(chan error)
+
+	go func() {
+		_, err := StreamResponse(w, pr)
+		done <- err
+	}()
+
+	pw.Write([]byte("data: first\n\n"))
+	// the pipe write returns once the chunk is consumed by the stream, so the
+	// flush happens right after it
+	<-time.After(10 * time.Millisecond)
+
+	pw.Write([]byte("data: second\n\n"))
+	pw.Close()
+
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !w.Flushed {
+		t.Error("the response was not flushed")
+	}
+	if body := w.Body.String(); body != "data: first\n\ndata: second\n\n" {
+		t.Errorf("unexpected body: %q", body)
+	}
+}
+
+func TestStreamResponse_readError(t *testing.T) {
+	pr, pw := io.Pipe()
+	w := httptest.NewRecorder()
+	done := make(chan error)
+
+	go func() {
+		_, err := StreamResponse(w, pr)
+		done <- err
+	}()
+
+	pw.Write([]byte("data: first\n\n"))
+	pw.CloseWithError(io.ErrUnexpectedEOF)
+
+	if err := <-done; err != io.ErrUnexpectedEOF {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if body := w.Body.String(); body != "data: first\n\n" {
+		t.Errorf("unexpected body: %q", body)
+	}
+}
@@ -0,0 +1,175 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package websocket
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	closeOpcode         = 0x8
+	closeMessageTooBig  = 1009
+	maxFrameHeaderSize  = 14
+	finalBit            = 0x80
+	maskBit             = 0x80
+	opcodeMask          = 0x0f
+	payloadLenMask      = 0x7f
+	controlOpcodeOffset = 0x8
+)
+
+// tunnel copies the frames between the client and the backend connections
+type tunnel struct {
+	idleTimeout    time.Duration
+	maxMessageSize int64
+	lastActivity   atomic.Int64
+}
+
+type copyResult struct {
+	toClient bool
+	err      error
+}
+
+// run tunnels the connections until one of them is closed, the idle timeout expires or a
+// message exceeds the max message size. In the last case, the client receives a close frame
+// with the 1009 status code. Both connections are closed before returning.
+func (t *tunnel) run(client net.Conn, clientReader io.Reader, backend net.Conn, backendReader io.Reader) error {
+	t.touch()
+	results := make(chan copyResult, 2)
+	go func() {
+		results <- copyResult{err: t.copy(backend, t.newIdleReader(client, clientReader))}
+	}()
+	go func() {
+		results <- copyResult{toClient: true, err: t.copy(client, t.newIdleReader(backend, backendReader))}
+	}()
+
+	res := <-results
+	pending := 1
+	backend.Close()
+	if errors.Is(res.err, ErrMessageTooBig) {
+		if !res.toClient {
+			// wait until the backend to client copy ends, so the close frame is
+			// not mixed with the frames of the backend
+			<-results
+			pending = 0
+		}
+		client.Write(closeFrame(closeMessageTooBig))
+	}
+	client.Close()
+	if pending > 0 {
+		<-results
+	}
+	return res.err
+}
+
+// copy moves the frames from src to dst. If there is no max message size, the stream is copied
+// as is. Otherwise the frame headers are parsed in order to track the size of the messages.
+func (t *tunnel) copy(dst io.Writer, src io.Reader) error {
+	if t.maxMessageSize <= 0 {
+		_, err := io.Copy(dst, src)
+		return err
+	}
+
+	header := make([]byte, maxFrameHeaderSize)
+	var size int64
+	for {
+		if _, err := io.ReadFull(src, header[:2]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		n := 2
+		length := int64(header[1] & payloadLenMask)
+		switch length {
+		case 126:
+			if _, err := io.ReadFull(src, header[n:n+2]); err != nil {
+				return err
+			}
+			length = int64(binary.BigEndian.Uint16(header[n : n+2]))
+			n += 2
+		case 127:
+			if _, err := io.ReadFull(src, header[n:n+8]); err != nil {
+				return err
+			}
+			length = int64(binary.BigEndian.Uint64(header[n : n+8]))
+			n += 8
+		}
+		if header[1]&maskBit != 0 {
+			if _, err := io.ReadFull(src, header[n:n+4]); err != nil {
+				return err
+			}
+			n += 4
+		}
+
+		if opcode := header[0] & opcodeMask; opcode < controlOpcodeOffset {
+			// data frames with an opcode start a new message, continuation frames don't
+			if opcode != 0 {
+				size = 0
+			}
+			size += length
+			if length < 0 || size > t.maxMessageSize {
+				return ErrMessageTooBig
+			}
+		}
+
+		if _, err := dst.Write(header[:n]); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(dst, src, length); err != nil {
+			return err
+		}
+	}
+}
+
+func (t *tunnel) touch() {
+	t.lastActivity.Store(time.Now().UnixNano())
+}
+
+func (t *tunnel) isIdle() bool {
+	return time.Since(time.Unix(0, t.lastActivity.Load())) >= t.idleTimeout
+}
+
+func (t *tunnel) newIdleReader(conn net.Conn, r io.Reader) io.Reader {
+	return idleReader{conn: conn, r: r, t: t}
+}
+
+// idleReader updates the activity of the tunnel with every read. When the idle timeout is
+// enabled, reads only fail because of a timeout if there is no activity in any direction.
+type idleReader struct {
+	conn net.Conn
+	r    io.Reader
+	t    *tunnel
+}
+
+func (i idleReader) Read(p []byte) (int, error) {
+	for {
+		if i.t.idleTimeout > 0 {
+			i.conn.SetReadDeadline(time.Now().Add(i.t.idleTimeout))
+		}
+		n, err := i.r.Read(p)
+		if n > 0 {
+			i.t.touch()
+		}
+		if n == 0 && isTimeout(err) && !i.t.isIdle() {
+			continue
+		}
+		return n, err
+	}
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// closeFrame returns an unmasked close frame with the received status code
+func closeFrame(code uint16) []byte {
+	frame := []byte{finalBit | closeOpcode, 2, 0, 0}
+	binary.BigEndian.PutUint16(frame[2:], code)
+	return frame
+}
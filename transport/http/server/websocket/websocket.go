@@ -0,0 +1,300 @@
+// SPDX-License-Identifier: Apache-2.0
+
+/*
+Package websocket provides a handler tunneling the WebSocket connections accepted by the router
+adapters to the backends
+*/
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/core"
+	"github.com/luraproject/lura/v2/logging"
+	"github.com/luraproject/lura/v2/proxy"
+	"github.com/luraproject/lura/v2/sd"
+)
+
+// Namespace is the key used to store the WebSocket options in the endpoint extra config
+const Namespace = "github.com/devopsfaith/krakend/transport/http/server/websocket"
+
+func init() {
+	config.MustRegisterNamespace(config.Namespace{
+		Name:  Namespace,
+		Scope: config.EndpointScope,
+		Schema: []byte(`{
+			"type": "object",
+			"additionalProperties": false,
+			"properties": {"idle_timeout": {"type": "string"}, "max_message_size": {"type": "integer", "minimum": 0}}
+		}`),
+	})
+}
+
+var (
+	// ErrNotUpgradeRequest is the error returned when a WebSocket endpoint receives a request
+	// without the required upgrade headers
+	ErrNotUpgradeRequest = errors.New("websocket: the request is not a websocket upgrade")
+	// ErrMessageTooBig is the error returned when a message exceeds the max message size
+	ErrMessageTooBig = errors.New("websocket: message too big")
+)
+
+// handshakeHeaders are the headers of the client handshake always forwarded to the backend,
+// no matter the input_headers of the endpoint
+var handshakeHeaders = []string{
+	"Sec-Websocket-Key",
+	"Sec-Websocket-Version",
+	"Sec-Websocket-Protocol",
+	"Sec-Websocket-Extensions",
+}
+
+// Config contains the options of a WebSocket endpoint
+type Config struct {
+	// IdleTimeout is the max time without messages in any direction before closing the
+	// tunnel. Zero means no timeout.
+	IdleTimeout time.Duration
+	// MaxMessageSize is the max size in bytes of the messages sent in any direction.
+	// Zero means no limit.
+	MaxMessageSize int64
+}
+
+// ConfigGetter extracts the WebSocket options from the extra config. It returns false if
+// the endpoint is not a WebSocket endpoint.
+//
+//	"extra_config": {
+//		"github.com/devopsfaith/krakend/transport/http/server/websocket": {
+//			"idle_timeout": "5m",
+//			"max_message_size": 65536
+//		}
+//	}
+func ConfigGetter(extra config.ExtraConfig) (Config, bool) {
+	cfg := Config{}
+	e, ok := extra[Namespace].(map[string]interface{})
+	if !ok {
+		return cfg, false
+	}
+	if v, ok := e["idle_timeout"].(string); ok {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.IdleTimeout = d
+		}
+	}
+	switch v := e["max_message_size"].(type) {
+	case float64:
+		cfg.MaxMessageSize = int64(v)
+	case int:
+		cfg.MaxMessageSize = int64(v)
+	case int64:
+		cfg.MaxMessageSize = v
+	}
+	return cfg, true
+}
+
+// Handler tunnels the WebSocket connection of the received http request to the backend. The
+// proxy request is the one built by the router adapter, so the params, the query strings and
+// the headers are already filtered following the endpoint configuration.
+type Handler func(w http.ResponseWriter, r *http.Request, request *proxy.Request)
+
+// New returns a Handler for the endpoint if it is configured as a WebSocket endpoint. The
+// endpoint must have a single backend, and its host is selected by the balancer of the service
+// discovery defined for the backend. The timeout of the endpoint covers the connection to the
+// backend and the handshake.
+func New(logger logging.Logger, endpoint *config.EndpointConfig) (Handler, bool) {
+	cfg, ok := ConfigGetter(endpoint.ExtraConfig)
+	if !ok {
+		return nil, false
+	}
+
+	logPrefix := "[ENDPOINT: " + endpoint.Endpoint + "][WebSocket]"
+	if len(endpoint.Backend) != 1 {
+		logger.Error(logPrefix, "WebSocket endpoints require exactly 1 backend, got", len(endpoint.Backend))
+		return nil, false
+	}
+	remote := endpoint.Backend[0]
+	balancer := sd.NewBalancer(sd.GetRegister().Get(remote.SD)(remote))
+	logger.Debug(logPrefix, "Tunneling the connections to", remote.URLPattern)
+
+	return func(w http.ResponseWriter, r *http.Request, request *proxy.Request) {
+		if !IsUpgradeRequest(r) {
+			w.Header().Set("Upgrade", "websocket")
+			http.Error(w, ErrNotUpgradeRequest.Error(), http.StatusUpgradeRequired)
+			return
+		}
+
+		host, err := balancer.Host()
+		if err != nil {
+			logger.Error(logPrefix, err.Error())
+			http.Error(w, "", http.StatusServiceUnavailable)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), endpoint.Timeout)
+		backendConn, backendReader, resp, err := handshake(ctx, host, remote.URLPattern, r, request)
+		cancel()
+		if err != nil {
+			logger.Error(logPrefix, "Connecting to the backend:", err.Error())
+			http.Error(w, "", http.StatusBadGateway)
+			return
+		}
+
+		if resp.StatusCode != http.StatusSwitchingProtocols {
+			// the backend rejected the upgrade, so its response is returned as is
+			for k, vs := range resp.Header {
+				for _, v := range vs {
+					w.Header().Add(k, v)
+				}
+			}
+			w.WriteHeader(resp.StatusCode)
+			io.Copy(w, resp.Body)
+			resp.Body.Close()
+			backendConn.Close()
+			return
+		}
+
+		clientConn, clientRW, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			backendConn.Close()
+			logger.Error(logPrefix, "Hijacking the connection:", err.Error())
+			http.Error(w, "", http.StatusInternalServerError)
+			return
+		}
+		// the server may have set deadlines on the connection before the hijack
+		clientConn.SetDeadline(time.Time{})
+
+		resp.Header.Set(core.KrakendHeaderName, core.KrakendHeaderValue)
+		clientRW.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+		resp.Header.Write(clientRW)
+		clientRW.WriteString("\r\n")
+		if err := clientRW.Flush(); err != nil {
+			clientConn.Close()
+			backendConn.Close()
+			return
+		}
+
+		t := &tunnel{idleTimeout: cfg.IdleTimeout, maxMessageSize: cfg.MaxMessageSize}
+		if err := t.run(clientConn, clientRW.Reader, backendConn, backendReader); err != nil {
+			logger.Debug(logPrefix, "Tunnel closed:", err.Error())
+		}
+	}, true
+}
+
+// IsUpgradeRequest returns true if the request asks for a WebSocket upgrade
+func IsUpgradeRequest(r *http.Request) bool {
+	if r.Method != http.MethodGet || !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return false
+	}
+	for _, v := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// handshake connects to the backend and sends the upgrade request. It returns the connection,
+// the reader to use for consuming it and the response of the backend.
+func handshake(ctx context.Context, host, pattern string, r *http.Request, request *proxy.Request) (net.Conn, *bufio.Reader, *http.Response, error) {
+	rq := request.Clone()
+	rq.GeneratePath(pattern)
+	u, err := url.Parse(host + rq.Path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(rq.Query) > 0 {
+		if u.RawQuery != "" {
+			u.RawQuery += "&"
+		}
+		u.RawQuery += rq.Query.Encode()
+	}
+
+	conn, err := dial(ctx, u)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	headers := make(http.Header, len(rq.Headers)+len(handshakeHeaders)+2)
+	for k, vs := range rq.Headers {
+		headers[k] = vs
+	}
+	for _, k := range handshakeHeaders {
+		if vs, ok := r.Header[k]; ok {
+			headers[k] = vs
+		}
+	}
+	headers.Set("Connection", "Upgrade")
+	headers.Set("Upgrade", "websocket")
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        u,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     headers,
+		Host:       u.Host,
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, nil, nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, nil, nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, br, resp, nil
+}
+
+func dial(ctx context.Context, u *url.URL) (net.Conn, error) {
+	var secure bool
+	switch u.Scheme {
+	case "http", "ws":
+		u.Scheme = "http"
+	case "https", "wss":
+		u.Scheme = "https"
+		secure = true
+	default:
+		return nil, fmt.Errorf("websocket: unsupported scheme %q", u.Scheme)
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		if secure {
+			addr = net.JoinHostPort(u.Hostname(), "443")
+		} else {
+			addr = net.JoinHostPort(u.Hostname(), "80")
+		}
+	}
+
+	if !secure {
+		return (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	}
+
+	tlsConfig := &tls.Config{}
+	if t, ok := http.DefaultTransport.(*http.Transport); ok && t.TLSClientConfig != nil {
+		tlsConfig = t.TLSClientConfig.Clone()
+	}
+	if tlsConfig.ServerName == "" {
+		tlsConfig.ServerName = u.Hostname()
+	}
+	// the upgrade mechanism is only available with HTTP/1.1
+	tlsConfig.NextProtos = []string{"http/1.1"}
+	return (&tls.Dialer{Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+}
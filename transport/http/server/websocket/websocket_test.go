@@ -0,0 +1,347 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luraproject/lura/v2/config"
+	"github.com/luraproject/lura/v2/core"
+	"github.com/luraproject/lura/v2/logging"
+	"github.com/luraproject/lura/v2/proxy"
+)
+
+func TestConfigGetter(t *testing.T) {
+	if _, ok := ConfigGetter(config.ExtraConfig{}); ok {
+		t.Error("the config should not be available")
+	}
+
+	cfg, ok := ConfigGetter(config.ExtraConfig{
+		Namespace: map[string]interface{}{
+			"idle_timeout":     "2s",
+			"max_message_size": 1024.0,
+		},
+	})
+	if !ok {
+		t.Error("the config should be available")
+	}
+	if cfg.IdleTimeout != 2*time.Second {
+		t.Errorf("unexpected idle timeout: %v", cfg.IdleTimeout)
+	}
+	if cfg.MaxMessageSize != 1024 {
+		t.Errorf("unexpected max message size: %d", cfg.MaxMessageSize)
+	}
+}
+
+func TestNew_notAvailable(t *testing.T) {
+	if _, ok := New(logging.NoOp, &config.EndpointConfig{}); ok {
+		t.Error("the handler should not be available without config")
+	}
+
+	endpoint := &config.EndpointConfig{
+		Endpoint: "/ws",
+		Backend: []*config.Backend{
+			{Host: []string{"http://127.0.0.1:8080"}},
+			{Host: []string{"http://127.0.0.1:8081"}},
+		},
+		ExtraConfig: config.ExtraConfig{Namespace: map[string]interface{}{}},
+	}
+	if _, ok := New(logging.NoOp, endpoint); ok {
+		t.Error("the handler should not be available with several backends")
+	}
+}
+
+func TestIsUpgradeRequest(t *testing.T) {
+	for i, tc := range []struct {
+		method   string
+		headers  map[string]string
+		expected bool
+	}{
+		{method: "GET"},
+		{method: "GET", headers: map[string]string{"Upgrade": "websocket"}},
+		{method: "GET", headers: map[string]string{"Upgrade": "h2c", "Connection": "Upgrade"}},
+		{method: "POST", headers: map[string]string{"Upgrade": "websocket", "Connection": "Upgrade"}},
+		{method: "GET", headers: map[string]string{"Upgrade": "websocket", "Connection": "Upgrade"}, expected: true},
+		{method: "GET", headers: map[string]string{"Upgrade": "WebSocket", "Connection": "keep-alive, upgrade"}, expected: true},
+	} {
+		r, _ := http.NewRequest(tc.method, "http://127.0.0.1/ws", http.NoBody)
+		for k, v := range tc.headers {
+			r.Header.Set(k, v)
+		}
+		if res := IsUpgradeRequest(r); res != tc.expected {
+			t.Errorf("#%d: unexpected result. have: %v, want: %v", i, res, tc.expected)
+		}
+	}
+}
+
+func TestHandler_tunnel(t *testing.T) {
+	requests := make(chan *http.Request, 1)
+	backend := httptest.NewServer(echoBackend(requests))
+	defer backend.Close()
+
+	gateway := newGateway(t, backend.URL, map[string]interface{}{})
+	defer gateway.Close()
+
+	conn, resp := dialGateway(t, gateway.URL+"/ws?q=1")
+	defer conn.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Errorf("unexpected status code: %d", resp.StatusCode)
+		return
+	}
+	if accept := resp.Header.Get("Sec-Websocket-Accept"); accept != acceptKey(testKey) {
+		t.Errorf("unexpected accept key: %s", accept)
+	}
+	if h := resp.Header.Get(core.KrakendHeaderName); h != core.KrakendHeaderValue {
+		t.Errorf("unexpected %s header: %s", core.KrakendHeaderName, h)
+	}
+
+	req := <-requests
+	if req.URL.Path != "/rooms/lobby" {
+		t.Errorf("unexpected path: %s", req.URL.Path)
+	}
+	if req.URL.RawQuery != "q=1" {
+		t.Errorf("unexpected query: %s", req.URL.RawQuery)
+	}
+	if h := req.Header.Get("X-Custom"); h != "custom" {
+		t.Errorf("unexpected forwarded header: %s", h)
+	}
+	if h := req.Header.Get("X-Not-Forwarded"); h != "" {
+		t.Errorf("unexpected header: %s", h)
+	}
+
+	for _, msg := range []string{"hello", "world", strings.Repeat("a", 300)} {
+		frame := newFrame(0x1, []byte(msg))
+		if _, err := conn.Write(frame); err != nil {
+			t.Error(err)
+			return
+		}
+		echo := make([]byte, len(frame))
+		if _, err := io.ReadFull(resp.Body.(*bodyReader).r, echo); err != nil {
+			t.Error(err)
+			return
+		}
+		if !bytes.Equal(frame, echo) {
+			t.Errorf("unexpected frame: %v", echo)
+		}
+	}
+}
+
+func TestHandler_maxMessageSize(t *testing.T) {
+	backend := httptest.NewServer(echoBackend(nil))
+	defer backend.Close()
+
+	gateway := newGateway(t, backend.URL, map[string]interface{}{"max_message_size": 8})
+	defer gateway.Close()
+
+	conn, resp := dialGateway(t, gateway.URL+"/ws")
+	defer conn.Close()
+	r := resp.Body.(*bodyReader).r
+
+	// fragmented messages are accumulated
+	frame := newFrame(0x1, []byte("12345"))
+	frame[0] &^= finalBit
+	conn.Write(frame)
+	echo := make([]byte, len(frame))
+	if _, err := io.ReadFull(r, echo); err != nil {
+		t.Error(err)
+		return
+	}
+
+	conn.Write(newFrame(0x0, []byte("67890")))
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !bytes.Equal(b, closeFrame(closeMessageTooBig)) {
+		t.Errorf("unexpected frame: %v", b)
+	}
+}
+
+func TestHandler_idleTimeout(t *testing.T) {
+	backend := httptest.NewServer(echoBackend(nil))
+	defer backend.Close()
+
+	gateway := newGateway(t, backend.URL, map[string]interface{}{"idle_timeout": "50ms"})
+	defer gateway.Close()
+
+	conn, resp := dialGateway(t, gateway.URL+"/ws")
+	defer conn.Close()
+	r := resp.Body.(*bodyReader).r
+
+	// the activity in any direction keeps the tunnel alive
+	for i := 0; i < 4; i++ {
+		<-time.After(30 * time.Millisecond)
+		frame := newFrame(0x1, []byte("ping"))
+		conn.Write(frame)
+		if _, err := io.ReadFull(r, make([]byte, len(frame))); err != nil {
+			t.Errorf("#%d: %v", i, err)
+			return
+		}
+	}
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := r.ReadByte(); err != io.EOF {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHandler_notUpgrade(t *testing.T) {
+	backend := httptest.NewServer(echoBackend(nil))
+	defer backend.Close()
+
+	gateway := newGateway(t, backend.URL, map[string]interface{}{})
+	defer gateway.Close()
+
+	resp, err := http.Get(gateway.URL + "/ws")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUpgradeRequired {
+		t.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+}
+
+func TestHandler_rejected(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer backend.Close()
+
+	gateway := newGateway(t, backend.URL, map[string]interface{}{})
+	defer gateway.Close()
+
+	conn, resp := dialGateway(t, gateway.URL+"/ws")
+	defer conn.Close()
+
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+}
+
+func TestHandler_noBackend(t *testing.T) {
+	gateway := newGateway(t, "http://127.0.0.1:1", map[string]interface{}{})
+	defer gateway.Close()
+
+	conn, resp := dialGateway(t, gateway.URL+"/ws")
+	defer conn.Close()
+
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+}
+
+const testKey = "dGhlIHNhbXBsZSBub25jZQ=="
+
+func newGateway(t *testing.T, backendURL string, cfg map[string]interface{}) *httptest.Server {
+	endpoint := &config.EndpointConfig{
+		Endpoint: "/ws",
+		Timeout:  time.Second,
+		Backend: []*config.Backend{
+			{
+				Host:       []string{backendURL},
+				URLPattern: "/rooms/{{.Room}}",
+			},
+		},
+		ExtraConfig: config.ExtraConfig{Namespace: cfg},
+	}
+	h, ok := New(logging.NoOp, endpoint)
+	if !ok {
+		t.Fatal("the handler should be available")
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h(w, r, &proxy.Request{
+			Params:  map[string]string{"Room": "lobby"},
+			Query:   r.URL.Query(),
+			Headers: map[string][]string{"X-Custom": r.Header.Values("X-Custom")},
+		})
+	}))
+}
+
+// bodyReader exposes the buffered reader of the connection, so the frames received after
+// the handshake are not lost
+type bodyReader struct {
+	io.ReadCloser
+	r *bufio.Reader
+}
+
+func dialGateway(t *testing.T, u string) (net.Conn, *http.Response) {
+	req, _ := http.NewRequest(http.MethodGet, u, http.NoBody)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Key", testKey)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("X-Custom", "custom")
+	req.Header.Set("X-Not-Forwarded", "nope")
+
+	conn, err := net.Dial("tcp", req.URL.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body = &bodyReader{ReadCloser: resp.Body, r: br}
+	return conn, resp
+}
+
+// echoBackend accepts the upgrade and sends back every received byte
+func echoBackend(requests chan<- *http.Request) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests != nil {
+			requests <- r
+		}
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+		rw.WriteString("Upgrade: websocket\r\nConnection: Upgrade\r\n")
+		rw.WriteString("Sec-WebSocket-Accept: " + acceptKey(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+		rw.Flush()
+		io.Copy(conn, rw)
+	})
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// newFrame returns a final and masked frame
+func newFrame(opcode byte, payload []byte) []byte {
+	frame := []byte{finalBit | opcode}
+	switch l := len(payload); {
+	case l < 126:
+		frame = append(frame, maskBit|byte(l))
+	default:
+		frame = append(frame, maskBit|126, byte(l>>8), byte(l))
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}